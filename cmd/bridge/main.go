@@ -8,10 +8,14 @@ import (
 	"time"
 
 	"screenpipe-assistant-bridge/internal/bridge"
+	"screenpipe-assistant-bridge/internal/paths"
 )
 
 func main() {
-	dataDir := os.ExpandEnv("$USERPROFILE/.screenpipe/data")
+	dataDir := paths.Expand(os.Getenv("SCREENPIPE_DATA_DIR"))
+	if dataDir == "" {
+		dataDir = paths.ScreenPipeData().Path
+	}
 	fmt.Println("[Bridge] Monitoring ScreenPipe data directory:", dataDir)
 
 	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
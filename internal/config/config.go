@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/spf13/viper"
+
+	"screenpipe-assistant-bridge/internal/paths"
 )
 
 // Config holds all configuration for the bridge
@@ -178,7 +180,7 @@ func Load() (*Config, error) {
 
 	// ScreenPipe Configuration
 	config.ScreenPipe = ScreenPipeConfig{
-		DataDir:      getEnvOrDefault("SCREENPIPE_DATA_DIR", "C:/Users/CUSTOM PC/.screenpipe/data"),
+		DataDir:      paths.Expand(getEnvOrDefault("SCREENPIPE_DATA_DIR", paths.ScreenPipeData().Path)),
 		PollInterval: getDurationEnvOrDefault("SCREENPIPE_POLL_INTERVAL", 5*time.Second),
 		FilePatterns: strings.Split(getEnvOrDefault("SCREENPIPE_FILE_PATTERNS", "*.mp4,*.wav,*.txt"), ","),
 	}
@@ -201,7 +203,7 @@ func Load() (*Config, error) {
 
 	// Obsidian Configuration
 	config.Obsidian = ObsidianConfig{
-		VaultPath:    getEnvOrDefault("OBSIDIAN_VAULT_PATH", "C:/Users/CUSTOM PC/ObsidianVault"),
+		VaultPath:    paths.Expand(getEnvOrDefault("OBSIDIAN_VAULT_PATH", paths.ObsidianVault().Path)),
 		TemplatePath: getEnvOrDefault("OBSIDIAN_TEMPLATE_PATH", "templates/note_template.md"),
 		Folder:       getEnvOrDefault("OBSIDIAN_FOLDER", "ScreenPipe Notes"),
 		TagPrefix:    getEnvOrDefault("OBSIDIAN_TAG_PREFIX", "screenpipe"),
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/cursor-blueprint-enforcer/screenpipe-assistant-bridge/internal/config"
+	"github.com/cursor-blueprint-enforcer/screenpipe-assistant-bridge/internal/paths"
 	"github.com/cursor-blueprint-enforcer/screenpipe-assistant-bridge/internal/processor"
 )
 
@@ -23,9 +24,10 @@ type Writer struct {
 // New creates a new Obsidian writer
 func New(cfg *config.Config) (*Writer, error) {
 	if cfg.Obsidian.VaultPath == "" {
-		// Default to a common Obsidian vault location
-		cfg.Obsidian.VaultPath = os.ExpandEnv("$USERPROFILE/Documents/Obsidian Vault")
+		// Default to the vault Obsidian has open, or its default location
+		cfg.Obsidian.VaultPath = paths.ObsidianVault().Path
 	}
+	cfg.Obsidian.VaultPath = paths.Expand(cfg.Obsidian.VaultPath)
 
 	// Create vault directory if it doesn't exist
 	if err := os.MkdirAll(cfg.Obsidian.VaultPath, 0755); err != nil {
@@ -0,0 +1,18 @@
+package paths
+
+import "path/filepath"
+
+// screenPipeCandidates lists where ScreenPipe keeps its data on macOS
+func screenPipeCandidates() []string {
+	return []string{filepath.Join(home(), ".screenpipe", "data")}
+}
+
+// obsidianConfigFiles lists where Obsidian keeps its vault registry
+func obsidianConfigFiles() []string {
+	return []string{filepath.Join(home(), "Library", "Application Support", "obsidian", "obsidian.json")}
+}
+
+// documentsDir returns the user's Documents folder
+func documentsDir() string {
+	return filepath.Join(home(), "Documents")
+}
@@ -0,0 +1,56 @@
+package paths
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// screenPipeCandidates lists where ScreenPipe keeps its data on Linux, most
+// likely first: ScreenPipe's own default, then the XDG data directory
+func screenPipeCandidates() []string {
+	return []string{
+		filepath.Join(home(), ".screenpipe", "data"),
+		filepath.Join(xdgDir("XDG_DATA_HOME", ".local/share"), "screenpipe", "data"),
+	}
+}
+
+// obsidianConfigFiles lists where Obsidian keeps its vault registry for the
+// native, Flatpak and Snap packages
+func obsidianConfigFiles() []string {
+	return []string{
+		filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "obsidian", "obsidian.json"),
+		filepath.Join(home(), ".var", "app", "md.obsidian.Obsidian", "config", "obsidian", "obsidian.json"),
+		filepath.Join(home(), "snap", "obsidian", "current", ".config", "obsidian", "obsidian.json"),
+	}
+}
+
+// documentsDir returns the XDG documents directory from the environment or
+// user-dirs.dirs, falling back to ~/Documents
+func documentsDir() string {
+	if dir := os.Getenv("XDG_DOCUMENTS_DIR"); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	file, err := os.Open(filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "user-dirs.dirs"))
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			value, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "XDG_DOCUMENTS_DIR=")
+			if !found {
+				continue
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			value = strings.Replace(value, "$HOME", home(), 1)
+			if filepath.IsAbs(value) {
+				return value
+			}
+		}
+	}
+	return filepath.Join(home(), "Documents")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// runAudit queries the LLM request audit log by date, source file or provider
+func runAudit(args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	since := flags.String("since", "", "Only records at or after this date (YYYY-MM-DD or RFC3339)")
+	until := flags.String("until", "", "Only records before this date (YYYY-MM-DD or RFC3339)")
+	file := flags.String("file", "", "Only records whose source file contains this text")
+	provider := flags.String("provider", "", "Only records for this provider")
+	asJSON := flags.Bool("json", false, "Print matching records as JSONL")
+	flags.Parse(args)
+
+	cfg, err := config.Read(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	query := audit.Query{File: *file, Provider: *provider}
+	if query.Since, err = parseDate(*since); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -since: %v\n", err)
+		return 2
+	}
+	if query.Until, err = parseDate(*until); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -until: %v\n", err)
+		return 2
+	}
+
+	records, err := audit.Search(cfg.Security.RequestLog.Path, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+		return 0
+	}
+
+	totalTokens := 0
+	for _, record := range records {
+		totalTokens += record.TokenUsage.TotalTokens
+		fmt.Printf("%s  %-7s %-8s %-14s %-20s %6dms %6d tok  %s\n",
+			record.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			record.Outcome, record.Provider, record.Model, record.PromptTemplate,
+			record.LatencyMS, record.TokenUsage.TotalTokens, record.SourceFile)
+		if record.Error != "" {
+			fmt.Printf("    error: %s\n", record.Error)
+		}
+	}
+	fmt.Printf("\n%d records, %d tokens\n", len(records), totalTokens)
+	return 0
+}
+
+// parseDate accepts YYYY-MM-DD (local midnight) or RFC3339; empty means unset
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
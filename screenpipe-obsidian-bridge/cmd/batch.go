@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/processor"
+	"screenpipe-obsidian-bridge/internal/watcher"
+)
+
+// runOnce processes every file currently in the ScreenPipe output directory
+// and exits, for use from cron. Files already turned into notes are skipped.
+func runOnce(args []string) int {
+	flags := flag.NewFlagSet("once", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	return processExisting(*configPath, *overrides, time.Time{}, time.Time{}, processor.Options{})
+}
+
+// runBackfill processes historical files modified within a date range
+func runBackfill(args []string) int {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	since := flags.String("since", "", "Only files modified at or after this date (YYYY-MM-DD or RFC3339)")
+	until := flags.String("until", "", "Only files modified before this date (YYYY-MM-DD or RFC3339)")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	sinceTime, err := parseDate(*since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -since: %v\n", err)
+		return 2
+	}
+	untilTime, err := parseDate(*until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid -until: %v\n", err)
+		return 2
+	}
+
+	return processExisting(*configPath, *overrides, sinceTime, untilTime, processor.Options{})
+}
+
+// runReprocess regenerates notes with the current prompts and template. Each
+// argument is either a note written by the bridge or a source file.
+func runReprocess(args []string) int {
+	flags := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "❌ reprocess needs at least one note or source file")
+		return 2
+	}
+
+	proc, closeAll, err := openProcessor(*configPath, *overrides, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer closeAll()
+
+	sources := make([]string, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		if source, found := proc.SourceForNote(arg); found {
+			sources = append(sources, source)
+			continue
+		}
+		if _, err := os.Stat(arg); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s is neither a note recorded in the ledger nor a readable source file\n", arg)
+			return 1
+		}
+		sources = append(sources, arg)
+	}
+
+	return processPaths(proc, sources, processor.Options{Reprocess: true})
+}
+
+// processExisting runs the files in the output directory modified within
+// [since, until) through the pipeline
+func processExisting(configPath string, overrides []string, since, until time.Time, opts processor.Options) int {
+	proc, closeAll, err := openProcessor(configPath, overrides, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer closeAll()
+
+	paths, err := watcher.Existing(&proc.Config().ScreenPipe, since, until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	return processPaths(proc, paths, opts)
+}
+
+// processPaths processes files one by one until done or interrupted, prints
+// one line per file and a summary, and fails if any file failed
+func processPaths(proc *processor.Processor, paths []string, opts processor.Options) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	processed, skipped, failed := 0, 0, 0
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "🛑 Interrupted")
+			break
+		}
+
+		outcome, err := proc.ProcessFile(ctx, path, opts)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("❌ %s: %v\n", path, err)
+		case outcome.Skipped != "":
+			skipped++
+			fmt.Printf("⏭️  %s (%s)\n", path, outcome.Skipped)
+		default:
+			processed++
+			fmt.Printf("✅ %s → %s\n", path, outcome.NotePath)
+		}
+	}
+
+	fmt.Printf("Processed %d, skipped %d, failed %d of %d files\n", processed, skipped, failed, len(paths))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// openProcessor loads the configuration, sets up logging and creates a
+// processor for one-shot commands. Validation is skipped for commands that
+// never call the LLM.
+func openProcessor(configPath string, overrides []string, validate bool) (*processor.Processor, func(), error) {
+	load := config.Read
+	if validate {
+		load = config.Load
+	}
+	cfg, err := load(configPath, overrides...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	credentials.SetLogger(logging.For(logging.ComponentCredentials))
+	logCloser, err := logging.Setup(&cfg.Logging)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+
+	proc, err := processor.New(cfg)
+	if err != nil {
+		logCloser.Close()
+		return nil, nil, fmt.Errorf("failed to create processor: %w", err)
+	}
+
+	closeAll := func() {
+		proc.Stop()
+		logCloser.Close()
+	}
+	return proc, closeAll, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// setFlags collects repeated -set key=value overrides
+type setFlags []string
+
+// String implements flag.Value
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set implements flag.Value
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// addSetFlag registers -set on a command's flag set
+func addSetFlag(flags *flag.FlagSet) *setFlags {
+	overrides := &setFlags{}
+	flags.Var(overrides, "set", "Override a setting, e.g. -set llm.model=gpt-4o (repeatable)")
+	return overrides
+}
+
+// runConfig dispatches the config subcommands
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ Usage: config print [-effective] | config validate")
+		return 2
+	}
+
+	switch args[0] {
+	case "print":
+		return runConfigPrint(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Unknown config command %q (want print or validate)\n", args[0])
+		return 2
+	}
+}
+
+// runConfigPrint shows settings with their source; secrets are masked
+func runConfigPrint(args []string) int {
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	effective := flags.Bool("effective", false, "Include defaults, showing every resolved setting")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	cfg, err := config.Read(*configPath, *overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	settings := cfg.Settings(*effective)
+	width := 0
+	for _, setting := range settings {
+		if n := len(setting.Key) + len(setting.Value) + 3; n > width {
+			width = n
+		}
+	}
+	for _, setting := range settings {
+		line := setting.Key + " = " + setting.Value
+		fmt.Printf("%-*s  # %s\n", width, line, setting.Source)
+	}
+	return 0
+}
+
+// runConfigValidate loads and validates the configuration, listing every problem
+func runConfigValidate(args []string) int {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	if _, err := config.Load(*configPath, *overrides...); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ Configuration is valid")
+	return 0
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/secure"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+// runKeygen prints a new random encryption key
+func runKeygen(args []string) int {
+	key, err := secure.GenerateKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Println(key)
+	return 0
+}
+
+// runDecrypt decrypts a ledger, cache or note file to stdout or -out
+func runDecrypt(args []string) int {
+	flags := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	out := flags.String("out", "", "Write plaintext to this file instead of stdout")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: decrypt [-config path] [-out file] <encrypted-file>")
+		return 2
+	}
+
+	codec, _, err := loadCodec(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	plaintext, err := codec.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to decrypt %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *out == "" {
+		os.Stdout.Write(plaintext)
+		return 0
+	}
+	if err := os.WriteFile(*out, plaintext, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write %s: %v\n", *out, err)
+		return 1
+	}
+	return 0
+}
+
+// runRekey re-encrypts the state files and encrypted notes with a new key.
+// Plaintext state files are encrypted too, so rekey also migrates existing
+// state after encryption is first enabled. Other files in the state
+// directory, such as the audit log, are left alone.
+//
+// Every file is re-encrypted to a staging file first, and the staged files
+// replace the originals only once all were written, so a failure leaves
+// everything under the old key. Files already under the new key are left
+// as they are, so a run interrupted while renaming can be repeated. The
+// bridge must not be running: rekey holds the state directory's lock.
+func runRekey(args []string) int {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	newKeyEnv := flags.String("new-key-env", "", "Environment variable holding the new base64 key")
+	newKeyFile := flags.String("new-key-file", "", "File holding the new key")
+	flags.Parse(args)
+
+	if *newKeyEnv == "" && *newKeyFile == "" {
+		fmt.Fprintln(os.Stderr, "usage: rekey [-config path] -new-key-env VAR | -new-key-file path")
+		return 2
+	}
+
+	oldCodec, cfg, err := loadCodec(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	newKey, err := secure.LoadKey(*newKeyEnv, *newKeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load new key: %v\n", err)
+		return 1
+	}
+	newCodec, err := secure.NewWithKey(newKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	lock, err := state.LockDir(cfg.Processing.StateDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v; stop the bridge before rekeying\n", err)
+		return 1
+	}
+	defer lock.Unlock()
+
+	// The queue journal is sealed record by record; fold it into the queue
+	// file, which is rekeyed whole. A queue file already rekeyed by an
+	// interrupted run has an empty journal.
+	if err := state.CompactQueue(cfg.Processing.StateDir, oldCodec); err != nil {
+		if state.CompactQueue(cfg.Processing.StateDir, newCodec) != nil {
+			fmt.Fprintf(os.Stderr, "❌ Rekey failed: %v\n", err)
+			return 1
+		}
+	}
+
+	var files []string
+	for _, name := range state.Files {
+		path := filepath.Join(cfg.Processing.StateDir, name)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	writer := obsidian.New(&cfg.Obsidian, &cfg.Security.Encryption, oldCodec)
+	err = filepath.WalkDir(writer.EncryptedNotesPath(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(path, obsidian.EncryptedExtension) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Rekey failed: %v\n", err)
+		return 1
+	}
+
+	// Stage every file before replacing any
+	staged := make(map[string]string, len(files))
+	removeStaged := func() {
+		for _, stagedPath := range staged {
+			os.Remove(stagedPath)
+		}
+	}
+	done, already := 0, 0
+	for _, path := range files {
+		stagedPath, err := stageRekey(path, oldCodec, newCodec)
+		if err != nil {
+			removeStaged()
+			fmt.Fprintf(os.Stderr, "❌ Rekey failed, no file was changed: %s: %v\n", path, err)
+			return 1
+		}
+		if stagedPath == "" {
+			already++
+			continue
+		}
+		staged[path] = stagedPath
+	}
+
+	for _, path := range files {
+		stagedPath, ok := staged[path]
+		if !ok {
+			continue
+		}
+		if err := os.Rename(stagedPath, path); err != nil {
+			removeStaged()
+			fmt.Fprintf(os.Stderr, "❌ Rekey failed: %s: %v\nRun rekey again with the same keys to finish.\n", path, err)
+			return 1
+		}
+		delete(staged, path)
+		done++
+	}
+
+	if already > 0 {
+		fmt.Printf("🔐 %d files were already encrypted with the new key.\n", already)
+	}
+	fmt.Printf("🔐 Re-encrypted %d files. Update security.encryption to use the new key before restarting.\n", done)
+	return 0
+}
+
+// stageRekey writes the content of path, encrypted with newCodec, to a
+// staging file beside it and returns the staging file's path. It returns ""
+// when newCodec already decrypts the file, as it does after an interrupted
+// rekey.
+func stageRekey(path string, oldCodec, newCodec *secure.Codec) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := oldCodec.Open(data)
+	if err != nil {
+		if _, newErr := newCodec.Open(data); newErr == nil {
+			return "", nil
+		}
+		return "", err
+	}
+
+	stagedPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".rekey")
+	if err := newCodec.WriteFile(stagedPath, plaintext, 0600); err != nil {
+		return "", err
+	}
+	return stagedPath, nil
+}
+
+// loadCodec builds the codec described by the configuration file
+func loadCodec(configPath string) (*secure.Codec, *config.Config, error) {
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	codec, err := secure.New(&cfg.Security.Encryption)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set up encryption: %w", err)
+	}
+	return codec, cfg, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/health"
+)
+
+// runDoctor runs the readiness checks once and prints each result with a
+// suggested fix; it exits non-zero when a check fails
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	// Read without validating so the remaining checks still run when the
+	// configuration is invalid
+	cfg, err := config.Read(*configPath, *overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	report := health.NewChecker(cfg).Run(context.Background())
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		for _, result := range report.Results {
+			mark := "✅"
+			switch result.Status {
+			case health.StatusWarn:
+				mark = "⚠️ "
+			case health.StatusFail:
+				mark = "❌"
+			}
+			fmt.Printf("%s %-18s %s\n", mark, result.Name, result.Detail)
+			if result.Fix != "" {
+				fmt.Printf("   %-18s → %s\n", "", result.Fix)
+			}
+		}
+	}
+
+	if !report.Healthy() {
+		return 1
+	}
+	return 0
+}
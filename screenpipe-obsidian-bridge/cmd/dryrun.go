@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/processor"
+	"screenpipe-obsidian-bridge/internal/watcher"
+)
+
+// runDryRun shows the exact prompts each file would send and, optionally,
+// the note it would produce, without calling the LLM, transcription or
+// vision services or touching the vault
+func runDryRun(args []string) int {
+	flags := flag.NewFlagSet("dry-run", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	since := flags.String("since", "", "Without file arguments, only files modified at or after this date")
+	until := flags.String("until", "", "Without file arguments, only files modified before this date")
+	showPrompts := flags.Bool("prompts", true, "Print the full prompts that would be sent")
+	render := flags.Bool("render", false, "Render the resulting note to stdout")
+	response := flags.String("response", "", "Recorded LLM result (ProcessingResult JSON) to render with; implies -render")
+	outDir := flags.String("out", "", "Write rendered notes to this scratch directory instead of stdout; implies -render")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	cfg, err := config.Read(*configPath, *overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+	// A dry run leaves no trace: it logs to stderr only
+	logConfig := cfg.Logging
+	logConfig.File = ""
+	credentials.SetLogger(logging.For(logging.ComponentCredentials))
+	logCloser, err := logging.Setup(&logConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to set up logging: %v\n", err)
+		return 1
+	}
+	defer logCloser.Close()
+
+	previewer, err := processor.NewPreviewer(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	// Notes are rendered from a recorded or placeholder LLM result
+	var responder llm.Client
+	var writer *obsidian.Writer
+	if *render || *response != "" || *outDir != "" {
+		if responder, err = llm.NewReplayClient(*response); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		writer = obsidian.New(&cfg.Obsidian, &config.EncryptionConfig{}, nil)
+		if *outDir != "" {
+			writer = writer.Scratch(*outDir)
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		sinceTime, err := parseDate(*since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid -since: %v\n", err)
+			return 2
+		}
+		untilTime, err := parseDate(*until)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid -until: %v\n", err)
+			return 2
+		}
+		if paths, err = watcher.Existing(&cfg.ScreenPipe, sinceTime, untilTime); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	exitCode := 0
+	for _, path := range paths {
+		preview, err := previewer.Preview(ctx, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", path, err)
+			exitCode = 1
+			continue
+		}
+		printPreview(os.Stdout, preview, *showPrompts)
+
+		if writer == nil || preview.Skipped != "" {
+			continue
+		}
+		result, err := responder.ProcessContent(ctx, preview.Content, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", path, err)
+			exitCode = 1
+			continue
+		}
+		result.Metadata.SourceType = preview.Type
+
+		if *outDir == "" {
+			fmt.Printf("--- rendered note\n%s\n", writer.RenderNote(result))
+			continue
+		}
+		notePath, err := writer.WriteNote(ctx, result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", path, err)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("--- rendered note written to %s\n\n", notePath)
+	}
+	return exitCode
+}
+
+// printPreview writes the prompts a file would send, or why it sends nothing
+func printPreview(w io.Writer, preview *processor.Preview, showPrompts bool) {
+	fmt.Fprintf(w, "=== %s (%s)\n", preview.Path, preview.Type)
+
+	rules := make([]string, 0, len(preview.Excluded))
+	for rule := range preview.Excluded {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		fmt.Fprintf(w, "excluded by %s: %d items\n", rule, preview.Excluded[rule])
+	}
+
+	if preview.Skipped != "" {
+		fmt.Fprintf(w, "nothing would be sent (%s)\n\n", preview.Skipped)
+		return
+	}
+
+	for _, call := range preview.Calls {
+		fmt.Fprintf(w, "would request %s\n", call)
+	}
+	fmt.Fprintf(w, "would send %d prompts for %d characters of content\n", len(preview.Prompts), len(preview.Content))
+	if !showPrompts {
+		fmt.Fprintln(w)
+		return
+	}
+	for _, prompt := range preview.Prompts {
+		fmt.Fprintf(w, "--- prompt %s\n%s\n", prompt.Template, prompt.Text)
+	}
+	fmt.Fprintln(w)
+}
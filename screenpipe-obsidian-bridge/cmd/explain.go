@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/transcribe"
+)
+
+// runExplainRule evaluates the privacy rules against a capture file or a
+// synthetic item and prints why each item was kept or dropped
+func runExplainRule(args []string) int {
+	flags := flag.NewFlagSet("explain-rule", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	app := flags.String("app", "", "Source application name")
+	window := flags.String("window", "", "Window title")
+	url := flags.String("url", "", "Browser URL")
+	at := flags.String("time", "", "Capture time as HH:MM or RFC3339 (default: now)")
+	flags.Parse(args)
+
+	cfg, err := config.Read(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	filter, err := privacy.New(&cfg.Privacy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid privacy rules: %v\n", err)
+		return 1
+	}
+
+	var items []extract.Item
+	if flags.NArg() > 0 {
+		if items, err = explainItems(flags.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to extract %s: %v\n", flags.Arg(0), err)
+			return 1
+		}
+	} else {
+		capturedAt, err := parseCaptureTime(*at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		items = []extract.Item{{App: *app, Window: *window, URL: *url, CapturedAt: capturedAt}}
+	}
+
+	for i, item := range items {
+		decision := filter.Evaluate(item)
+
+		fmt.Printf("Item %d: app=%q window=%q url=%q time=%s\n",
+			i+1, item.App, item.Window, item.URL, item.CapturedAt.Format(time.RFC3339))
+		for _, trace := range decision.Trace {
+			mark := "  "
+			if trace.Matched && trace.Rule == decision.Rule {
+				mark = "→ "
+			}
+			fmt.Printf("  %s%-20s %-8s %s\n", mark, trace.Rule, trace.Action, trace.Detail)
+		}
+
+		switch {
+		case decision.Rule == "" && decision.Keep:
+			fmt.Println("  KEPT (no rule matched, default action is include)")
+		case decision.Rule == "":
+			fmt.Println("  DROPPED (no rule matched, default action is exclude)")
+		case decision.Keep:
+			fmt.Printf("  KEPT by rule %q\n", decision.Rule)
+		default:
+			fmt.Printf("  DROPPED by rule %q\n", decision.Rule)
+		}
+		fmt.Println()
+	}
+
+	return 0
+}
+
+// explainItems returns the items the privacy rules see for a capture file.
+// Audio and images carry no app or window, so like the processor they are
+// judged by capture time alone: an image by its modification time, a
+// recording by the time it started.
+func explainItems(path string) ([]extract.Item, error) {
+	kind := extract.TypeOf(path)
+	if kind == "audio" || kind == "image" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		capturedAt := info.ModTime()
+		if kind == "audio" {
+			if length, err := transcribe.WAVDuration(path); err == nil {
+				capturedAt = capturedAt.Add(-length)
+			}
+		}
+		return []extract.Item{{CapturedAt: capturedAt}}, nil
+	}
+	result, err := extract.File(path)
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// parseCaptureTime accepts HH:MM (today) or a full RFC3339 timestamp
+func parseCaptureTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -time %q (want HH:MM or RFC3339)", value)
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location()), nil
+}
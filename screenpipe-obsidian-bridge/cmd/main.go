@@ -1,17 +1,9 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
-	"log"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"screenpipe-obsidian-bridge/internal/config"
-	"screenpipe-obsidian-bridge/internal/processor"
 )
 
 const (
@@ -20,11 +12,45 @@ const (
 )
 
 func main() {
+	// Dispatch subcommands; without one, the flags below start the daemon
+	// as "run" does
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runRun(os.Args[2:]))
+		case "once":
+			os.Exit(runOnce(os.Args[2:]))
+		case "backfill":
+			os.Exit(runBackfill(os.Args[2:]))
+		case "reprocess":
+			os.Exit(runReprocess(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "dry-run":
+			os.Exit(runDryRun(os.Args[2:]))
+		case "explain-rule":
+			os.Exit(runExplainRule(os.Args[2:]))
+		case "keygen":
+			os.Exit(runKeygen(os.Args[2:]))
+		case "decrypt":
+			os.Exit(runDecrypt(os.Args[2:]))
+		case "rekey":
+			os.Exit(runRekey(os.Args[2:]))
+		case "audit":
+			os.Exit(runAudit(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
 	// Parse command line flags
 	var (
 		configPath = flag.String("config", "", "Path to configuration file")
 		version    = flag.Bool("version", false, "Show version information")
 		help       = flag.Bool("help", false, "Show help information")
+		overrides  = addSetFlag(flag.CommandLine)
 	)
 	flag.Parse()
 
@@ -40,93 +66,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Setup logging
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.Printf("🚀 Starting %s v%s", appName, appVersion)
-
-	// Load configuration
-	cfg, err := config.Load(*configPath)
-	if err != nil {
-		log.Fatalf("❌ Failed to load configuration: %v", err)
-	}
-
-	log.Printf("✅ Configuration loaded successfully")
-	log.Printf("   📂 ScreenPipe Output: %s", cfg.ScreenPipe.OutputPath)
-	log.Printf("   📝 Obsidian Vault: %s", cfg.Obsidian.VaultPath)
-	log.Printf("   🤖 LLM Provider: %s", cfg.LLM.Provider)
-
-	// Create processor
-	proc, err := processor.New(cfg)
-	if err != nil {
-		log.Fatalf("❌ Failed to create processor: %v", err)
-	}
-
-	// Setup context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Setup signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start processor in goroutine
-	errChan := make(chan error, 1)
-	go func() {
-		if err := proc.Start(ctx); err != nil {
-			errChan <- fmt.Errorf("processor failed: %w", err)
-		}
-	}()
-
-	// Status reporting goroutine
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				status := proc.GetStatus()
-				log.Printf("📊 Status: Queue=%d, Processing=%d, Provider=%s",
-					status.QueueLength, status.ProcessingFiles, status.LLMProvider)
-			}
-		}
-	}()
-
-	// Wait for shutdown signal or error
-	select {
-	case sig := <-sigChan:
-		log.Printf("🛑 Received signal: %v", sig)
-		cancel()
-	case err := <-errChan:
-		log.Printf("❌ Application error: %v", err)
-		cancel()
-	}
-
-	// Graceful shutdown
-	log.Println("🔄 Shutting down gracefully...")
-	
-	// Give some time for cleanup
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		if err := proc.Stop(); err != nil {
-			log.Printf("❌ Error during shutdown: %v", err)
-		}
-	}()
-
-	select {
-	case <-done:
-		log.Println("✅ Shutdown completed successfully")
-	case <-shutdownCtx.Done():
-		log.Println("⚠️ Shutdown timeout reached")
-	}
-
-	log.Printf("👋 %s stopped", appName)
+	os.Exit(runDaemon(*configPath, *overrides...))
 }
 
 // showHelp displays usage information
@@ -138,10 +78,24 @@ and generates Obsidian-compatible markdown notes.
 
 USAGE:
     %s [OPTIONS]
+    %s run | once [-config path]
+    %s backfill [-config path] [-since date] [-until date]
+    %s reprocess [-config path] <note|source>...
+    %s dry-run [-config path] [-render] [-response file] [-out dir] [-prompts=false] [file...]
+    %s status [-config path] [-json]
+    %s explain-rule [-config path] [-app name] [-window title] [-url url] [-time HH:MM] [file]
+    %s keygen | decrypt [-out file] <file> | rekey -new-key-env VAR
+    %s audit [-since date] [-until date] [-file text] [-provider name] [-json]
+    %s doctor [-config path] [-json]
+    %s config print [-effective] | config validate
 
 OPTIONS:
     -config string
         Path to configuration file (default: searches common locations)
+
+    -set key=value
+        Override a setting, e.g. -set processing.batch_size=10 (repeatable;
+        also accepted by run, once, backfill, reprocess, dry-run and doctor)
     
     -version
         Show version information
@@ -158,8 +112,58 @@ CONFIGURATION:
     - config.yaml
     - ./config.yaml
 
+COMMANDS:
+    run
+        Watch the ScreenPipe output and process new files (the default)
+
+    once
+        Process the files currently in the output directory and exit (for cron)
+
+    backfill
+        Process historical files modified between -since and -until
+
+    reprocess
+        Regenerate notes in place with the current prompts and template; takes
+        notes written by the bridge or their source files
+
+    dry-run
+        Show the exact (redacted) prompts each file would send without calling
+        the LLM; -render, -response and -out render the resulting note from a
+        recorded or placeholder result to stdout or a scratch directory
+
+    status
+        Show the running daemon's status (requires server.enabled)
+
+    explain-rule
+        Show which privacy rule keeps or drops each item of a capture file,
+        or of a synthetic item described with -app, -window, -url and -time
+
+    keygen
+        Print a new base64 AES-256 key for security.encryption
+
+    decrypt
+        Decrypt an encrypted ledger or note file to stdout (or -out)
+
+    rekey
+        Re-encrypt the state directory and encrypted notes with a new key
+
+    audit
+        Query the LLM request audit log (security.enable_request_logging)
+
+    config
+        print lists the settings from the file with their source line; with
+        -effective, every resolved setting including defaults and overrides.
+        validate reports every configuration problem. Secrets are masked.
+
+    doctor
+        Check configuration, directories, file watching, vault, LLM access and
+        disk space, and print how to fix problems
+
 ENVIRONMENT VARIABLES:
-    OPENAI_API_KEY    OpenAI API key (overrides config file)
+    OPENAI_API_KEY          OpenAI API key (overrides config file)
+    SCREENPIPE_BRIDGE_<KEY> Override any setting, e.g. SCREENPIPE_BRIDGE_LLM_MODEL
+                            for llm.model (lists are comma-separated)
+    SCREENPIPE_BRIDGE_KEY   Base64 encryption key (default security.encryption.key_env)
 
 EXAMPLES:
     # Run with default configuration
@@ -172,5 +176,5 @@ EXAMPLES:
     %s -version
 
 For more information, visit: https://github.com/djb258/screenpipe
-`, appName, appVersion, os.Args[0], os.Args[0], os.Args[0], os.Args[0])
-} 
\ No newline at end of file
+`, appName, appVersion, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/health"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/processor"
+	"screenpipe-obsidian-bridge/internal/server"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+// runRun parses the run subcommand's flags and starts the daemon
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	overrides := addSetFlag(flags)
+	flags.Parse(args)
+
+	return runDaemon(*configPath, *overrides...)
+}
+
+// runDaemon watches the ScreenPipe output and processes new files until it
+// receives SIGINT or SIGTERM. The configuration is reloaded when its file
+// changes or on SIGHUP.
+func runDaemon(configPath string, overrides ...string) int {
+	// Until the configuration is loaded, log at info level to stderr; API
+	// keys are redacted from every log line
+	credentials.SetLogger(logging.For(logging.ComponentCredentials))
+	logger := logging.For(logging.ComponentMain)
+	logger.Info("starting", "app", appName, "version", appVersion)
+
+	// Load configuration
+	cfg, err := config.Load(configPath, overrides...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	// Setup logging
+	logCloser, err := logging.Setup(&cfg.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to set up logging: %v\n", err)
+		return 1
+	}
+	defer func() { logCloser.Close() }()
+
+	logger.Info("configuration loaded",
+		"screenpipe_output", cfg.ScreenPipe.OutputPath,
+		"vault", cfg.Obsidian.VaultPath,
+		"llm_provider", cfg.LLM.Provider)
+	logger.Info("using path", "key", "screenpipe.output_path", "path", cfg.ScreenPipe.OutputPath, "source", cfg.Source("screenpipe.output_path"))
+	logger.Info("using path", "key", "obsidian.vault_path", "path", cfg.Obsidian.VaultPath, "source", cfg.Source("obsidian.vault_path"))
+
+	// Hold the state directory so rekey cannot rewrite it under us
+	lock, err := state.LockDir(cfg.Processing.StateDir)
+	if err != nil {
+		logger.Error("cannot use state directory, is the bridge already running?", "error", err)
+		return 1
+	}
+	defer lock.Unlock()
+
+	// Create processor
+	proc, err := processor.New(cfg)
+	if err != nil {
+		logger.Error("failed to create processor", "error", err)
+		return 1
+	}
+
+	checker := health.NewChecker(cfg)
+
+	// Serve metrics, health checks and status on the bridge's HTTP port
+	var httpServer *server.Server
+	if cfg.Server.Enabled {
+		httpServer = server.New(&cfg.Server)
+		httpServer.Handle("/healthz", health.LivenessHandler())
+		httpServer.Handle("/readyz", checker.ReadinessHandler())
+		httpServer.Handle("/status", statusHandler(proc))
+		if cfg.Deerflow.Inbound {
+			httpServer.Handle("/deerflow", proc.DeerflowHandler())
+		}
+		if err := httpServer.Start(); err != nil {
+			logger.Error("failed to start HTTP server", "error", err)
+			return 1
+		}
+	}
+
+	// Setup context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Setup signal handling
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Start processor in goroutine
+	errChan := make(chan error, 1)
+	go func() {
+		if err := proc.Start(ctx); err != nil {
+			errChan <- fmt.Errorf("processor failed: %w", err)
+		}
+	}()
+
+	// Status reporting goroutine
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				status := proc.GetStatus()
+				logger.Info("status",
+					"queue", status.QueueLength,
+					"falling_behind", status.FallingBehind,
+					"settling", status.PendingFiles,
+					"processing", status.ProcessingFiles,
+					"excluded", status.ExcludedItems,
+					"provider", status.LLMProvider)
+			}
+		}
+	}()
+
+	// Reload on SIGHUP or when the config or a prompt template changes
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	configChanged := watchConfigFiles(watchCtx, logger, cfg)
+
+	reload := func(trigger string) {
+		next, err := reloadConfig(logger, proc, cfg, overrides)
+		if err != nil {
+			logger.Error("rejected new configuration, keeping the running one", "trigger", trigger, "error", err)
+			return
+		}
+		if next == nil {
+			logger.Info("configuration unchanged", "trigger", trigger)
+			return
+		}
+
+		if !reflect.DeepEqual(next.Logging, cfg.Logging) {
+			closer, err := logging.Setup(&next.Logging)
+			if err != nil {
+				logger.Error("failed to apply logging settings", "error", err)
+			} else {
+				logCloser.Close()
+				logCloser = closer
+			}
+		}
+		if !reflect.DeepEqual(next.WatchedFiles(), cfg.WatchedFiles()) {
+			stopWatch()
+			watchCtx, stopWatch = context.WithCancel(ctx)
+			configChanged = watchConfigFiles(watchCtx, logger, next)
+		}
+		checker.SetConfig(next)
+		cfg = next
+	}
+
+	// Wait for shutdown signal or error
+wait:
+	for {
+		select {
+		case sig := <-sigChan:
+			logger.Info("received signal", "signal", sig.String())
+			cancel()
+			break wait
+		case err := <-errChan:
+			logger.Error("application error", "error", err)
+			cancel()
+			break wait
+		case <-reloadChan:
+			reload("SIGHUP")
+		case <-configChanged:
+			reload("file change")
+		}
+	}
+	stopWatch()
+
+	// Graceful shutdown
+	logger.Info("shutting down gracefully")
+
+	// Give some time for cleanup
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := proc.Stop(); err != nil {
+			logger.Error("error during shutdown", "error", err)
+		}
+		if httpServer != nil {
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("error stopping HTTP server", "error", err)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		logger.Info("shutdown completed")
+	case <-shutdownCtx.Done():
+		logger.Warn("shutdown timeout reached")
+	}
+
+	logger.Info("stopped", "app", appName)
+
+	return 0
+}
+
+// reloadConfig loads the configuration again and applies it to proc. It
+// returns nil when nothing changed and an error, with the running
+// configuration untouched, when the new one is invalid or cannot be applied.
+func reloadConfig(logger *slog.Logger, proc *processor.Processor, running *config.Config, overrides []string) (*config.Config, error) {
+	next, err := config.Load(running.Path(), overrides...)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := config.Diff(running, next)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := proc.Reload(next); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if config.RequiresRestart(change.Key) {
+			logger.Warn("setting changed but needs a restart to take effect", "key", change.Key, "running", change.Old, "new", change.New)
+			continue
+		}
+		logger.Info("setting changed", "key", change.Key, "old", change.Old, "new", change.New)
+	}
+	return next, nil
+}
+
+// watchConfigFiles signals changes to the config file and prompt templates.
+// Without a watch, reloads still happen on SIGHUP.
+func watchConfigFiles(ctx context.Context, logger *slog.Logger, cfg *config.Config) <-chan struct{} {
+	changed, err := config.Watch(ctx, cfg.WatchedFiles())
+	if err != nil {
+		logger.Warn("not watching configuration for changes; send SIGHUP to reload", "error", err)
+		return nil
+	}
+	logger.Debug("watching configuration for changes", "files", cfg.WatchedFiles())
+	return changed
+}
+
+// statusHandler serves the processor status as JSON for the status command
+func statusHandler(proc *processor.Processor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(proc.GetStatus())
+	})
+}
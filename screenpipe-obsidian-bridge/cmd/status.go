@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/processor"
+)
+
+// runStatus asks the running daemon for its status over the HTTP server
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to configuration file")
+	asJSON := flags.Bool("json", false, "Print the raw status JSON")
+	flags.Parse(args)
+
+	cfg, err := config.Read(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if !cfg.Server.Enabled {
+		fmt.Fprintln(os.Stderr, "❌ The status command needs server.enabled so it can reach the daemon")
+		return 1
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	response, err := client.Get("http://" + cfg.Server.Listen + "/status")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Daemon not reachable on %s: %v\n", cfg.Server.Listen, err)
+		return 1
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "❌ Daemon returned %s\n", response.Status)
+		return 1
+	}
+
+	var status processor.ProcessorStatus
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid status response: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(status)
+		return 0
+	}
+
+	fmt.Printf("Running since:   %s (%s)\n", status.StartedAt.Local().Format("2006-01-02 15:04:05"), time.Since(status.StartedAt).Round(time.Second))
+	fmt.Printf("LLM provider:    %s\n", status.LLMProvider)
+	fmt.Printf("Watching:        %v\n", status.WatchedPaths)
+	fmt.Printf("Queue:           %d\n", status.QueueLength)
+	if status.QueueLength > 0 {
+		fmt.Printf("Oldest queued:   %s\n", time.Duration(status.QueueOldestAge*float64(time.Second)).Round(time.Second))
+	}
+	if status.FallingBehind {
+		fmt.Println("⚠️  Falling behind: the queue is over its alert depth or age")
+	}
+	fmt.Printf("Settling:        %d\n", status.PendingFiles)
+	fmt.Printf("Processing:      %d\n", status.ProcessingFiles)
+	fmt.Printf("Processed:       %d (failed %d)\n", status.ProcessedFiles, status.FailedFiles)
+	fmt.Printf("Ledger entries:  %d\n", status.LedgerEntries)
+	fmt.Printf("Excluded items:  %d\n", status.ExcludedItems)
+
+	rules := make([]string, 0, len(status.ExcludedByRule))
+	for rule := range status.ExcludedByRule {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		fmt.Printf("  %-16s %d\n", rule, status.ExcludedByRule[rule])
+	}
+	return 0
+}
@@ -0,0 +1,226 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/rotate"
+)
+
+// Outcomes recorded for an LLM call
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Record is one line of the audit log, describing a single LLM call
+type Record struct {
+	Timestamp      time.Time  `json:"timestamp"`
+	Provider       string     `json:"provider"`
+	Model          string     `json:"model"`
+	PromptTemplate string     `json:"prompt_template"`
+	SourceFile     string     `json:"source_file"`
+	ContentHash    string     `json:"content_hash"`
+	CorrelationID  string     `json:"correlation_id,omitempty"`
+	Redaction      Redaction  `json:"redaction"`
+	TokenUsage     TokenUsage `json:"token_usage"`
+	LatencyMS      int64      `json:"latency_ms"`
+	Outcome        string     `json:"outcome"`
+	Error          string     `json:"error,omitempty"`
+	Prompt         string     `json:"prompt,omitempty"`
+	Response       string     `json:"response,omitempty"`
+}
+
+// Redaction summarizes what was removed before and after the call
+type Redaction struct {
+	// ExcludedItems counts capture items dropped by privacy rules
+	ExcludedItems int `json:"excluded_items"`
+	// APIKeys counts API keys masked in the prompt, response or error
+	APIKeys int `json:"api_keys"`
+}
+
+// TokenUsage mirrors the provider's token accounting for the call
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Logger appends audit records to a size-rotated JSONL file. A nil Logger
+// discards records, so callers need not check whether logging is enabled.
+type Logger struct {
+	writer         *rotate.Writer
+	includeContent bool
+	mutex          sync.Mutex
+}
+
+// New opens the audit log, or returns nil when request logging is disabled
+func New(cfg *config.SecurityConfig) (*Logger, error) {
+	if !cfg.EnableRequestLogging {
+		return nil, nil
+	}
+
+	writer, err := rotate.New(cfg.RequestLog.Path, int64(cfg.RequestLog.MaxSizeMB)*1024*1024, cfg.RequestLog.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Logger{
+		writer:         writer,
+		includeContent: cfg.RequestLog.IncludeContent,
+	}, nil
+}
+
+// Log redacts and appends a record. The prompt and response are only kept
+// when the log is configured to include content, and then only API keys are
+// masked: the prompt holds the captured text as sent, after the privacy rules
+// dropped excluded items but otherwise verbatim.
+func (l *Logger) Log(record Record) error {
+	if l == nil {
+		return nil
+	}
+
+	var n int
+	record.Error, n = credentials.RedactCount(record.Error)
+	record.Redaction.APIKeys += n
+	if l.includeContent {
+		record.Prompt, n = credentials.RedactCount(record.Prompt)
+		record.Redaction.APIKeys += n
+		record.Response, n = credentials.RedactCount(record.Response)
+		record.Redaction.APIKeys += n
+	} else {
+		record.Prompt = ""
+		record.Response = ""
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, err := l.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.writer.Close()
+}
+
+// contextKey keys the per-file audit details carried in a context
+type contextKey struct{}
+
+// WithExcludedItems records in ctx how many items privacy rules dropped from
+// the content about to be sent, for the redaction summary
+func WithExcludedItems(ctx context.Context, excluded int) context.Context {
+	return context.WithValue(ctx, contextKey{}, excluded)
+}
+
+// ExcludedItems returns the count stored by WithExcludedItems
+func ExcludedItems(ctx context.Context) int {
+	excluded, _ := ctx.Value(contextKey{}).(int)
+	return excluded
+}
+
+// sourceFileKey keys the captured file carried in a context
+type sourceFileKey struct{}
+
+// WithSourceFile records in ctx the captured file a request is made for,
+// when what is sent is a part of it written elsewhere
+func WithSourceFile(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, sourceFileKey{}, path)
+}
+
+// SourceFile returns the path stored by WithSourceFile, or fallback
+func SourceFile(ctx context.Context, fallback string) string {
+	if path, ok := ctx.Value(sourceFileKey{}).(string); ok && path != "" {
+		return path
+	}
+	return fallback
+}
+
+// Query selects audit records; zero fields match everything
+type Query struct {
+	Since    time.Time
+	Until    time.Time
+	File     string // Substring of the source file path
+	Provider string
+}
+
+// Matches reports whether a record satisfies the query
+func (q Query) Matches(record Record) bool {
+	if !q.Since.IsZero() && record.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !record.Timestamp.Before(q.Until) {
+		return false
+	}
+	if q.File != "" && !strings.Contains(record.SourceFile, q.File) {
+		return false
+	}
+	if q.Provider != "" && !strings.EqualFold(record.Provider, q.Provider) {
+		return false
+	}
+	return true
+}
+
+// Search reads the audit log and its rotated files and returns matching
+// records, oldest first
+func Search(path string, query Query) ([]Record, error) {
+	files := rotate.Files(path)
+	records := []Record{}
+
+	// Rotated files are newest first; read them oldest first
+	for i := len(files) - 1; i >= 0; i-- {
+		matched, err := searchFile(files[i], query)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, matched...)
+	}
+
+	return records, nil
+}
+
+// searchFile returns the matching records of one JSONL file
+func searchFile(path string, query Query) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid audit record: %w", path, line, err)
+		}
+		if query.Matches(record) {
+			records = append(records, record)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,12 @@
+// Package command holds helpers for the external tools the bridge runs, such
+// as ffmpeg and whisper.cpp.
+package command
+
+import "strings"
+
+// LastLine returns the last non-empty line of a command's output, which is
+// where ffmpeg and whisper.cpp report why they failed
+func LastLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
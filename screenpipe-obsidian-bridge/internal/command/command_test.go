@@ -0,0 +1,19 @@
+package command
+
+import "testing"
+
+func TestLastLine(t *testing.T) {
+	tests := []struct {
+		out, want string
+	}{
+		{"", ""},
+		{"failed\n", "failed"},
+		{"frame=1\r\nmoov atom not found\r\n", "moov atom not found"},
+		{"loading model\nerror: failed to open audio\n\n  \n", "error: failed to open audio"},
+	}
+	for _, test := range tests {
+		if got := LastLine(test.out); got != test.want {
+			t.Errorf("LastLine(%q) = %q, want %q", test.out, got, test.want)
+		}
+	}
+}
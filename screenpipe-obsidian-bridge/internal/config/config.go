@@ -1,26 +1,56 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"screenpipe-obsidian-bridge/internal/paths"
 )
 
 // Config represents the application configuration
 type Config struct {
-	ScreenPipe ScreenPipeConfig `yaml:"screenpipe"`
-	LLM        LLMConfig        `yaml:"llm"`
-	Obsidian   ObsidianConfig   `yaml:"obsidian"`
-	Processing ProcessingConfig `yaml:"processing"`
-	Logging    LoggingConfig    `yaml:"logging"`
+	ScreenPipe    ScreenPipeConfig    `yaml:"screenpipe"`
+	LLM           LLMConfig           `yaml:"llm"`
+	Transcription TranscriptionConfig `yaml:"transcription"`
+	Video         VideoConfig         `yaml:"video"`
+	OCR           OCRConfig           `yaml:"ocr"`
+	Vision        VisionConfig        `yaml:"vision"`
+	Obsidian      ObsidianConfig      `yaml:"obsidian"`
+	Tasks         TasksConfig         `yaml:"tasks"`
+	Processing    ProcessingConfig    `yaml:"processing"`
+	Logging       LoggingConfig       `yaml:"logging"`
+	Privacy       PrivacyConfig       `yaml:"privacy"`
+	Security      SecurityConfig      `yaml:"security"`
+	Server        ServerConfig        `yaml:"server"`
+	Deerflow      DeerflowConfig      `yaml:"deerflow"`
+
+	// sources maps setting keys to where their values came from
+	sources map[string]string
+	// path is the file the configuration was read from
+	path string
 }
 
 // ScreenPipeConfig contains ScreenPipe-related settings
 type ScreenPipeConfig struct {
 	OutputPath    string   `yaml:"output_path"`
 	WatchPatterns []string `yaml:"watch_patterns"`
+	// ExcludePatterns skips files and whole subdirectories whose name, or
+	// path relative to OutputPath, matches one of the globs
+	ExcludePatterns []string `yaml:"exclude_patterns"`
+	// PollInterval is how often directories are scanned when the platform's
+	// file watch limit is reached and they cannot be watched natively
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// QuietPeriod is how long a file's size and modification time must stay
+	// unchanged before it is processed
+	QuietPeriod time.Duration `yaml:"quiet_period"`
 }
 
 // LLMConfig contains LLM provider settings
@@ -31,13 +61,300 @@ type LLMConfig struct {
 	Model       string  `yaml:"model"`
 	MaxTokens   int     `yaml:"max_tokens"`
 	Temperature float32 `yaml:"temperature"`
+	// Credentials lists where API keys come from; when empty, APIKey is used
+	Credentials []CredentialSource `yaml:"credentials"`
+	// Pricing overrides the built-in per-model prices used for cost metrics
+	Pricing map[string]ModelPrice `yaml:"pricing"`
+	// Prompts replaces the built-in prompt templates with files
+	Prompts PromptsConfig `yaml:"prompts"`
+	// ExtractEntities sends a fourth prompt asking for the people, projects
+	// and apps in each capture, used by obsidian.entities
+	ExtractEntities bool `yaml:"extract_entities"`
+}
+
+// PromptsConfig names template files for the prompts sent per capture. Each
+// file must contain one %s where the captured content goes; empty paths keep
+// the built-in template.
+type PromptsConfig struct {
+	ActivityAnalysis   string `yaml:"activity_analysis"`
+	TaskExtraction     string `yaml:"task_extraction"`
+	DoctrineCompliance string `yaml:"doctrine_compliance"`
+	EntityExtraction   string `yaml:"entity_extraction"`
+}
+
+// ModelPrice is a model's price in US dollars per million tokens
+type ModelPrice struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
+}
+
+// CredentialSource describes one API key. Exactly one field should be set;
+// listing several sources forms a key pool.
+type CredentialSource struct {
+	// Key is a literal API key
+	Key string `yaml:"key"`
+	// Env names an environment variable read on every request
+	Env string `yaml:"env"`
+	// File is a file whose trimmed contents are the key, re-read when it changes
+	File string `yaml:"file"`
+	// Command is run through the shell and its trimmed output used as the key
+	Command string `yaml:"command"`
+}
+
+// TranscriptionConfig controls how audio captures (wav, mp3, m4a, flac) are
+// turned into text before analysis
+type TranscriptionConfig struct {
+	// Provider is "openai" for an OpenAI-compatible /audio/transcriptions
+	// endpoint, "whisper_cpp" for a local whisper.cpp binary, or empty to
+	// skip audio files
+	Provider string `yaml:"provider"`
+	// Endpoint is the OpenAI-compatible base URL, defaulting to llm.endpoint;
+	// requests use the LLM credentials
+	Endpoint string `yaml:"endpoint"`
+	// Model is the hosted transcription model
+	Model string `yaml:"model"`
+	// Binary is the whisper.cpp executable and ModelPath its ggml model file
+	Binary    string `yaml:"binary"`
+	ModelPath string `yaml:"model_path"`
+	// Language is an ISO-639-1 code; empty detects the spoken language
+	Language string `yaml:"language"`
+	// Timeout bounds the transcription of one file
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxFileMB skips larger audio files; hosted APIs accept up to 25 MB
+	MaxFileMB int `yaml:"max_file_mb"`
+}
+
+// VideoConfig controls how screen recordings (mp4, mkv, mov) are sampled
+// into frames for OCR
+type VideoConfig struct {
+	// FFmpeg is the ffmpeg executable; without it video files are skipped
+	FFmpeg string `yaml:"ffmpeg"`
+	// Sampling is "scene" to take a frame whenever the picture changes by
+	// more than SceneThreshold (0-1), or "interval" for one frame per Interval
+	Sampling       string        `yaml:"sampling"`
+	SceneThreshold float64       `yaml:"scene_threshold"`
+	Interval       time.Duration `yaml:"interval"`
+	// MaxFrames caps the frames sampled from one recording
+	MaxFrames int `yaml:"max_frames"`
+	// DedupeDistance drops frames whose perceptual hash differs from the
+	// previous kept frame in at most this many of 64 bits
+	DedupeDistance int `yaml:"dedupe_distance"`
+	// Timeout bounds sampling and OCR of one file
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxFileMB skips larger recordings
+	MaxFileMB int `yaml:"max_file_mb"`
+}
+
+// OCRConfig configures text recognition on screenshots and sampled frames
+type OCRConfig struct {
+	// Tesseract is the tesseract executable
+	Tesseract string `yaml:"tesseract"`
+	// Languages are tesseract language codes joined by "+", e.g. "eng+deu"
+	Languages string `yaml:"languages"`
+	// Timeout bounds recognizing the text of one screenshot
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// VisionConfig controls describing screenshots (png, jpg) with a
+// vision-capable model in addition to OCR
+type VisionConfig struct {
+	// Provider is "openai" for OpenAI or a local OpenAI-compatible vision
+	// server, "anthropic" for Claude, or empty to rely on OCR alone
+	Provider string `yaml:"provider"`
+	// Endpoint is the API base URL, defaulting to llm.endpoint for openai
+	// and Anthropic's API for anthropic
+	Endpoint string `yaml:"endpoint"`
+	// Model must accept image inputs
+	Model string `yaml:"model"`
+	// Credentials lists where API keys come from; when empty the LLM
+	// credentials are used
+	Credentials []CredentialSource `yaml:"credentials"`
+	// MaxDimension downscales images whose longer side is larger, in pixels
+	MaxDimension int `yaml:"max_dimension"`
+	// MaxUploadKB is the largest image sent; bigger ones are downscaled
+	// further until they fit
+	MaxUploadKB int `yaml:"max_upload_kb"`
+	// MaxTokens bounds the length of the description
+	MaxTokens int `yaml:"max_tokens"`
+	// Timeout bounds the description of one image
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxFileMB skips larger screenshots
+	MaxFileMB int `yaml:"max_file_mb"`
 }
 
 // ObsidianConfig contains Obsidian vault settings
 type ObsidianConfig struct {
-	VaultPath        string `yaml:"vault_path"`
+	VaultPath         string `yaml:"vault_path"`
 	NotesSubdirectory string `yaml:"notes_subdirectory"`
-	FilenameTemplate string `yaml:"filename_template"`
+	FilenameTemplate  string `yaml:"filename_template"`
+	// Attachments stores source media in the vault and embeds it in notes
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	// Entities links notes to notes about the people, projects and apps
+	// they mention
+	Entities EntitiesConfig `yaml:"entities"`
+	// Links links notes to existing vault notes they mention
+	Links LinksConfig `yaml:"links"`
+	// Tasks controls how action items are written
+	Tasks NoteTasksConfig `yaml:"tasks"`
+}
+
+// NoteTasksConfig controls the action items written to notes
+type NoteTasksConfig struct {
+	// Format is "plain" for bare checkboxes or "tasks" for the Obsidian
+	// Tasks plugin's emoji format with dates, priority and recurrence
+	Format string `yaml:"format"`
+	// Tag is added to every action item, e.g. "#task" to match a Tasks
+	// plugin global filter
+	Tag string `yaml:"tag"`
+	// Inbox is a vault-relative note that embeds the open action items of
+	// all generated notes; empty disables it
+	Inbox string `yaml:"inbox"`
+}
+
+// LinksConfig controls wikilinks to notes already in the vault, matched by
+// title and frontmatter aliases
+type LinksConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Exclude lists notes never linked to, as vault-relative path globs
+	// ("Daily/*") or titles and aliases
+	Exclude []string `yaml:"exclude"`
+	// MinLength is the shortest title or alias matched, in characters
+	MinLength int `yaml:"min_length"`
+}
+
+// EntitiesConfig controls wikilinks to per-entity notes. Entities come from
+// the LLM when llm.extract_entities is set and from the Known dictionary.
+type EntitiesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Folders of the entity notes, relative to the vault
+	PeopleFolder   string `yaml:"people_folder"`
+	ProjectsFolder string `yaml:"projects_folder"`
+	AppsFolder     string `yaml:"apps_folder"`
+	// Known lists entities to link whenever they are mentioned, with the
+	// spellings they are mentioned by
+	Known []KnownEntity `yaml:"known"`
+}
+
+// KnownEntity is a person, project (including code repositories) or app
+// that is linked by name or alias
+type KnownEntity struct {
+	Name string `yaml:"name"`
+	// Kind is "person", "project" or "app"
+	Kind    string   `yaml:"kind"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// AttachmentsConfig controls storing screenshots, video keyframes and audio
+// in the vault, so notes stay complete when the vault is synced elsewhere
+type AttachmentsConfig struct {
+	// Mode is "off", "copy", "link" for hard links (copying across
+	// filesystems), or "downscale" to store images and keyframes as JPEG no
+	// larger than MaxDimension
+	Mode string `yaml:"mode"`
+	// Folder overrides Obsidian's attachment location setting, with the same
+	// meaning: "/" is the vault root, "./" and "./sub" are relative to the
+	// note, anything else is relative to the vault
+	Folder string `yaml:"folder"`
+	// Types lists the source types whose media is stored: image, video
+	// (keyframes) and audio
+	Types []string `yaml:"types"`
+	// MaxDimension is the longer side of downscaled images, in pixels
+	MaxDimension int `yaml:"max_dimension"`
+	// MaxFileMB leaves larger source files out of the vault
+	MaxFileMB int `yaml:"max_file_mb"`
+}
+
+// TasksConfig exports action items to task managers and checks the exported
+// tasks off in their notes once they are completed there
+type TasksConfig struct {
+	// Sinks lists the task managers every action item is exported to
+	Sinks []TaskSinkConfig `yaml:"sinks"`
+	// SyncInterval is how often exported tasks are checked for completion,
+	// and failed exports retried
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// VaultName names the vault in the obsidian:// links back to notes;
+	// defaults to the vault folder's name
+	VaultName string `yaml:"vault_name"`
+	// Dedupe merges action items that repeat ones from recent notes
+	Dedupe TaskDedupeConfig `yaml:"dedupe"`
+}
+
+// TaskDedupeConfig controls recognizing an action item extracted again,
+// e.g. from every capture while an email is on screen. A repeat is not
+// written as a new checkbox; the original is marked as seen again.
+type TaskDedupeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Window is how long an action item is remembered after it was last seen
+	Window time.Duration `yaml:"window"`
+	// Similarity is the share of significant words (0-1) two action items
+	// must have in common to be the same
+	Similarity float64 `yaml:"similarity"`
+	// EmbeddingModel, when set, also compares action items by embeddings
+	// from the LLM provider, catching rewordings
+	EmbeddingModel string `yaml:"embedding_model"`
+	// EmbeddingSimilarity is the cosine similarity (0-1) of embeddings of
+	// the same action item
+	EmbeddingSimilarity float64 `yaml:"embedding_similarity"`
+}
+
+// TaskSinkConfig is one task manager
+type TaskSinkConfig struct {
+	// Type is "todoist", "github" or "caldav"
+	Type string `yaml:"type"`
+	// Name identifies the sink in the export state and metrics; defaults to
+	// Type. Renaming a sink exports every open task to it again.
+	Name string `yaml:"name"`
+	// Endpoint is the API base URL for todoist and github (defaulting to
+	// the public APIs) and the task list collection URL for caldav
+	Endpoint string `yaml:"endpoint"`
+	// Project is the Todoist project ID; empty uses the inbox
+	Project string `yaml:"project"`
+	// Repository is the GitHub repository, as owner/name
+	Repository string `yaml:"repository"`
+	// Labels are added to Todoist tasks and GitHub issues
+	Labels []string `yaml:"labels"`
+	// Username authenticates to the CalDAV server together with the key
+	Username string `yaml:"username"`
+	// Credentials lists where the API token (todoist, github) or password
+	// (caldav) comes from; a CalDAV server without authentication needs none
+	Credentials []CredentialSource `yaml:"credentials"`
+}
+
+// SinkName returns the name a sink is known by
+func (s *TaskSinkConfig) SinkName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// DeerflowConfig connects the bridge to a Deerflow workflow: every
+// processed note is POSTed to its webhook, and Deerflow can call back to
+// reprocess a capture or report an action item done
+type DeerflowConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// WebhookURL receives a signed JSON payload for every processed note
+	WebhookURL string `yaml:"webhook_url"`
+	// WorkflowID is sent with every payload
+	WorkflowID string `yaml:"workflow_id"`
+	// APIKey is sent as a bearer token; Credentials take precedence
+	APIKey      string             `yaml:"api_key"`
+	Credentials []CredentialSource `yaml:"credentials"`
+	// Secret is where the shared secret comes from that signs payloads and
+	// inbound calls with HMAC-SHA256
+	Secret []CredentialSource `yaml:"secret"`
+	// Tags and Types limit the notes sent to those with one of the tags or
+	// source types; empty sends every note
+	Tags  []string `yaml:"tags"`
+	Types []string `yaml:"types"`
+	// MaxAttempts bounds the deliveries of a payload; retries wait
+	// RetryDelay, doubled after every failure
+	MaxAttempts int           `yaml:"max_attempts"`
+	RetryDelay  time.Duration `yaml:"retry_delay"`
+	// DeliveryLog is the JSONL file every delivery attempt is recorded in
+	DeliveryLog string `yaml:"delivery_log"`
+	// Inbound serves POST /deerflow on the bridge's HTTP server
+	Inbound bool `yaml:"inbound"`
 }
 
 // ProcessingConfig contains processing behavior settings
@@ -45,16 +362,116 @@ type ProcessingConfig struct {
 	BatchSize           int  `yaml:"batch_size"`
 	BatchDelay          int  `yaml:"batch_delay"`
 	EnableDoctrineCheck bool `yaml:"enable_doctrine_check"`
+	// StateDir holds the processed-file ledger and other persisted state
+	StateDir string `yaml:"state_dir"`
+	// ReconcileInterval is how often the output directory is scanned for
+	// files the watcher missed
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"`
+	// AlertQueueDepth and AlertQueueAge mark the bridge as falling behind
+	// when more files are queued, or the oldest has waited longer
+	AlertQueueDepth int           `yaml:"alert_queue_depth"`
+	AlertQueueAge   time.Duration `yaml:"alert_queue_age"`
+	// MaxAttempts bounds how often a file that fails to process is tried;
+	// retries wait RetryDelay, doubled after every failure up to an hour
+	MaxAttempts int           `yaml:"max_attempts"`
+	RetryDelay  time.Duration `yaml:"retry_delay"`
 }
 
 // LoggingConfig contains logging settings
 type LoggingConfig struct {
 	Level string `yaml:"level"`
 	File  string `yaml:"file"`
+	// Format is "text" (default) or "json"
+	Format string `yaml:"format"`
+	// Components overrides Level per component (watcher, processor, llm, obsidian, ...)
+	Components map[string]string `yaml:"components"`
+	// MaxSizeMB rotates File after this many megabytes, keeping MaxBackups files
+	MaxSizeMB  int `yaml:"max_size_mb"`
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// ServerConfig controls the bridge's HTTP server
+type ServerConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Listen is the address to serve on, e.g. 127.0.0.1:9464
+	Listen string `yaml:"listen"`
+}
+
+// PrivacyConfig contains the capture allow/deny rules
+type PrivacyConfig struct {
+	// DefaultAction applies when no rule matches: "include" or "exclude"
+	DefaultAction string        `yaml:"default_action"`
+	Rules         []PrivacyRule `yaml:"rules"`
+}
+
+// PrivacyRule matches captured items by source app, window title, URL domain
+// and time of day. All non-empty criteria must match for the rule to apply.
+type PrivacyRule struct {
+	Name         string   `yaml:"name"`
+	Action       string   `yaml:"action"`
+	Apps         []string `yaml:"apps"`
+	WindowTitles []string `yaml:"window_titles"`
+	Domains      []string `yaml:"domains"`
+	TimeRanges   []string `yaml:"time_ranges"`
+}
+
+// SecurityConfig contains security-related settings
+type SecurityConfig struct {
+	Encryption EncryptionConfig `yaml:"encryption"`
+	// EnableAPIKeyRotation rotates through the llm.credentials pool every
+	// APIKeyRotationInterval in addition to rotating on 401/429 responses
+	EnableAPIKeyRotation   bool          `yaml:"enable_api_key_rotation"`
+	APIKeyRotationInterval time.Duration `yaml:"api_key_rotation_interval"`
+	// EnableRequestLogging writes a JSONL audit record for every LLM call
+	EnableRequestLogging bool             `yaml:"enable_request_logging"`
+	RequestLog           RequestLogConfig `yaml:"request_log"`
 }
 
-// Load reads and parses the configuration file
-func Load(configPath string) (*Config, error) {
+// RequestLogConfig controls the LLM request audit log
+type RequestLogConfig struct {
+	// Path defaults to audit.jsonl in the state directory
+	Path string `yaml:"path"`
+	// IncludeContent adds the full prompt and response to records. Only API
+	// keys are masked; the prompt holds the captured text as sent.
+	IncludeContent bool `yaml:"include_content"`
+	MaxSizeMB      int  `yaml:"max_size_mb"`
+	MaxBackups     int  `yaml:"max_backups"`
+}
+
+// EncryptionConfig controls at-rest encryption of persisted state and notes
+type EncryptionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// KeyEnv names the environment variable holding the base64 AES-256 key
+	KeyEnv string `yaml:"key_env"`
+	// KeyFile is read when KeyEnv is unset (e.g. a key exported from the OS keyring)
+	KeyFile string `yaml:"key_file"`
+	// EncryptedNoteTypes lists source types (text, json, markdown, ...) whose
+	// notes are written encrypted into EncryptedSubfolder instead of in plaintext
+	EncryptedNoteTypes []string `yaml:"encrypted_note_types"`
+	EncryptedSubfolder string   `yaml:"encrypted_subfolder"`
+}
+
+// Load reads, parses and validates the configuration file. Overrides are
+// key=value settings from -set flags.
+func Load(configPath string, overrides ...string) (*Config, error) {
+	config, err := Read(configPath, overrides...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// Read reads and parses the configuration file without validating it, for
+// commands that only need part of the configuration. Settings are resolved
+// with increasing precedence from defaults, the file, SCREENPIPE_BRIDGE_*
+// environment variables (and OPENAI_API_KEY) and -set overrides.
+func Read(configPath string, overrides ...string) (*Config, error) {
 	// If no path provided, try default locations
 	if configPath == "" {
 		configPath = findDefaultConfig()
@@ -65,50 +482,787 @@ func Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	config := Config{sources: map[string]string{}, path: configPath}
+	if err := config.decodeFile(configPath, data); err != nil {
+		return nil, err
 	}
 
-	// Override API key from environment if set
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		config.LLM.APIKey = apiKey
+	if err := config.applyEnv(); err != nil {
+		return nil, err
 	}
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	if err := config.applyOverrides(overrides); err != nil {
+		return nil, err
 	}
 
+	config.applyDefaults()
+
 	return &config, nil
 }
 
-// Validate checks if the configuration is valid
+// Validate checks the whole configuration and reports every problem, each
+// naming the setting and where its value came from
 func (c *Config) Validate() error {
-	if c.ScreenPipe.OutputPath == "" {
-		return fmt.Errorf("screenpipe.output_path is required")
+	v := &validator{config: c}
+
+	// ScreenPipe
+	if v.required("screenpipe.output_path", c.ScreenPipe.OutputPath) {
+		v.directory("screenpipe.output_path", c.ScreenPipe.OutputPath)
+	}
+	if len(c.ScreenPipe.WatchPatterns) == 0 {
+		v.fail("screenpipe.watch_patterns", "at least one pattern is required, e.g. '*.json'")
+	}
+	for i, pattern := range c.ScreenPipe.WatchPatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			v.fail(fmt.Sprintf("screenpipe.watch_patterns[%d]", i), fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+		}
+	}
+	for i, pattern := range c.ScreenPipe.ExcludePatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			v.fail(fmt.Sprintf("screenpipe.exclude_patterns[%d]", i), fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+		}
+	}
+	if c.ScreenPipe.PollInterval < time.Second {
+		v.fail("screenpipe.poll_interval", fmt.Sprintf("%s is shorter than the 1s minimum", c.ScreenPipe.PollInterval))
+	}
+	if c.ScreenPipe.QuietPeriod < 100*time.Millisecond || c.ScreenPipe.QuietPeriod > time.Hour {
+		v.fail("screenpipe.quiet_period", fmt.Sprintf("%s must be between 100ms and 1h", c.ScreenPipe.QuietPeriod))
 	}
 
-	if c.LLM.APIKey == "" {
-		return fmt.Errorf("llm.api_key is required (set via config or OPENAI_API_KEY env var)")
+	// LLM
+	switch c.LLM.Provider {
+	case "openai":
+		if c.LLM.APIKey == "" && len(c.LLM.Credentials) == 0 {
+			v.fail("llm.api_key", "required for the openai provider (set via config, llm.credentials or OPENAI_API_KEY env var)")
+		}
+	default:
+		v.fail("llm.provider", fmt.Sprintf("unsupported provider %q (supported: openai)", c.LLM.Provider))
+	}
+	if c.LLM.Endpoint != "" {
+		if u, err := url.Parse(c.LLM.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fail("llm.endpoint", fmt.Sprintf("%q is not an http(s) URL", c.LLM.Endpoint))
+		}
+	}
+	v.required("llm.model", c.LLM.Model)
+	if c.LLM.MaxTokens != 0 {
+		// Split evenly across the three prompts; zero leaves it to the provider
+		v.intRange("llm.max_tokens", c.LLM.MaxTokens, 3, 1000000)
+	}
+	if c.LLM.Temperature < 0 || c.LLM.Temperature > 2 {
+		v.fail("llm.temperature", fmt.Sprintf("%g must be between 0 and 2", c.LLM.Temperature))
+	}
+	v.credentials("llm.credentials", c.LLM.Credentials)
+	for model, price := range c.LLM.Pricing {
+		if price.Prompt < 0 || price.Completion < 0 {
+			v.fail("llm.pricing."+model, "prices must not be negative")
+		}
 	}
 
-	if c.Obsidian.VaultPath == "" {
-		return fmt.Errorf("obsidian.vault_path is required")
+	// Transcription
+	switch c.Transcription.Provider {
+	case "":
+	case "openai":
+		if c.Transcription.Endpoint != "" {
+			if u, err := url.Parse(c.Transcription.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fail("transcription.endpoint", fmt.Sprintf("%q is not an http(s) URL", c.Transcription.Endpoint))
+			}
+		}
+		v.required("transcription.model", c.Transcription.Model)
+	case "whisper_cpp":
+		if v.required("transcription.model_path", c.Transcription.ModelPath) {
+			if info, err := os.Stat(c.Transcription.ModelPath); err != nil || info.IsDir() {
+				v.fail("transcription.model_path", fmt.Sprintf("%s is not a whisper.cpp model file", c.Transcription.ModelPath))
+			}
+		}
+	default:
+		v.fail("transcription.provider", fmt.Sprintf("unsupported provider %q (supported: openai, whisper_cpp)", c.Transcription.Provider))
+	}
+	if c.Transcription.Timeout < time.Second {
+		v.fail("transcription.timeout", fmt.Sprintf("%s is shorter than the 1s minimum", c.Transcription.Timeout))
 	}
+	v.intRange("transcription.max_file_mb", c.Transcription.MaxFileMB, 1, 4096)
 
-	// Check if paths exist
-	if _, err := os.Stat(c.ScreenPipe.OutputPath); os.IsNotExist(err) {
-		return fmt.Errorf("screenpipe output path does not exist: %s", c.ScreenPipe.OutputPath)
+	// Video and OCR
+	switch c.Video.Sampling {
+	case "scene":
+		if c.Video.SceneThreshold <= 0 || c.Video.SceneThreshold >= 1 {
+			v.fail("video.scene_threshold", fmt.Sprintf("%g must be between 0 and 1", c.Video.SceneThreshold))
+		}
+	case "interval":
+		if c.Video.Interval < time.Second {
+			v.fail("video.interval", fmt.Sprintf("%s is shorter than the 1s minimum", c.Video.Interval))
+		}
+	default:
+		v.fail("video.sampling", fmt.Sprintf("must be \"scene\" or \"interval\", got %q", c.Video.Sampling))
+	}
+	v.intRange("video.max_frames", c.Video.MaxFrames, 1, 10000)
+	v.intRange("video.dedupe_distance", c.Video.DedupeDistance, 0, 64)
+	if c.Video.Timeout < time.Second {
+		v.fail("video.timeout", fmt.Sprintf("%s is shorter than the 1s minimum", c.Video.Timeout))
+	}
+	v.intRange("video.max_file_mb", c.Video.MaxFileMB, 1, 1<<20)
+	v.required("ocr.languages", c.OCR.Languages)
+	if c.OCR.Timeout < time.Second {
+		v.fail("ocr.timeout", fmt.Sprintf("%s is shorter than the 1s minimum", c.OCR.Timeout))
+	}
+
+	// Vision
+	switch c.Vision.Provider {
+	case "":
+	case "openai", "anthropic":
+		if c.Vision.Endpoint != "" {
+			if u, err := url.Parse(c.Vision.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fail("vision.endpoint", fmt.Sprintf("%q is not an http(s) URL", c.Vision.Endpoint))
+			}
+		}
+		v.required("vision.model", c.Vision.Model)
+	default:
+		v.fail("vision.provider", fmt.Sprintf("unsupported provider %q (supported: openai, anthropic)", c.Vision.Provider))
+	}
+	v.credentials("vision.credentials", c.Vision.Credentials)
+	v.intRange("vision.max_dimension", c.Vision.MaxDimension, 64, 8192)
+	v.intRange("vision.max_upload_kb", c.Vision.MaxUploadKB, 16, 20480)
+	v.intRange("vision.max_tokens", c.Vision.MaxTokens, 16, 100000)
+	if c.Vision.Timeout < time.Second {
+		v.fail("vision.timeout", fmt.Sprintf("%s is shorter than the 1s minimum", c.Vision.Timeout))
+	}
+	v.intRange("vision.max_file_mb", c.Vision.MaxFileMB, 1, 1024)
+
+	// Obsidian
+	if v.required("obsidian.vault_path", c.Obsidian.VaultPath) {
+		v.directory("obsidian.vault_path", c.Obsidian.VaultPath)
+	}
+	if err := ValidateFilenameTemplate(c.Obsidian.FilenameTemplate); err != nil {
+		v.fail("obsidian.filename_template", err.Error())
+	}
+
+	switch c.Obsidian.Attachments.Mode {
+	case "off", "copy", "link", "downscale":
+	default:
+		v.fail("obsidian.attachments.mode", fmt.Sprintf("must be off, copy, link or downscale, got %q", c.Obsidian.Attachments.Mode))
+	}
+	for _, sourceType := range c.Obsidian.Attachments.Types {
+		if sourceType != "image" && sourceType != "video" && sourceType != "audio" {
+			v.fail("obsidian.attachments.types", fmt.Sprintf("unknown type %q (supported: image, video, audio)", sourceType))
+		}
+	}
+	if strings.Contains(c.Obsidian.Attachments.Folder, "..") {
+		v.fail("obsidian.attachments.folder", fmt.Sprintf("%q must stay inside the vault", c.Obsidian.Attachments.Folder))
+	}
+	for i, entity := range c.Obsidian.Entities.Known {
+		key := fmt.Sprintf("obsidian.entities.known[%d]", i)
+		if strings.TrimSpace(entity.Name) == "" {
+			v.fail(key, "name is required")
+		} else if strings.ContainsAny(entity.Name, `/\[]|#^:`) {
+			v.fail(key, fmt.Sprintf("name %q cannot contain any of / \\ [ ] | # ^ :", entity.Name))
+		}
+		if entity.Kind != "person" && entity.Kind != "project" && entity.Kind != "app" {
+			v.fail(key, fmt.Sprintf("kind must be person, project or app, got %q", entity.Kind))
+		}
+	}
+	for _, folder := range []struct{ key, path string }{
+		{"obsidian.entities.people_folder", c.Obsidian.Entities.PeopleFolder},
+		{"obsidian.entities.projects_folder", c.Obsidian.Entities.ProjectsFolder},
+		{"obsidian.entities.apps_folder", c.Obsidian.Entities.AppsFolder},
+	} {
+		if filepath.IsAbs(folder.path) || strings.Contains(folder.path, "..") {
+			v.fail(folder.key, fmt.Sprintf("%q must be a folder inside the vault", folder.path))
+		}
+	}
+	for _, pattern := range c.Obsidian.Links.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			v.fail("obsidian.links.exclude", fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+		}
+	}
+	v.intRange("obsidian.links.min_length", c.Obsidian.Links.MinLength, 1, 100)
+	if c.Obsidian.Tasks.Format != "plain" && c.Obsidian.Tasks.Format != "tasks" {
+		v.fail("obsidian.tasks.format", fmt.Sprintf("must be plain or tasks, got %q", c.Obsidian.Tasks.Format))
+	}
+	if tag := c.Obsidian.Tasks.Tag; tag != "" && (!strings.HasPrefix(tag, "#") || len(tag) == 1 || strings.ContainsAny(tag, " \t")) {
+		v.fail("obsidian.tasks.tag", fmt.Sprintf("%q must be a single #tag", tag))
+	}
+	if filepath.IsAbs(c.Obsidian.Tasks.Inbox) || strings.Contains(c.Obsidian.Tasks.Inbox, "..") {
+		v.fail("obsidian.tasks.inbox", fmt.Sprintf("%q must be a note inside the vault", c.Obsidian.Tasks.Inbox))
+	}
+	v.intRange("obsidian.attachments.max_dimension", c.Obsidian.Attachments.MaxDimension, 64, 8192)
+	v.intRange("obsidian.attachments.max_file_mb", c.Obsidian.Attachments.MaxFileMB, 1, 4096)
+
+	// Tasks
+	if c.Tasks.Dedupe.Window < time.Minute {
+		v.fail("tasks.dedupe.window", fmt.Sprintf("%s is shorter than the 1m minimum", c.Tasks.Dedupe.Window))
+	}
+	if c.Tasks.Dedupe.Similarity <= 0 || c.Tasks.Dedupe.Similarity > 1 {
+		v.fail("tasks.dedupe.similarity", fmt.Sprintf("%g must be above 0 and at most 1", c.Tasks.Dedupe.Similarity))
+	}
+	if c.Tasks.Dedupe.EmbeddingSimilarity <= 0 || c.Tasks.Dedupe.EmbeddingSimilarity > 1 {
+		v.fail("tasks.dedupe.embedding_similarity", fmt.Sprintf("%g must be above 0 and at most 1", c.Tasks.Dedupe.EmbeddingSimilarity))
+	}
+	sinkNames := make(map[string]bool)
+	for i, sink := range c.Tasks.Sinks {
+		key := fmt.Sprintf("tasks.sinks[%d]", i)
+		switch sink.Type {
+		case "todoist":
+		case "github":
+			if owner, name, ok := strings.Cut(sink.Repository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+				v.fail(key+".repository", fmt.Sprintf("%q must be owner/name", sink.Repository))
+			}
+		case "caldav":
+			v.required(key+".endpoint", sink.Endpoint)
+		default:
+			v.fail(key+".type", fmt.Sprintf("unsupported task sink %q (supported: todoist, github, caldav)", sink.Type))
+		}
+		if sink.Endpoint != "" {
+			if u, err := url.Parse(sink.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fail(key+".endpoint", fmt.Sprintf("%q is not an http(s) URL", sink.Endpoint))
+			}
+		}
+		if len(sink.Credentials) == 0 && sink.Type != "caldav" {
+			v.fail(key+".credentials", "an API token is required")
+		}
+		v.credentials(key+".credentials", sink.Credentials)
+		if sinkNames[sink.SinkName()] {
+			v.fail(key+".name", fmt.Sprintf("%q is used by more than one sink", sink.SinkName()))
+		}
+		sinkNames[sink.SinkName()] = true
+	}
+	if c.Tasks.SyncInterval < 30*time.Second {
+		v.fail("tasks.sync_interval", fmt.Sprintf("%s is shorter than the 30s minimum", c.Tasks.SyncInterval))
+	}
+
+	// Processing
+	v.intRange("processing.batch_size", c.Processing.BatchSize, 1, 1000)
+	v.intRange("processing.batch_delay", c.Processing.BatchDelay, 1, 86400)
+	if c.Processing.ReconcileInterval < 10*time.Second {
+		v.fail("processing.reconcile_interval", fmt.Sprintf("%s is shorter than the 10s minimum", c.Processing.ReconcileInterval))
+	}
+	v.intRange("processing.alert_queue_depth", c.Processing.AlertQueueDepth, 1, 1000000)
+	if c.Processing.AlertQueueAge < time.Second {
+		v.fail("processing.alert_queue_age", fmt.Sprintf("%s is shorter than the 1s minimum", c.Processing.AlertQueueAge))
+	}
+	v.intRange("processing.max_attempts", c.Processing.MaxAttempts, 1, 100)
+	if c.Processing.RetryDelay < time.Second {
+		v.fail("processing.retry_delay", fmt.Sprintf("%s is shorter than the 1s minimum", c.Processing.RetryDelay))
+	}
+
+	// Logging
+	if !validLevel(c.Logging.Level) {
+		v.fail("logging.level", fmt.Sprintf("unknown level %q (want debug, info, warn or error)", c.Logging.Level))
+	}
+	switch c.Logging.Format {
+	case "", "text", "json":
+	default:
+		v.fail("logging.format", fmt.Sprintf("must be \"text\" or \"json\", got %q", c.Logging.Format))
+	}
+	for component, level := range c.Logging.Components {
+		if !validLevel(level) {
+			v.fail("logging.components."+component, fmt.Sprintf("unknown level %q (want debug, info, warn or error)", level))
+		}
 	}
+	v.intRange("logging.max_size_mb", c.Logging.MaxSizeMB, 1, 10240)
+	v.intRange("logging.max_backups", c.Logging.MaxBackups, 0, 1000)
 
-	if _, err := os.Stat(c.Obsidian.VaultPath); os.IsNotExist(err) {
-		return fmt.Errorf("obsidian vault path does not exist: %s", c.Obsidian.VaultPath)
+	// Privacy
+	switch c.Privacy.DefaultAction {
+	case "", "include", "exclude":
+	default:
+		v.fail("privacy.default_action", fmt.Sprintf("must be \"include\" or \"exclude\", got %q", c.Privacy.DefaultAction))
+	}
+	for i, rule := range c.Privacy.Rules {
+		if rule.Action != "include" && rule.Action != "exclude" {
+			v.fail(fmt.Sprintf("privacy.rules[%d].action", i), fmt.Sprintf("must be \"include\" or \"exclude\", got %q", rule.Action))
+		}
+		for j, timeRange := range rule.TimeRanges {
+			if !timeRangePattern.MatchString(strings.TrimSpace(timeRange)) {
+				v.fail(fmt.Sprintf("privacy.rules[%d].time_ranges[%d]", i, j), fmt.Sprintf("%q is not HH:MM-HH:MM", timeRange))
+			}
+		}
+	}
+
+	// Security
+	if c.Security.EnableAPIKeyRotation && c.Security.APIKeyRotationInterval < time.Minute {
+		v.fail("security.api_key_rotation_interval", fmt.Sprintf("%s is shorter than the 1m minimum", c.Security.APIKeyRotationInterval))
+	}
+	v.intRange("security.request_log.max_size_mb", c.Security.RequestLog.MaxSizeMB, 1, 10240)
+	v.intRange("security.request_log.max_backups", c.Security.RequestLog.MaxBackups, 0, 1000)
+	if c.Security.Encryption.Enabled && c.Security.Encryption.KeyEnv == "" && c.Security.Encryption.KeyFile == "" {
+		v.fail("security.encryption.key_env", "encryption needs key_env or key_file")
+	}
+	if c.Security.Encryption.Enabled && c.Security.EnableRequestLogging && c.Security.RequestLog.IncludeContent {
+		v.fail("security.request_log.include_content", "the audit log is not encrypted; prompts and responses cannot be kept with security.encryption enabled")
 	}
 
+	// Deerflow
+	if c.Deerflow.Enabled {
+		if v.required("deerflow.webhook_url", c.Deerflow.WebhookURL) {
+			if u, err := url.Parse(c.Deerflow.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fail("deerflow.webhook_url", fmt.Sprintf("%q is not an http(s) URL", c.Deerflow.WebhookURL))
+			}
+		}
+		if len(c.Deerflow.Secret) == 0 {
+			v.fail("deerflow.secret", "a signing secret is required")
+		}
+		if c.Deerflow.Inbound && !c.Server.Enabled {
+			v.fail("deerflow.inbound", "needs server.enabled")
+		}
+	}
+	v.credentials("deerflow.credentials", c.Deerflow.Credentials)
+	v.credentials("deerflow.secret", c.Deerflow.Secret)
+	v.intRange("deerflow.max_attempts", c.Deerflow.MaxAttempts, 1, 100)
+	if c.Deerflow.RetryDelay < time.Second {
+		v.fail("deerflow.retry_delay", fmt.Sprintf("%s is shorter than the 1s minimum", c.Deerflow.RetryDelay))
+	}
+
+	// Server
+	if c.Server.Enabled {
+		if _, port, err := net.SplitHostPort(c.Server.Listen); err != nil || port == "" {
+			v.fail("server.listen", fmt.Sprintf("%q is not a host:port address", c.Server.Listen))
+		}
+	}
+
+	return v.err()
+}
+
+// timeRangePattern matches HH:MM-HH:MM privacy time ranges
+var timeRangePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d-([01]\d|2[0-3]):[0-5]\d$`)
+
+// validLevel reports whether text is a known log level
+func validLevel(text string) bool {
+	switch strings.ToLower(text) {
+	case "", "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+// FilenameFields are the placeholders supported in obsidian.filename_template
+var FilenameFields = []string{"{{.Timestamp}}", "{{.Hash}}", "{{.Date}}", "{{.Time}}"}
+
+// ValidateFilenameTemplate checks that a filename template only uses known
+// placeholders and cannot produce paths outside the notes directory
+func ValidateFilenameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("filename template is empty")
+	}
+
+	rest := template
+	for _, field := range FilenameFields {
+		rest = strings.ReplaceAll(rest, field, "")
+	}
+	if start := strings.Index(rest, "{{"); start >= 0 {
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return fmt.Errorf("unterminated placeholder in %q", template)
+		}
+		return fmt.Errorf("unknown placeholder %s (supported: %s)", rest[start:start+end+2], strings.Join(FilenameFields, ", "))
+	}
+	if strings.ContainsAny(rest, `/\`) || strings.Contains(rest, "..") {
+		return fmt.Errorf("filename template %q must not contain path separators", template)
+	}
 	return nil
 }
 
+// validator collects validation problems
+type validator struct {
+	config   *Config
+	problems []string
+}
+
+// fail records a problem with the setting key
+func (v *validator) fail(key, message string) {
+	v.problems = append(v.problems, fmt.Sprintf("%s (%s): %s", key, v.config.Source(key), message))
+}
+
+// required records a problem when value is empty and reports whether it was set
+func (v *validator) required(key, value string) bool {
+	if value == "" {
+		v.fail(key, "is required")
+		return false
+	}
+	return true
+}
+
+// directory records a problem unless path is an existing directory
+func (v *validator) directory(key, path string) {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		v.fail(key, fmt.Sprintf("%s does not exist", path))
+	case err != nil:
+		v.fail(key, fmt.Sprintf("%s is not accessible: %v", path, err))
+	case !info.IsDir():
+		v.fail(key, fmt.Sprintf("%s is not a directory", path))
+	}
+}
+
+// intRange records a problem unless min <= value <= max
+func (v *validator) intRange(key string, value, min, max int) {
+	if value < min || value > max {
+		v.fail(key, fmt.Sprintf("%d must be between %d and %d", value, min, max))
+	}
+}
+
+// credentials checks that every source in a credential list names one key
+func (v *validator) credentials(key string, sources []CredentialSource) {
+	for i, source := range sources {
+		set := 0
+		for _, value := range []string{source.Key, source.Env, source.File, source.Command} {
+			if value != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			v.fail(fmt.Sprintf("%s[%d]", key, i), "must set exactly one of key, env, file or command")
+		}
+	}
+}
+
+// err combines the problems into one error, one per line
+func (v *validator) err() error {
+	switch len(v.problems) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New(v.problems[0])
+	}
+	return fmt.Errorf("%d problems:\n  %s", len(v.problems), strings.Join(v.problems, "\n  "))
+}
+
+// applyDefaults fills in optional settings that were left empty
+func (c *Config) applyDefaults() {
+	c.expandPaths()
+
+	// Locate ScreenPipe's data and the Obsidian vault when not configured,
+	// recording why each path was chosen as its source
+	if c.ScreenPipe.OutputPath == "" {
+		choice := paths.ScreenPipeData()
+		c.ScreenPipe.OutputPath = choice.Path
+		c.sources["screenpipe.output_path"] = SourceDefault + ": " + choice.Reason
+	}
+
+	if c.Obsidian.VaultPath == "" {
+		choice := paths.ObsidianVault()
+		c.Obsidian.VaultPath = choice.Path
+		c.sources["obsidian.vault_path"] = SourceDefault + ": " + choice.Reason
+	}
+
+	if c.Processing.StateDir == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			c.Processing.StateDir = filepath.Join(dir, "screenpipe-obsidian-bridge")
+		} else {
+			c.Processing.StateDir = ".screenpipe-bridge"
+		}
+	}
+
+	if c.Security.Encryption.KeyEnv == "" {
+		c.Security.Encryption.KeyEnv = "SCREENPIPE_BRIDGE_KEY"
+	}
+
+	if c.LLM.Provider == "" {
+		c.LLM.Provider = "openai"
+	}
+
+	if c.Obsidian.FilenameTemplate == "" {
+		c.Obsidian.FilenameTemplate = "screenpipe-{{.Timestamp}}-{{.Hash}}.md"
+	}
+
+	if c.Processing.BatchSize == 0 {
+		c.Processing.BatchSize = 5
+	}
+
+	if c.Processing.BatchDelay == 0 {
+		c.Processing.BatchDelay = 30
+	}
+
+	if c.Processing.ReconcileInterval == 0 {
+		c.Processing.ReconcileInterval = 5 * time.Minute
+	}
+
+	if c.Processing.AlertQueueDepth == 0 {
+		c.Processing.AlertQueueDepth = 100
+	}
+
+	if c.Processing.AlertQueueAge == 0 {
+		c.Processing.AlertQueueAge = 10 * time.Minute
+	}
+
+	if c.Processing.MaxAttempts == 0 {
+		c.Processing.MaxAttempts = 8
+	}
+
+	if c.Processing.RetryDelay == 0 {
+		c.Processing.RetryDelay = time.Minute
+	}
+
+	if c.Logging.Level == "" {
+		c.Logging.Level = "info"
+	}
+
+	if c.Logging.Format == "" {
+		c.Logging.Format = "text"
+	}
+
+	if c.Privacy.DefaultAction == "" {
+		c.Privacy.DefaultAction = "include"
+	}
+
+	if c.Logging.MaxSizeMB == 0 {
+		c.Logging.MaxSizeMB = 10
+	}
+
+	// An explicit 0 keeps no old files
+	if c.Logging.MaxBackups == 0 && !c.isSet("logging.max_backups") {
+		c.Logging.MaxBackups = 5
+	}
+
+	if c.Security.RequestLog.Path == "" {
+		c.Security.RequestLog.Path = filepath.Join(c.Processing.StateDir, "audit.jsonl")
+	}
+
+	if c.Deerflow.MaxAttempts == 0 {
+		c.Deerflow.MaxAttempts = 5
+	}
+
+	if c.Deerflow.RetryDelay == 0 {
+		c.Deerflow.RetryDelay = 30 * time.Second
+	}
+
+	if c.Deerflow.DeliveryLog == "" {
+		c.Deerflow.DeliveryLog = filepath.Join(c.Processing.StateDir, "deerflow-deliveries.jsonl")
+	}
+
+	if c.Security.RequestLog.MaxSizeMB == 0 {
+		c.Security.RequestLog.MaxSizeMB = 10
+	}
+
+	if c.Security.RequestLog.MaxBackups == 0 && !c.isSet("security.request_log.max_backups") {
+		c.Security.RequestLog.MaxBackups = 5
+	}
+
+	if c.ScreenPipe.PollInterval == 0 {
+		c.ScreenPipe.PollInterval = 10 * time.Second
+	}
+
+	if c.ScreenPipe.QuietPeriod == 0 {
+		c.ScreenPipe.QuietPeriod = 2 * time.Second
+	}
+
+	if c.Security.APIKeyRotationInterval == 0 {
+		c.Security.APIKeyRotationInterval = 24 * time.Hour
+	}
+
+	if c.Server.Listen == "" {
+		c.Server.Listen = "127.0.0.1:9464"
+	}
+
+	if c.Transcription.Model == "" {
+		c.Transcription.Model = "whisper-1"
+	}
+
+	if c.Transcription.Binary == "" {
+		c.Transcription.Binary = "whisper-cli"
+	}
+
+	if c.Transcription.Timeout == 0 {
+		c.Transcription.Timeout = 10 * time.Minute
+	}
+
+	if c.Transcription.MaxFileMB == 0 {
+		c.Transcription.MaxFileMB = 25
+	}
+
+	if c.Video.FFmpeg == "" {
+		c.Video.FFmpeg = "ffmpeg"
+	}
+
+	if c.Video.Sampling == "" {
+		c.Video.Sampling = "scene"
+	}
+
+	if c.Video.SceneThreshold == 0 {
+		c.Video.SceneThreshold = 0.3
+	}
+
+	if c.Video.Interval == 0 {
+		c.Video.Interval = 10 * time.Second
+	}
+
+	if c.Video.MaxFrames == 0 {
+		c.Video.MaxFrames = 60
+	}
+
+	if c.Video.DedupeDistance == 0 {
+		c.Video.DedupeDistance = 6
+	}
+
+	if c.Video.Timeout == 0 {
+		c.Video.Timeout = 10 * time.Minute
+	}
+
+	if c.Video.MaxFileMB == 0 {
+		c.Video.MaxFileMB = 500
+	}
+
+	if c.OCR.Tesseract == "" {
+		c.OCR.Tesseract = "tesseract"
+	}
+
+	if c.OCR.Languages == "" {
+		c.OCR.Languages = "eng"
+	}
+
+	if c.OCR.Timeout == 0 {
+		c.OCR.Timeout = time.Minute
+	}
+
+	if c.Obsidian.Attachments.Mode == "" {
+		c.Obsidian.Attachments.Mode = "off"
+	}
+
+	if c.Obsidian.Attachments.Types == nil {
+		c.Obsidian.Attachments.Types = []string{"image", "video"}
+	}
+
+	if c.Obsidian.Attachments.MaxDimension == 0 {
+		c.Obsidian.Attachments.MaxDimension = 1280
+	}
+
+	if c.Obsidian.Attachments.MaxFileMB == 0 {
+		c.Obsidian.Attachments.MaxFileMB = 50
+	}
+
+	if c.Obsidian.Entities.PeopleFolder == "" {
+		c.Obsidian.Entities.PeopleFolder = "People"
+	}
+
+	if c.Obsidian.Entities.ProjectsFolder == "" {
+		c.Obsidian.Entities.ProjectsFolder = "Projects"
+	}
+
+	if c.Obsidian.Entities.AppsFolder == "" {
+		c.Obsidian.Entities.AppsFolder = "Apps"
+	}
+
+	if c.Obsidian.Links.MinLength == 0 {
+		c.Obsidian.Links.MinLength = 4
+	}
+
+	if c.Obsidian.Tasks.Format == "" {
+		c.Obsidian.Tasks.Format = "plain"
+	}
+
+	if c.Obsidian.Tasks.Inbox != "" && !strings.HasSuffix(c.Obsidian.Tasks.Inbox, ".md") {
+		c.Obsidian.Tasks.Inbox += ".md"
+	}
+
+	if c.Vision.Model == "" {
+		switch c.Vision.Provider {
+		case "openai":
+			c.Vision.Model = "gpt-4o-mini"
+		case "anthropic":
+			c.Vision.Model = "claude-3-5-sonnet-latest"
+		}
+	}
+
+	if c.Vision.MaxDimension == 0 {
+		// Claude and OpenAI both scale larger images down to about this size
+		c.Vision.MaxDimension = 1568
+	}
+
+	if c.Vision.MaxUploadKB == 0 {
+		c.Vision.MaxUploadKB = 3072
+	}
+
+	if c.Vision.MaxTokens == 0 {
+		c.Vision.MaxTokens = 500
+	}
+
+	if c.Vision.Timeout == 0 {
+		c.Vision.Timeout = time.Minute
+	}
+
+	if c.Vision.MaxFileMB == 0 {
+		c.Vision.MaxFileMB = 20
+	}
+
+	if c.Tasks.SyncInterval == 0 {
+		c.Tasks.SyncInterval = 5 * time.Minute
+	}
+
+	if c.Tasks.Dedupe.Window == 0 {
+		c.Tasks.Dedupe.Window = 7 * 24 * time.Hour
+	}
+
+	if c.Tasks.Dedupe.Similarity == 0 {
+		c.Tasks.Dedupe.Similarity = 0.6
+	}
+
+	if c.Tasks.Dedupe.EmbeddingSimilarity == 0 {
+		c.Tasks.Dedupe.EmbeddingSimilarity = 0.9
+	}
+
+	if c.Security.Encryption.EncryptedSubfolder == "" {
+		c.Security.Encryption.EncryptedSubfolder = "Encrypted"
+	}
+}
+
+// expandPaths expands ~ and environment variables in every path setting, so
+// values from the file, the environment and flags behave the same
+func (c *Config) expandPaths() {
+	for _, path := range []*string{
+		&c.ScreenPipe.OutputPath,
+		&c.Obsidian.VaultPath,
+		&c.Processing.StateDir,
+		&c.Logging.File,
+		&c.Security.RequestLog.Path,
+		&c.Security.Encryption.KeyFile,
+		&c.Deerflow.DeliveryLog,
+		&c.LLM.Prompts.ActivityAnalysis,
+		&c.LLM.Prompts.TaskExtraction,
+		&c.LLM.Prompts.DoctrineCompliance,
+		&c.LLM.Prompts.EntityExtraction,
+		&c.Transcription.Binary,
+		&c.Transcription.ModelPath,
+		&c.Video.FFmpeg,
+		&c.OCR.Tesseract,
+	} {
+		*path = paths.Expand(*path)
+	}
+	for i := range c.LLM.Credentials {
+		c.LLM.Credentials[i].File = paths.Expand(c.LLM.Credentials[i].File)
+	}
+	for i := range c.Vision.Credentials {
+		c.Vision.Credentials[i].File = paths.Expand(c.Vision.Credentials[i].File)
+	}
+	for i := range c.Deerflow.Credentials {
+		c.Deerflow.Credentials[i].File = paths.Expand(c.Deerflow.Credentials[i].File)
+	}
+	for i := range c.Deerflow.Secret {
+		c.Deerflow.Secret[i].File = paths.Expand(c.Deerflow.Secret[i].File)
+	}
+	for i := range c.Tasks.Sinks {
+		for j := range c.Tasks.Sinks[i].Credentials {
+			c.Tasks.Sinks[i].Credentials[j].File = paths.Expand(c.Tasks.Sinks[i].Credentials[j].File)
+		}
+	}
+}
+
+// Path returns the file the configuration was read from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// WatchedFiles lists the files whose changes should trigger a reload: the
+// config file and any prompt template files
+func (c *Config) WatchedFiles() []string {
+	files := []string{c.path}
+	for _, prompt := range []string{c.LLM.Prompts.ActivityAnalysis, c.LLM.Prompts.TaskExtraction, c.LLM.Prompts.DoctrineCompliance, c.LLM.Prompts.EntityExtraction} {
+		if prompt != "" {
+			files = append(files, prompt)
+		}
+	}
+	return files
+}
+
 // findDefaultConfig searches for config files in standard locations
 func findDefaultConfig() string {
 	possiblePaths := []string{
@@ -129,4 +1283,4 @@ func findDefaultConfig() string {
 // GetObsidianNotesPath returns the full path to the ScreenPipe notes directory
 func (c *Config) GetObsidianNotesPath() string {
 	return filepath.Join(c.Obsidian.VaultPath, c.Obsidian.NotesSubdirectory)
-} 
\ No newline at end of file
+}
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override settings, e.g.
+// SCREENPIPE_BRIDGE_LLM_MODEL for llm.model
+const EnvPrefix = "SCREENPIPE_BRIDGE_"
+
+// Sources of a setting, from lowest to highest precedence after the defaults
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag -set"
+)
+
+// secretKeys are masked when settings are printed
+var secretKeys = map[string]bool{
+	"llm.api_key":      true,
+	"deerflow.api_key": true,
+}
+
+// field is a settable scalar setting and its dotted key
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// Setting is one resolved setting for display
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// EnvName returns the environment variable that overrides key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// decodeFile parses YAML into c and records the line each key was set on.
+// Unknown keys and malformed values are reported by key and line.
+func (c *Config) decodeFile(path string, data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	document := root.Content[0]
+	recordNodeSources(document, "", path, c.sources)
+
+	problems := []string{}
+	checkKnownKeys(document, reflect.TypeOf(*c), "", path, &problems)
+
+	if err := document.Decode(c); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		keysByLine := map[string]string{}
+		for key, source := range c.sources {
+			keysByLine[strings.TrimPrefix(source, path+":")] = key
+		}
+		for _, message := range typeErr.Errors {
+			// Messages look like "line 12: cannot unmarshal !!str `abc` into int"
+			line, detail, ok := strings.Cut(strings.TrimPrefix(message, "line "), ": ")
+			if key, known := keysByLine[line]; ok && known {
+				message = fmt.Sprintf("%s (%s:%s): %s", key, path, line, detail)
+			}
+			problems = append(problems, message)
+		}
+	}
+
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("invalid config file: %s", problems[0])
+	}
+	return fmt.Errorf("invalid config file, %d problems:\n  %s", len(problems), strings.Join(problems, "\n  "))
+}
+
+// checkKnownKeys reports mapping keys that match no setting
+func checkKnownKeys(node *yaml.Node, t reflect.Type, prefix, path string, problems *[]string) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		checkKnownKeys(node, t.Elem(), prefix, path, problems)
+		return
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkKnownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), path, problems)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		fieldType, known := fields[name]
+		if !known {
+			*problems = append(*problems, fmt.Sprintf("%s (%s:%d): unknown setting", key, path, node.Content[i].Line))
+			continue
+		}
+		checkKnownKeys(node.Content[i+1], fieldType, key, path, problems)
+	}
+}
+
+// recordNodeSources records file:line for every mapping key below node
+func recordNodeSources(node *yaml.Node, prefix, path string, sources map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		sources[key] = fmt.Sprintf("%s:%d", path, node.Content[i].Line)
+		recordNodeSources(node.Content[i+1], key, path, sources)
+	}
+}
+
+// applyEnv overrides settings from SCREENPIPE_BRIDGE_* variables and the
+// OPENAI_API_KEY shortcut
+func (c *Config) applyEnv() error {
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		c.LLM.APIKey = apiKey
+		c.sources["llm.api_key"] = "env OPENAI_API_KEY"
+	}
+
+	for _, f := range c.fields() {
+		name := EnvName(f.key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("%s (from env %s): %w", f.key, name, err)
+		}
+		c.sources[f.key] = "env " + name
+	}
+	return nil
+}
+
+// applyOverrides applies key=value settings given with -set flags
+func (c *Config) applyOverrides(overrides []string) error {
+	fields := map[string]reflect.Value{}
+	for _, f := range c.fields() {
+		fields[f.key] = f.value
+	}
+
+	for _, override := range overrides {
+		key, raw, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("-set %q: want key=value", override)
+		}
+		key = strings.TrimSpace(key)
+		value, known := fields[key]
+		if !known {
+			return fmt.Errorf("-set %q: unknown setting %s", override, key)
+		}
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("%s (from %s): %w", key, SourceFlag, err)
+		}
+		c.sources[key] = SourceFlag
+	}
+	return nil
+}
+
+// fields lists the scalar settings that env variables and flags can set.
+// Lists of structs and maps can only be set in the file.
+func (c *Config) fields() []field {
+	fields := []field{}
+	collectFields(reflect.ValueOf(c).Elem(), "", &fields)
+	return fields
+}
+
+// collectFields walks a config struct by its yaml tags
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || !structField.IsExported() {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		switch {
+		case value.Kind() == reflect.Struct:
+			collectFields(value, key, fields)
+		case isScalar(value):
+			*fields = append(*fields, field{key: key, value: value})
+		}
+	}
+}
+
+// isScalar reports whether setValue can parse into v
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// setValue parses raw into v according to its type
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (e.g. 30s, 24h)", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q (want true or false)", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("cannot be set from text")
+	}
+	return nil
+}
+
+// isSet reports whether a setting was given in the file, the environment or
+// a flag, as opposed to left at its zero value
+func (c *Config) isSet(key string) bool {
+	_, ok := c.sources[key]
+	return ok
+}
+
+// Source returns where a setting's value came from: a file and line, an
+// environment variable, a -set flag or the default. Keys below settings, such
+// as privacy.rules[2].action, resolve to their closest recorded parent.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	if c.isSetting(key) {
+		return SourceDefault
+	}
+
+	for {
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			return SourceDefault
+		}
+		key = key[:i]
+		if source, ok := c.sources[key]; ok && c.isSetting(key) {
+			return source
+		}
+	}
+}
+
+// IsDefault reports whether a source is a built-in default, including paths
+// that were detected rather than configured
+func IsDefault(source string) bool {
+	return source == SourceDefault || strings.HasPrefix(source, SourceDefault+": ")
+}
+
+// isSetting reports whether key names a setting rather than a section
+func (c *Config) isSetting(key string) bool {
+	if _, ok := fileOnlySettings[key]; ok {
+		return true
+	}
+	for _, f := range c.fields() {
+		if f.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// fileOnlySettings return the lists and maps that only the config file can set
+var fileOnlySettings = map[string]func(c *Config) interface{}{
+	"deerflow.credentials":    func(c *Config) interface{} { return c.Deerflow.Credentials },
+	"deerflow.secret":         func(c *Config) interface{} { return c.Deerflow.Secret },
+	"llm.credentials":         func(c *Config) interface{} { return c.LLM.Credentials },
+	"llm.pricing":             func(c *Config) interface{} { return c.LLM.Pricing },
+	"logging.components":      func(c *Config) interface{} { return c.Logging.Components },
+	"obsidian.entities.known": func(c *Config) interface{} { return c.Obsidian.Entities.Known },
+	"privacy.rules":           func(c *Config) interface{} { return c.Privacy.Rules },
+	"tasks.sinks":             func(c *Config) interface{} { return c.Tasks.Sinks },
+	"vision.credentials":      func(c *Config) interface{} { return c.Vision.Credentials },
+}
+
+// Settings returns every scalar setting with its resolved value and source,
+// sorted by key, with secrets masked. Unless effective is set, settings that
+// were not set explicitly are left out.
+func (c *Config) Settings(effective bool) []Setting {
+	settings := []Setting{}
+	for _, f := range c.fields() {
+		source := c.Source(f.key)
+		if !effective && IsDefault(source) {
+			continue
+		}
+		settings = append(settings, Setting{
+			Key:    f.key,
+			Value:  formatValue(f.key, f.value),
+			Source: source,
+		})
+	}
+
+	// File-only settings are summarized rather than expanded
+	for key, value := range fileOnlySettings {
+		source := c.Source(key)
+		if !effective && IsDefault(source) {
+			continue
+		}
+		count := reflect.ValueOf(value(c)).Len()
+		settings = append(settings, Setting{Key: key, Value: fmt.Sprintf("(%d entries)", count), Source: source})
+	}
+
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings
+}
+
+// formatValue renders a setting for display, masking secrets
+func formatValue(key string, v reflect.Value) string {
+	if secretKeys[key] {
+		if v.String() == "" {
+			return `""`
+		}
+		return maskSecret(v.String())
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// maskSecret keeps only the last four characters of a secret
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
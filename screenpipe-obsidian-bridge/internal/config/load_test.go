@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file with the paths that would otherwise be
+// discovered on this machine
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "screenpipe:\n  output_path: " + dir + "\nobsidian:\n  vault_path: " + dir + "\nprocessing:\n  state_dir: " + dir + "\n" + body
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+	return path
+}
+
+func TestLayering(t *testing.T) {
+	path := writeConfig(t, `llm:
+  model: gpt-4o
+  max_tokens: 500
+  temperature: 0.2
+`)
+	t.Setenv("SCREENPIPE_BRIDGE_LLM_MAX_TOKENS", "800")
+	t.Setenv("SCREENPIPE_BRIDGE_LLM_TEMPERATURE", "0.5")
+
+	cfg, err := Read(path, "llm.temperature=0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		got    interface{}
+		want   interface{}
+		source string
+	}{
+		{"llm.model", cfg.LLM.Model, "gpt-4o", path + ":8"},
+		{"llm.max_tokens", cfg.LLM.MaxTokens, 800, "env SCREENPIPE_BRIDGE_LLM_MAX_TOKENS"},
+		{"llm.temperature", cfg.LLM.Temperature, float32(0.9), SourceFlag},
+		{"screenpipe.quiet_period", cfg.ScreenPipe.QuietPeriod, 2 * time.Second, SourceDefault},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("%s = %v, want %v", test.key, test.got, test.want)
+			}
+			if source := cfg.Source(test.key); source != test.source {
+				t.Errorf("Source(%s) = %q, want %q", test.key, source, test.source)
+			}
+		})
+	}
+}
+
+func TestSourceOfNestedKeys(t *testing.T) {
+	path := writeConfig(t, `privacy:
+  rules:
+    - name: banking
+      action: exclude
+      domains: [chase.com]
+`)
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		source string
+	}{
+		{"privacy.rules", path + ":8"},
+		{"privacy.rules[0].action", path + ":8"},
+		{"privacy.default_action", SourceDefault},
+		{"tasks.sinks", SourceDefault},
+	}
+	for _, test := range tests {
+		if source := cfg.Source(test.key); source != test.source {
+			t.Errorf("Source(%s) = %q, want %q", test.key, source, test.source)
+		}
+	}
+}
+
+func TestMaxBackups(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		env  string
+		want int
+	}{
+		{"unset", "", "", 5},
+		{"zero in the file", "logging:\n  max_backups: 0\n", "", 0},
+		{"zero from the environment", "", "0", 0},
+		{"set", "logging:\n  max_backups: 2\n", "", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeConfig(t, test.body)
+			if test.env != "" {
+				t.Setenv("SCREENPIPE_BRIDGE_LOGGING_MAX_BACKUPS", test.env)
+			}
+			cfg, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Logging.MaxBackups != test.want {
+				t.Errorf("logging.max_backups = %d, want %d", cfg.Logging.MaxBackups, test.want)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		env       map[string]string
+		overrides []string
+		want      string
+	}{
+		{
+			name: "unknown key",
+			body: "llm:\n  modle: gpt-4o\n",
+			want: "llm.modle (%s:8): unknown setting",
+		},
+		{
+			name: "malformed value",
+			body: "llm:\n  max_tokens: lots\n",
+			want: "llm.max_tokens (%s:8)",
+		},
+		{
+			name: "malformed env variable",
+			env:  map[string]string{"SCREENPIPE_BRIDGE_PROCESSING_BATCH_DELAY": "soon"},
+			want: "processing.batch_delay (from env SCREENPIPE_BRIDGE_PROCESSING_BATCH_DELAY)",
+		},
+		{
+			name:      "unknown flag setting",
+			overrides: []string{"llm.modle=gpt-4o"},
+			want:      "unknown setting llm.modle",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeConfig(t, test.body)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			_, err := Read(path, test.overrides...)
+			if err == nil {
+				t.Fatal("Read succeeded, want an error")
+			}
+			want := strings.ReplaceAll(test.want, "%s", path)
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not contain %q", err, want)
+			}
+		})
+	}
+}
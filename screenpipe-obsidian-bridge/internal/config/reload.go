@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// restartSettings are the key prefixes whose changes only take effect after
+// a restart, each with how a reload keeps its running value
+var restartSettings = []struct {
+	key  string
+	keep func(c, running *Config)
+}{
+	{"screenpipe.poll_interval", func(c, running *Config) { c.ScreenPipe.PollInterval = running.ScreenPipe.PollInterval }},
+	{"screenpipe.quiet_period", func(c, running *Config) { c.ScreenPipe.QuietPeriod = running.ScreenPipe.QuietPeriod }},
+	{"processing.state_dir", func(c, running *Config) { c.Processing.StateDir = running.Processing.StateDir }},
+	{"processing.batch_size", func(c, running *Config) { c.Processing.BatchSize = running.Processing.BatchSize }},
+	{"processing.batch_delay", func(c, running *Config) { c.Processing.BatchDelay = running.Processing.BatchDelay }},
+	{"processing.reconcile_interval", func(c, running *Config) { c.Processing.ReconcileInterval = running.Processing.ReconcileInterval }},
+	{"tasks.sync_interval", func(c, running *Config) { c.Tasks.SyncInterval = running.Tasks.SyncInterval }},
+	{"security.enable_request_logging", func(c, running *Config) { c.Security.EnableRequestLogging = running.Security.EnableRequestLogging }},
+	{"security.request_log.", func(c, running *Config) { c.Security.RequestLog = running.Security.RequestLog }},
+	{"security.encryption.", func(c, running *Config) { c.Security.Encryption = running.Security.Encryption }},
+	{"server.", func(c, running *Config) { c.Server = running.Server }},
+	{"deerflow.inbound", func(c, running *Config) { c.Deerflow.Inbound = running.Deerflow.Inbound }},
+	{"deerflow.delivery_log", func(c, running *Config) { c.Deerflow.DeliveryLog = running.Deerflow.DeliveryLog }},
+}
+
+// Change is one setting that differs between two configurations
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// RequiresRestart reports whether a change to key needs a restart
+func RequiresRestart(key string) bool {
+	for _, setting := range restartSettings {
+		prefix := setting.key
+		if key == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(key, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists the settings that differ from old to new, sorted by key, with
+// secrets masked
+func Diff(old, new *Config) []Change {
+	oldSettings := map[string]string{}
+	for _, setting := range old.Settings(true) {
+		oldSettings[setting.Key] = setting.Value
+	}
+
+	changes := []Change{}
+	for _, setting := range new.Settings(true) {
+		previous := oldSettings[setting.Key]
+		if value, fileOnly := fileOnlySettings[setting.Key]; fileOnly {
+			// Entries can change without changing their count
+			if reflect.DeepEqual(value(old), value(new)) {
+				continue
+			}
+		} else if previous == setting.Value {
+			continue
+		}
+		changes = append(changes, Change{Key: setting.Key, Old: previous, New: setting.Value})
+	}
+	return changes
+}
+
+// KeepRestartSettings copies the settings that need a restart from running
+// into c, so a reloaded configuration describes what is actually in effect
+func (c *Config) KeepRestartSettings(running *Config) {
+	for _, setting := range restartSettings {
+		setting.keep(c, running)
+	}
+}
+
+// Watch reports changes to files on the returned channel until ctx is done.
+// It watches the files' directories, since editors often replace a file
+// rather than write to it, and coalesces bursts of events into one signal.
+func Watch(ctx context.Context, files []string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	wanted := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			abs = file
+		}
+		wanted[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+
+		// Editors write, rename and chmod in quick succession
+		const settle = 500 * time.Millisecond
+		timer := time.NewTimer(settle)
+		timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if wanted[event.Name] && !event.Has(fsnotify.Chmod) {
+					timer.Reset(settle)
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-timer.C:
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import "testing"
+
+func TestKeepRestartSettings(t *testing.T) {
+	path := writeConfig(t, "")
+	running, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One change to every restart-only setting, and one that applies live
+	changed := []string{
+		"screenpipe.poll_interval=9s",
+		"screenpipe.quiet_period=9s",
+		"processing.state_dir=/elsewhere",
+		"processing.batch_size=9",
+		"processing.batch_delay=9",
+		"processing.reconcile_interval=9m",
+		"tasks.sync_interval=9m",
+		"security.enable_request_logging=true",
+		"security.request_log.path=/elsewhere/audit.jsonl",
+		"security.encryption.enabled=true",
+		"server.listen=127.0.0.1:9999",
+		"deerflow.inbound=true",
+		"deerflow.delivery_log=/elsewhere/deliveries.jsonl",
+		"llm.model=gpt-4o-mini",
+	}
+	reloaded, err := Read(path, changed...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restart := 0
+	for _, change := range Diff(running, reloaded) {
+		if RequiresRestart(change.Key) {
+			restart++
+		}
+	}
+	if restart != len(restartSettings) {
+		t.Errorf("%d changes need a restart, want one per restart setting (%d)", restart, len(restartSettings))
+	}
+
+	reloaded.KeepRestartSettings(running)
+	changes := Diff(running, reloaded)
+	if len(changes) != 1 || changes[0].Key != "llm.model" {
+		t.Errorf("after keeping restart settings, changes are %v, want only llm.model", changes)
+	}
+}
+
+func TestRequiresRestart(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"processing.state_dir", true},
+		{"security.request_log.max_size_mb", true},
+		{"server.enabled", true},
+		{"deerflow.inbound", true},
+		{"deerflow.webhook_url", false},
+		{"llm.model", false},
+		{"processing.state_directory", false},
+	}
+	for _, test := range tests {
+		if got := RequiresRestart(test.key); got != test.want {
+			t.Errorf("RequiresRestart(%s) = %v, want %v", test.key, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// logger receives key pool events; see SetLogger
+var logger = slog.Default()
+
+// SetLogger sets the logger used for key pool events. The logging package
+// depends on this one for redaction, so it cannot be imported here.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// Pool rotates through several key providers. It moves to the next provider
+// when the current key is rejected (401) or rate limited (429), and on a fixed
+// schedule when an interval is set.
+type Pool struct {
+	providers []Provider
+	interval  time.Duration
+
+	mutex     sync.Mutex
+	current   int
+	rotatedAt time.Time
+	// issued maps each key handed out to the provider it came from, so a
+	// failure only rotates away from the provider still in use
+	issued map[string]int
+}
+
+// NewPool creates a pool; an interval of zero disables scheduled rotation
+func NewPool(providers []Provider, interval time.Duration) *Pool {
+	return &Pool{
+		providers: providers,
+		interval:  interval,
+		rotatedAt: time.Now(),
+		issued:    make(map[string]int),
+	}
+}
+
+// Key implements Provider.Key, skipping providers that fail to produce a key
+func (p *Pool) Key(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	if p.interval > 0 && time.Since(p.rotatedAt) >= p.interval {
+		p.rotateLocked("scheduled rotation")
+	}
+	start := p.current
+	p.mutex.Unlock()
+
+	var lastErr error
+	for i := 0; i < len(p.providers); i++ {
+		index := (start + i) % len(p.providers)
+		key, err := p.providers[index].Key(ctx)
+		if err == nil {
+			p.mutex.Lock()
+			p.issued[key] = index
+			p.mutex.Unlock()
+			return key, nil
+		}
+		lastErr = err
+		logger.Warn("API key unavailable", "key", index+1, "error", err)
+	}
+
+	return "", fmt.Errorf("no API key available in pool: %w", lastErr)
+}
+
+// Report implements Provider.Report. Only a failure of the current key
+// rotates; requests that were in flight with a key already rotated away from
+// must not skip its healthy successor.
+func (p *Pool) Report(key string, statusCode int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	index, ok := p.issued[key]
+	if !ok {
+		return
+	}
+	p.providers[index].Report(key, statusCode)
+	if index != p.current {
+		return
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized:
+		p.rotateLocked(fmt.Sprintf("key %s was rejected", Mask(key)))
+	case http.StatusTooManyRequests:
+		p.rotateLocked(fmt.Sprintf("key %s was rate limited", Mask(key)))
+	}
+}
+
+// Size returns the number of keys in the pool
+func (p *Pool) Size() int {
+	return len(p.providers)
+}
+
+// rotateLocked advances to the next provider; callers must hold the mutex
+func (p *Pool) rotateLocked(reason string) {
+	p.rotatedAt = time.Now()
+	if len(p.providers) < 2 {
+		return
+	}
+	p.current = (p.current + 1) % len(p.providers)
+	logger.Info("rotated API key", "key", p.current+1, "keys", len(p.providers), "reason", reason)
+}
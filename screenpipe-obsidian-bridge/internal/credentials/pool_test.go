@@ -0,0 +1,50 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func poolKey(t *testing.T, p *Pool) string {
+	t.Helper()
+	key, err := p.Key(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestPoolRotatesOnRejectedKey(t *testing.T) {
+	p := NewPool([]Provider{staticProvider("key-one"), staticProvider("key-two")}, 0)
+
+	first := poolKey(t, p)
+	p.Report(first, http.StatusTooManyRequests)
+	if got := poolKey(t, p); got != "key-two" {
+		t.Fatalf("after 429 got %q, want key-two", got)
+	}
+	p.Report("key-two", http.StatusUnauthorized)
+	if got := poolKey(t, p); got != "key-one" {
+		t.Fatalf("after 401 got %q, want key-one", got)
+	}
+}
+
+func TestPoolIgnoresStaleFailures(t *testing.T) {
+	p := NewPool([]Provider{staticProvider("key-one"), staticProvider("key-two"), staticProvider("key-three")}, 0)
+
+	// Two requests in flight with the same key are both rate limited
+	a, b := poolKey(t, p), poolKey(t, p)
+	p.Report(a, http.StatusTooManyRequests)
+	p.Report(b, http.StatusTooManyRequests)
+	if got := poolKey(t, p); got != "key-two" {
+		t.Fatalf("got %q, want key-two: the second 429 skipped a healthy key", got)
+	}
+}
+
+func TestPoolSuccessDoesNotRotate(t *testing.T) {
+	p := NewPool([]Provider{staticProvider("key-one"), staticProvider("key-two")}, 0)
+	p.Report(poolKey(t, p), http.StatusOK)
+	if got := poolKey(t, p); got != "key-one" {
+		t.Fatalf("got %q, want key-one", got)
+	}
+}
@@ -0,0 +1,198 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// commandTTL is how long the output of a key command is reused
+const commandTTL = 5 * time.Minute
+
+// Provider supplies API keys to LLM clients on every request
+type Provider interface {
+	// Key returns the key to use for the next request
+	Key(ctx context.Context) (string, error)
+	// Report tells the provider how a request made with key ended so that
+	// pools can rotate away from rejected or rate-limited keys
+	Report(key string, statusCode int)
+}
+
+// New builds a provider from the LLM credential sources, falling back to the
+// static llm.api_key. Several sources form a pool that rotates on 401/429
+// and, when enabled, on the configured schedule.
+func New(llmCfg *config.LLMConfig, securityCfg *config.SecurityConfig) (Provider, error) {
+	sources := llmCfg.Credentials
+	if len(sources) == 0 {
+		sources = []config.CredentialSource{{Key: llmCfg.APIKey}}
+	}
+	return NewSources("llm.credentials", sources, securityCfg)
+}
+
+// NewSources builds a provider from a list of credential sources; key names
+// the list in errors
+func NewSources(key string, sources []config.CredentialSource, securityCfg *config.SecurityConfig) (Provider, error) {
+	providers := make([]Provider, 0, len(sources))
+	for i, source := range sources {
+		provider, err := newSource(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", key, i, err)
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 1 && !securityCfg.EnableAPIKeyRotation {
+		return providers[0], nil
+	}
+
+	interval := time.Duration(0)
+	if securityCfg.EnableAPIKeyRotation {
+		interval = securityCfg.APIKeyRotationInterval
+	}
+	return NewPool(providers, interval), nil
+}
+
+// newSource creates the provider for a single credential source
+func newSource(source config.CredentialSource) (Provider, error) {
+	switch {
+	case source.Key != "":
+		Register(source.Key)
+		return staticProvider(source.Key), nil
+	case source.Env != "":
+		return envProvider(source.Env), nil
+	case source.File != "":
+		return &fileProvider{path: source.File}, nil
+	case source.Command != "":
+		return &commandProvider{command: source.Command}, nil
+	default:
+		return nil, fmt.Errorf("no key, env, file or command set")
+	}
+}
+
+// staticProvider always returns the same key
+type staticProvider string
+
+// Key implements Provider.Key
+func (p staticProvider) Key(ctx context.Context) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("API key is empty")
+	}
+	return string(p), nil
+}
+
+// Report implements Provider.Report
+func (p staticProvider) Report(key string, statusCode int) {}
+
+// envProvider reads the key from an environment variable on every request
+type envProvider string
+
+// Key implements Provider.Key
+func (p envProvider) Key(ctx context.Context) (string, error) {
+	key := strings.TrimSpace(os.Getenv(string(p)))
+	if key == "" {
+		return "", fmt.Errorf("environment variable %s is not set", string(p))
+	}
+	Register(key)
+	return key, nil
+}
+
+// Report implements Provider.Report
+func (p envProvider) Report(key string, statusCode int) {}
+
+// fileProvider reads the key from a file, re-reading it when it changes
+type fileProvider struct {
+	path    string
+	mutex   sync.Mutex
+	key     string
+	modTime time.Time
+}
+
+// Key implements Provider.Key
+func (p *fileProvider) Key(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat key file %s: %w", p.path, err)
+	}
+	if p.key != "" && info.ModTime().Equal(p.modTime) {
+		return p.key, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file %s: %w", p.path, err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("key file %s is empty", p.path)
+	}
+
+	Register(key)
+	p.key = key
+	p.modTime = info.ModTime()
+	return key, nil
+}
+
+// Report implements Provider.Report
+func (p *fileProvider) Report(key string, statusCode int) {}
+
+// commandProvider runs a command (e.g. a password manager CLI) for the key
+// and caches its output briefly
+type commandProvider struct {
+	command   string
+	mutex     sync.Mutex
+	key       string
+	fetchedAt time.Time
+}
+
+// Key implements Provider.Key
+func (p *commandProvider) Key(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.key != "" && time.Since(p.fetchedAt) < commandTTL {
+		return p.key, nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.command)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("key command failed: %w", err)
+	}
+	key := strings.TrimSpace(string(output))
+	if key == "" {
+		return "", fmt.Errorf("key command returned no output")
+	}
+
+	Register(key)
+	p.key = key
+	p.fetchedAt = time.Now()
+	return key, nil
+}
+
+// Report implements Provider.Report; a rejected key forces the command to
+// run again on the next request
+func (p *commandProvider) Report(key string, statusCode int) {
+	if statusCode != http.StatusUnauthorized {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.key = ""
+}
@@ -0,0 +1,117 @@
+package credentials
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// keyPattern matches API keys that were never registered, such as keys echoed
+// back in provider error messages: OpenAI (sk-, sk-proj-), Anthropic
+// (sk-ant-), xAI (xai-) and Google (AIza plus 35 characters). The prefixes
+// need their hyphen so identifiers like skipped_items are left alone.
+var keyPattern = regexp.MustCompile(`\b(?:sk-(?:proj-|ant-)?[-_A-Za-z0-9]{16,}|xai-[-_A-Za-z0-9]{16,}|AIza[-_A-Za-z0-9]{35}\b)`)
+
+var (
+	knownMutex sync.RWMutex
+	knownKeys  = map[string]struct{}{}
+)
+
+// Register adds a key to the set that Redact removes from text
+func Register(key string) {
+	if len(key) < 8 {
+		return
+	}
+	knownMutex.Lock()
+	defer knownMutex.Unlock()
+	knownKeys[key] = struct{}{}
+}
+
+// Mask shortens a key to a recognizable but harmless form, e.g. "sk-…9xQ2"
+func Mask(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	prefix := ""
+	if strings.HasPrefix(key, "sk-") {
+		prefix = "sk-"
+	}
+	return prefix + "…" + key[len(key)-4:]
+}
+
+// Redact replaces every registered key and anything that looks like an API
+// key in s with its masked form
+func Redact(s string) string {
+	redacted, _ := RedactCount(s)
+	return redacted
+}
+
+// RedactCount is Redact that also reports how many keys were replaced
+func RedactCount(s string) (string, int) {
+	count := 0
+
+	knownMutex.RLock()
+	for key := range knownKeys {
+		if n := strings.Count(s, key); n > 0 {
+			s = strings.ReplaceAll(s, key, Mask(key))
+			count += n
+		}
+	}
+	knownMutex.RUnlock()
+
+	s = keyPattern.ReplaceAllStringFunc(s, func(key string) string {
+		count++
+		return Mask(key)
+	})
+	return s, count
+}
+
+// RedactError returns err with keys removed from its message. The original
+// error stays reachable through errors.Unwrap.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	redacted := Redact(message)
+	if redacted == message {
+		return err
+	}
+	return &redactedError{message: redacted, err: err}
+}
+
+// redactedError carries a redacted message for a wrapped error
+type redactedError struct {
+	message string
+	err     error
+}
+
+// Error implements error
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactingWriter wraps w so every write has keys redacted, for use as the
+// log output
+func RedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+// redactingWriter implements RedactingWriter
+type redactingWriter struct {
+	w io.Writer
+}
+
+// Write implements io.Writer
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,58 @@
+package credentials
+
+import (
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that sets the Authorization header from a
+// Provider on every request. Rejected (401) and rate-limited (429) requests
+// are retried with the next key when the provider is a pool.
+type Transport struct {
+	Provider Provider
+	Base     http.RoundTripper
+}
+
+// NewHTTPClient returns an HTTP client that authenticates with provider
+func NewHTTPClient(provider Provider) *http.Client {
+	return &http.Client{
+		Transport: &Transport{Provider: provider, Base: http.DefaultTransport},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if pool, ok := t.Provider.(*Pool); ok && req.GetBody != nil {
+		attempts = pool.Size()
+	}
+
+	for attempt := 1; ; attempt++ {
+		key, err := t.Provider.Key(req.Context())
+		if err != nil {
+			return nil, RedactError(err)
+		}
+
+		authed := req.Clone(req.Context())
+		authed.Header.Set("Authorization", "Bearer "+key)
+		if attempt > 1 {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			authed.Body = body
+		}
+
+		resp, err := t.Base.RoundTrip(authed)
+		if err != nil {
+			return nil, RedactError(err)
+		}
+
+		t.Provider.Report(key, resp.StatusCode)
+
+		retryable := resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests
+		if !retryable || attempt >= attempts {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
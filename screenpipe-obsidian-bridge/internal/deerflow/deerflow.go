@@ -0,0 +1,249 @@
+// Package deerflow connects the bridge to a Deerflow workflow. Processed
+// notes and their action items are POSTed to the workflow's webhook as
+// HMAC-signed JSON, and the workflow can call back, signed the same way, to
+// reprocess a capture or report an action item done.
+package deerflow
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+var logger = logging.For(logging.ComponentDeerflow)
+
+// requestTimeout bounds one delivery to the webhook
+const requestTimeout = 30 * time.Second
+
+// maxClockSkew is how far the timestamp of a signed inbound request may be
+// from the bridge's clock before it is refused as a replay
+const maxClockSkew = 5 * time.Minute
+
+// Headers sent with every delivery and expected on inbound requests
+const (
+	HeaderSignature = "X-Deerflow-Signature"
+	HeaderTimestamp = "X-Deerflow-Timestamp"
+	HeaderDelivery  = "X-Deerflow-Delivery"
+	HeaderEvent     = "X-Deerflow-Event"
+	HeaderWorkflow  = "X-Deerflow-Workflow"
+)
+
+// EventNoteProcessed is sent when a note is written or rewritten
+const EventNoteProcessed = "note.processed"
+
+// Webhook delivers signed payloads to the configured Deerflow workflow
+type Webhook struct {
+	config *config.DeerflowConfig
+	http   *http.Client
+	// keys supplies the bearer token, nil when none is configured; secret
+	// supplies the signing secret
+	keys   credentials.Provider
+	secret credentials.Provider
+}
+
+// New creates the webhook, or returns nil when Deerflow is disabled
+func New(cfg *config.DeerflowConfig, securityCfg *config.SecurityConfig) (*Webhook, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	secret, err := credentials.NewSources("deerflow.secret", cfg.Secret, securityCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys credentials.Provider
+	sources := cfg.Credentials
+	if len(sources) == 0 && cfg.APIKey != "" {
+		sources = []config.CredentialSource{{Key: cfg.APIKey}}
+	}
+	if len(sources) > 0 {
+		if keys, err = credentials.NewSources("deerflow.credentials", sources, securityCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Webhook{
+		config: cfg,
+		http:   &http.Client{},
+		keys:   keys,
+		secret: secret,
+	}, nil
+}
+
+// Matches reports whether a note with these tags and source type passes the
+// deerflow.tags and deerflow.types filters
+func (w *Webhook) Matches(tags []string, sourceType string) bool {
+	if len(w.config.Types) > 0 && !contains(w.config.Types, sourceType) {
+		return false
+	}
+	if len(w.config.Tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if contains(w.config.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether values holds value, ignoring case and a leading #
+func contains(values []string, value string) bool {
+	value = strings.TrimPrefix(value, "#")
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimPrefix(candidate, "#"), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDelivery wraps a payload for the outbox under a new delivery ID
+func NewDelivery(event string, payload []byte, now time.Time) state.Delivery {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return state.Delivery{
+		ID:          hex.EncodeToString(id),
+		Event:       event,
+		Payload:     payload,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+}
+
+// Error is a delivery the webhook answered with an unexpected status
+type Error struct {
+	Status int
+	Body   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("status %d: %s", e.Status, e.Body)
+}
+
+// Retryable reports whether a failed delivery may succeed later: network
+// errors, timeouts, rate limits and server errors are retried, other
+// refusals are not
+func Retryable(err error) bool {
+	var status *Error
+	if !errors.As(err, &status) {
+		return true
+	}
+	return status.Status == http.StatusRequestTimeout || status.Status == http.StatusTooManyRequests || status.Status >= 500
+}
+
+// Send makes one delivery attempt and returns the response status, zero
+// when no response was received
+func (w *Webhook) Send(ctx context.Context, delivery state.Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	secret, err := w.secret.Key(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read signing secret: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.WebhookURL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.Payload))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	if w.config.WorkflowID != "" {
+		req.Header.Set(HeaderWorkflow, w.config.WorkflowID)
+	}
+	key := ""
+	if w.keys != nil {
+		if key, err = w.keys.Key(ctx); err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return 0, credentials.RedactError(err)
+	}
+	defer resp.Body.Close()
+	if w.keys != nil {
+		w.keys.Report(key, resp.StatusCode)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return resp.StatusCode, &Error{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// RetryDelay is how long to wait after a delivery's nth failed attempt:
+// deerflow.retry_delay, doubled after every further failure
+func (w *Webhook) RetryDelay(attempts int) time.Duration {
+	delay := w.config.RetryDelay
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// MaxAttempts is how many times a delivery is tried before it is dropped
+func (w *Webhook) MaxAttempts() int {
+	return w.config.MaxAttempts
+}
+
+// Sign returns the signature header value for a body sent at timestamp: the
+// hex HMAC-SHA256 of "timestamp.body" under secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of an inbound request's body and refuses
+// requests signed too long ago
+func (w *Webhook) Verify(ctx context.Context, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(HeaderTimestamp)
+	signature := header.Get(HeaderSignature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing %s or %s header", HeaderSignature, HeaderTimestamp)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", HeaderTimestamp)
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("timestamp is %s off", skew.Round(time.Second))
+	}
+
+	secret, err := w.secret.Key(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read signing secret: %w", err)
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package deerflow
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// newWebhook creates a webhook signing with secret and posting to url
+func newWebhook(t *testing.T, secret, url string) *Webhook {
+	t.Helper()
+	w, err := New(&config.DeerflowConfig{
+		Enabled:    true,
+		WebhookURL: url,
+		Secret:     []config.CredentialSource{{Key: secret}},
+	}, &config.SecurityConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestSign(t *testing.T) {
+	// openssl dgst -sha256 -hmac s3cret <<< '1700000000.{"a":1}', without the newline
+	want := "sha256=1698a50bc74d1ff1db85c4e0a5297c2ad9fdba245d5737cdb789e4cc6e098940"
+	if got := Sign("s3cret", "1700000000", []byte(`{"a":1}`)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("s3cret", "1700000001", []byte(`{"a":1}`)) == want {
+		t.Error("signature does not cover the timestamp")
+	}
+	if Sign("other", "1700000000", []byte(`{"a":1}`)) == want {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	w := newWebhook(t, "s3cret", "")
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"action":"reprocess"}`)
+	signed := func(secret string, at time.Time, body []byte) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		header := http.Header{}
+		header.Set(HeaderTimestamp, timestamp)
+		header.Set(HeaderSignature, Sign(secret, timestamp, body))
+		return header
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantErr string
+	}{
+		{"valid", signed("s3cret", now, body), ""},
+		{"within skew", signed("s3cret", now.Add(-maxClockSkew), body), ""},
+		{"ahead within skew", signed("s3cret", now.Add(maxClockSkew), body), ""},
+		{"too old", signed("s3cret", now.Add(-maxClockSkew-time.Second), body), "off"},
+		{"too far ahead", signed("s3cret", now.Add(maxClockSkew+time.Second), body), "off"},
+		{"wrong secret", signed("other", now, body), "does not match"},
+		{"other body", signed("s3cret", now, []byte(`{"action":"done"}`)), "does not match"},
+		{"no headers", http.Header{}, "missing"},
+		{"no signature", http.Header{HeaderTimestamp: {"1700000000"}}, "missing"},
+		{"invalid timestamp", http.Header{HeaderTimestamp: {"yesterday"}, HeaderSignature: {"sha256=00"}}, "invalid"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := w.Verify(context.Background(), test.header, body, now)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendIsVerifiable(t *testing.T) {
+	receiver := newWebhook(t, "s3cret", "")
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = receiver.Verify(r.Context(), r.Header, body, time.Now())
+	}))
+	defer server.Close()
+
+	sender := newWebhook(t, "s3cret", server.URL)
+	status, err := sender.Send(context.Background(), NewDelivery(EventNoteProcessed, []byte(`{"note":"x"}`), time.Now()))
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Send = %d, %v", status, err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver refused the delivery: %v", verifyErr)
+	}
+}
@@ -0,0 +1,145 @@
+package deerflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+// maxRequestBytes bounds the body of an inbound request
+const maxRequestBytes = 64 * 1024
+
+// Actions Deerflow can ask for in inbound requests
+const (
+	ActionReprocess  = "reprocess"
+	ActionTaskStatus = "task_status"
+)
+
+// ErrNotFound is returned by Actions for a note, source or task the bridge
+// does not know
+var ErrNotFound = errors.New("not found")
+
+// Request is the JSON body of an inbound request
+type Request struct {
+	Action string `json:"action"`
+	// Note is a vault-relative note path and Source a captured file, either
+	// naming what to reprocess
+	Note   string `json:"note,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Task and Status report an action item's status
+	Task   TaskRef `json:"task,omitempty"`
+	Status string  `json:"status,omitempty"`
+}
+
+// TaskRef names an action item, by the Key sent in its payload or by its
+// note and text
+type TaskRef struct {
+	Key  string `json:"key,omitempty"`
+	Note string `json:"note,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// Actions carries out inbound requests
+type Actions interface {
+	// Reprocess queues the source file, given or recorded for the note, to
+	// be processed again and returns the source
+	Reprocess(ctx context.Context, note, source string) (string, error)
+	// CompleteTask ticks an action item in its note
+	CompleteTask(ctx context.Context, task TaskRef) error
+}
+
+// Handler serves inbound requests signed with the secret of the webhook
+// current returns; while it returns nil, requests are refused
+func Handler(current func() *Webhook, actions Actions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		webhook := current()
+		if webhook == nil {
+			http.Error(w, "deerflow is disabled", http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes+1))
+		if err != nil || len(body) > maxRequestBytes {
+			http.Error(w, "request body too large or unreadable", http.StatusBadRequest)
+			return
+		}
+		if err := webhook.Verify(r.Context(), r.Header, body, time.Now()); err != nil {
+			metrics.DeerflowRequests.With("unknown", "rejected").Inc()
+			logger.WarnContext(r.Context(), "refused unsigned inbound request", "remote", r.RemoteAddr, "error", err)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var request Request
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		status, message := serve(r.Context(), actions, request)
+		action, result := request.Action, "accepted"
+		if action != ActionReprocess && action != ActionTaskStatus {
+			action = "unknown"
+		}
+		if status >= 400 {
+			result = "error"
+		}
+		metrics.DeerflowRequests.With(action, result).Inc()
+		logger.InfoContext(r.Context(), "inbound request", "action", request.Action, "status", status, "result", message)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"result": message})
+	})
+}
+
+// serve carries out a verified request and returns the response status and
+// message
+func serve(ctx context.Context, actions Actions, request Request) (int, string) {
+	switch request.Action {
+	case ActionReprocess:
+		if request.Note == "" && request.Source == "" {
+			return http.StatusBadRequest, "reprocess needs a note or a source"
+		}
+		source, err := actions.Reprocess(ctx, request.Note, request.Source)
+		if err != nil {
+			return failure(err)
+		}
+		return http.StatusAccepted, "reprocessing " + source
+
+	case ActionTaskStatus:
+		if request.Task.Key == "" && (request.Task.Note == "" || request.Task.Text == "") {
+			return http.StatusBadRequest, "task_status needs a task key, or a note and text"
+		}
+		switch request.Status {
+		case "done", "completed":
+		case "":
+			return http.StatusBadRequest, "task_status needs a status"
+		default:
+			return http.StatusBadRequest, "unsupported status " + request.Status + " (want done)"
+		}
+		if err := actions.CompleteTask(ctx, request.Task); err != nil {
+			return failure(err)
+		}
+		return http.StatusOK, "task completed"
+	}
+	return http.StatusBadRequest, "unknown action " + request.Action
+}
+
+// failure maps an Actions error to a response
+func failure(err error) (int, string) {
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound, err.Error()
+	}
+	return http.StatusInternalServerError, err.Error()
+}
@@ -0,0 +1,83 @@
+package deerflow
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/rotate"
+)
+
+// Size limit and backups of the delivery log
+const (
+	logMaxBytes   = 10 * 1024 * 1024
+	logMaxBackups = 3
+)
+
+// Outcomes of a delivery attempt
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeRetrying  = "retrying"
+	OutcomeFailed    = "failed"
+)
+
+// LogRecord is one line of the delivery log, describing one attempt
+type LogRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Delivery  string    `json:"delivery"`
+	Event     string    `json:"event"`
+	Attempt   int       `json:"attempt"`
+	Outcome   string    `json:"outcome"`
+	// Status is the webhook's response status, zero when it did not answer
+	Status    int    `json:"status,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	// NextAttempt is when a retried delivery is sent again
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+}
+
+// DeliveryLog appends delivery attempts to a size-rotated JSONL file. The
+// file is opened on the first attempt, so nothing is created while Deerflow
+// is disabled.
+type DeliveryLog struct {
+	path   string
+	mutex  sync.Mutex
+	writer *rotate.Writer
+}
+
+// NewDeliveryLog returns the delivery log at path
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{path: path}
+}
+
+// Record appends an attempt to the log
+func (l *DeliveryLog) Record(record LogRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.writer == nil {
+		writer, err := rotate.New(l.path, logMaxBytes, logMaxBackups)
+		if err != nil {
+			return err
+		}
+		l.writer = writer
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = l.writer.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the log file, if it was opened
+func (l *DeliveryLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.writer == nil {
+		return nil
+	}
+	return l.writer.Close()
+}
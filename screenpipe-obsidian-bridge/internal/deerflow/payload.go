@@ -0,0 +1,63 @@
+package deerflow
+
+import (
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/tasks"
+)
+
+// Payload is the JSON body of a note.processed delivery
+type Payload struct {
+	Event      string `json:"event"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+	Note       Note   `json:"note"`
+	Summary    string `json:"summary"`
+	// ActionItems are the note's new action items; RepeatedActionItems
+	// were merged into ones sent with earlier notes
+	ActionItems         []ActionItem       `json:"action_items"`
+	RepeatedActionItems []llm.RepeatedTask `json:"repeated_action_items,omitempty"`
+	ComplianceScore     int                `json:"compliance_score"`
+}
+
+// Note describes the note a payload is about
+type Note struct {
+	// Path is the vault-relative path without extension, as wikilinks name
+	// it, and URI an obsidian:// link opening the note
+	Path        string   `json:"path"`
+	URI         string   `json:"uri"`
+	Tags        []string `json:"tags"`
+	SourceFile  string   `json:"source_file"`
+	SourceType  string   `json:"source_type"`
+	ProcessedAt string   `json:"processed_at"`
+	// Reprocessed is set when the note replaced an earlier one for the
+	// same source
+	Reprocessed bool `json:"reprocessed,omitempty"`
+}
+
+// ActionItem is an action item as sent to Deerflow. Key identifies it in
+// task_status callbacks.
+type ActionItem struct {
+	Key  string `json:"key"`
+	Text string `json:"text"`
+	llm.TaskDetail
+}
+
+// NotePayload builds the note.processed payload for a written note
+func NotePayload(result *llm.ProcessingResult, note Note, workflowID string) Payload {
+	payload := Payload{
+		Event:               EventNoteProcessed,
+		WorkflowID:          workflowID,
+		Note:                note,
+		Summary:             result.ActivitySummary,
+		ActionItems:         make([]ActionItem, 0, len(result.ActionableTasks)),
+		RepeatedActionItems: result.Metadata.RepeatedTasks,
+		ComplianceScore:     result.DoctrineCompliance.ComplianceScore,
+	}
+	for i, text := range result.ActionableTasks {
+		payload.ActionItems = append(payload.ActionItems, ActionItem{
+			Key:        tasks.Key(text),
+			Text:       text,
+			TaskDetail: result.DetailOf(i),
+		})
+	}
+	return payload
+}
@@ -0,0 +1,35 @@
+package extract
+
+import (
+	"fmt"
+	"os"
+
+	"screenpipe-obsidian-bridge/internal/transcribe"
+)
+
+// Audio turns the transcript of a recording into items, one per segment.
+// Recordings are written when they end, so segment times are counted back
+// from the file's modification time.
+func Audio(path string, transcript *transcribe.Transcript) (*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	startedAt := info.ModTime().Add(-transcript.Duration)
+
+	result := &Result{
+		Path:     path,
+		Type:     "audio",
+		Language: transcript.Language,
+		Duration: transcript.Duration,
+		Items:    make([]Item, 0, len(transcript.Segments)),
+	}
+	for _, segment := range transcript.Segments {
+		result.Items = append(result.Items, Item{
+			Text:       segment.Text,
+			CapturedAt: startedAt.Add(segment.Start),
+			Offset:     segment.Start,
+		})
+	}
+	return result, nil
+}
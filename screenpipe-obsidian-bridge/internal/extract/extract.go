@@ -0,0 +1,198 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Item is a single unit of captured content together with the context it
+// was captured in (source application, window, URL and time).
+type Item struct {
+	Text       string    `json:"text"`
+	App        string    `json:"app,omitempty"`
+	Window     string    `json:"window,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	CapturedAt time.Time `json:"captured_at"`
+	// Offset is where a transcript segment or video frame is in its recording
+	Offset time.Duration `json:"offset,omitempty"`
+	// Label says what a screenshot item holds, e.g. LabelScreenText
+	Label string `json:"label,omitempty"`
+	// Image is the video frame the text was read from, when it was kept
+	Image []byte `json:"-"`
+}
+
+// Result is the content extracted from one ScreenPipe output file
+type Result struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	Items []Item `json:"items"`
+	// Duration and Language describe audio and video recordings, Resolution
+	// video recordings and screenshots
+	Duration   time.Duration `json:"duration,omitempty"`
+	Language   string        `json:"language,omitempty"`
+	Resolution string        `json:"resolution,omitempty"`
+}
+
+// File reads a ScreenPipe output file and splits it into items
+func File(path string) (*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	result := &Result{
+		Path: path,
+		Type: TypeOf(path),
+	}
+	switch result.Type {
+	case "audio":
+		return nil, fmt.Errorf("%s is audio and needs to be transcribed", path)
+	case "video":
+		return nil, fmt.Errorf("%s is a video and needs its frames read", path)
+	case "image":
+		return nil, fmt.Errorf("%s is an image and needs OCR or a vision model", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	switch result.Type {
+	case "json":
+		result.Items = parseJSONItems(data, info.ModTime())
+	default:
+		result.Items = []Item{{Text: string(data), CapturedAt: info.ModTime()}}
+	}
+
+	return result, nil
+}
+
+// Text joins the items into the content that is sent to the LLM
+func (r *Result) Text() string {
+	if r.Type == "audio" || r.Type == "video" {
+		return r.timelineText()
+	}
+	if r.Type == "image" {
+		return r.screenshotText()
+	}
+	if len(r.Items) == 1 && r.Items[0].App == "" && r.Items[0].Window == "" {
+		return r.Items[0].Text
+	}
+
+	var b strings.Builder
+	for i, item := range r.Items {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		if header := item.header(); header != "" {
+			b.WriteString(header)
+			b.WriteString("\n")
+		}
+		b.WriteString(item.Text)
+	}
+	return b.String()
+}
+
+// header describes where an item was captured, e.g. "[15:04:05] Slack - general"
+func (i Item) header() string {
+	parts := []string{}
+	if i.App != "" {
+		parts = append(parts, i.App)
+	}
+	if i.Window != "" {
+		parts = append(parts, i.Window)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%s] %s", i.CapturedAt.Format("15:04:05"), strings.Join(parts, " - "))
+}
+
+// TypeOf classifies a file by its extension
+func TypeOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".md":
+		return "markdown"
+	case ".wav", ".mp3", ".m4a", ".flac":
+		return "audio"
+	case ".mp4", ".mkv", ".mov":
+		return "video"
+	case ".png", ".jpg", ".jpeg":
+		return "image"
+	default:
+		return "text"
+	}
+}
+
+// parseJSONItems understands the common ScreenPipe JSON shapes: a single
+// frame/segment object, an array of them, or an object with a "data" array.
+// Entries may wrap their fields in a "content" object as the ScreenPipe
+// search API does. Unknown JSON is passed through as a single text item.
+func parseJSONItems(data []byte, fallback time.Time) []Item {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []Item{{Text: string(data), CapturedAt: fallback}}
+	}
+
+	var entries []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		entries = v
+	case map[string]interface{}:
+		if list, ok := v["data"].([]interface{}); ok {
+			entries = list
+		} else {
+			entries = []interface{}{v}
+		}
+	}
+
+	items := []Item{}
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if inner, ok := fields["content"].(map[string]interface{}); ok {
+			fields = inner
+		}
+
+		item := Item{
+			Text:       firstString(fields, "text", "ocr_text", "transcription", "content"),
+			App:        firstString(fields, "app_name", "app"),
+			Window:     firstString(fields, "window_name", "window_title", "window"),
+			URL:        firstString(fields, "browser_url", "url"),
+			CapturedAt: fallback,
+		}
+		if ts := firstString(fields, "timestamp", "created_at"); ts != "" {
+			if parsed, err := time.Parse(time.RFC3339, ts); err == nil {
+				item.CapturedAt = parsed
+			}
+		}
+		if item.Text == "" {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return []Item{{Text: string(data), CapturedAt: fallback}}
+	}
+	return items
+}
+
+// firstString returns the first non-empty string value among keys
+func firstString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := fields[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
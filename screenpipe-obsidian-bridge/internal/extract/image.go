@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Labels of the items extracted from a screenshot
+const (
+	LabelScreenText  = "Text on screen"
+	LabelDescription = "Description"
+)
+
+// Image turns the recognized text and the model's description of a
+// screenshot into items; either may be empty when its step is disabled
+func Image(path, text, description string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	result := &Result{Path: path, Type: "image"}
+	if header, _, err := image.DecodeConfig(f); err == nil {
+		result.Resolution = fmt.Sprintf("%dx%d", header.Width, header.Height)
+	}
+	for _, item := range []Item{
+		{Label: LabelScreenText, Text: text},
+		{Label: LabelDescription, Text: description},
+	} {
+		if strings.TrimSpace(item.Text) != "" {
+			item.CapturedAt = info.ModTime()
+			result.Items = append(result.Items, item)
+		}
+	}
+	return result, nil
+}
+
+// screenshotText names a screenshot and lists its labelled items
+func (r *Result) screenshotText() string {
+	if len(r.Items) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Screenshot: " + filepath.Base(r.Path))
+	if r.Resolution != "" {
+		b.WriteString(", " + r.Resolution)
+	}
+	b.WriteString("\n")
+	for _, item := range r.Items {
+		fmt.Fprintf(&b, "\n%s:\n%s\n", item.Label, item.Text)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,47 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timelineText describes a recording and lists its items with their
+// offsets, e.g. "[01:05] Let's move the launch to Friday"
+func (r *Result) timelineText() string {
+	if len(r.Items) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if r.Type == "video" {
+		b.WriteString("Screen recording: " + filepath.Base(r.Path))
+	} else {
+		b.WriteString("Audio recording: " + filepath.Base(r.Path))
+	}
+	if r.Duration > 0 {
+		b.WriteString(", " + r.Duration.Round(time.Second).String())
+	}
+	if r.Resolution != "" {
+		b.WriteString(", " + r.Resolution)
+	}
+	if r.Language != "" {
+		b.WriteString(", language: " + r.Language)
+	}
+	b.WriteString("\n")
+	for _, item := range r.Items {
+		fmt.Fprintf(&b, "\n[%s] %s", FormatOffset(item.Offset), item.Text)
+	}
+	return b.String()
+}
+
+// FormatOffset formats an offset into a recording as mm:ss, or h:mm:ss
+// past the first hour
+func FormatOffset(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,38 @@
+package extract
+
+import (
+	"fmt"
+	"os"
+
+	"screenpipe-obsidian-bridge/internal/video"
+)
+
+// Video turns the text read from a screen recording into items, one per
+// distinct frame. Like audio, frame times are counted back from the file's
+// modification time.
+func Video(path string, recording *video.Recording) (*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	startedAt := info.ModTime().Add(-recording.Duration)
+
+	result := &Result{
+		Path:     path,
+		Type:     "video",
+		Duration: recording.Duration,
+		Items:    make([]Item, 0, len(recording.Frames)),
+	}
+	if recording.Width > 0 && recording.Height > 0 {
+		result.Resolution = fmt.Sprintf("%dx%d", recording.Width, recording.Height)
+	}
+	for _, frame := range recording.Frames {
+		result.Items = append(result.Items, Item{
+			Text:       frame.Text,
+			CapturedAt: startedAt.Add(frame.Offset),
+			Offset:     frame.Offset,
+			Image:      frame.Image,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,434 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/ocr"
+	"screenpipe-obsidian-bridge/internal/paths"
+	"screenpipe-obsidian-bridge/internal/transcribe"
+	"screenpipe-obsidian-bridge/internal/video"
+	"screenpipe-obsidian-bridge/internal/watcher"
+)
+
+// Check outcomes, from best to worst
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Disk space below these limits produces a warning or a failure
+const (
+	lowDiskSpace      = 1 << 30   // 1 GiB
+	criticalDiskSpace = 100 << 20 // 100 MiB
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Fix suggests how to resolve a warning or failure
+	Fix string `json:"fix,omitempty"`
+}
+
+// Report collects the results of a run
+type Report struct {
+	Results   []Result  `json:"results"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Healthy reports whether no check failed; warnings are allowed
+func (r Report) Healthy() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Checker runs the readiness checks against a configuration
+type Checker struct {
+	config *config.Config
+
+	mutex  sync.Mutex
+	cached Report
+}
+
+// NewChecker creates a checker for cfg
+func NewChecker(cfg *config.Config) *Checker {
+	return &Checker{config: cfg}
+}
+
+// SetConfig makes later checks use cfg, e.g. after a reload, and drops the
+// cached report
+func (c *Checker) SetConfig(cfg *config.Config) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.config = cfg
+	c.cached = Report{}
+}
+
+// Run performs every check. The LLM check makes a network call, so callers
+// polling readiness should use Cached.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{CheckedAt: time.Now()}
+	add := func(result Result) {
+		report.Results = append(report.Results, result)
+	}
+
+	add(c.checkConfig())
+	add(c.checkOutputDir())
+	add(c.checkWatchCapacity())
+	add(c.checkVault())
+	add(c.checkFilenameTemplate())
+	add(c.checkLLM(ctx))
+	add(c.checkTranscription())
+	add(c.checkVideo())
+	add(c.checkImages())
+	add(c.checkDiskSpace("vault disk space", c.config.Obsidian.VaultPath))
+	add(c.checkDiskSpace("state disk space", c.config.Processing.StateDir))
+
+	return report
+}
+
+// Cached returns the last report if it is younger than maxAge, running the
+// checks again otherwise
+func (c *Checker) Cached(ctx context.Context, maxAge time.Duration) Report {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if time.Since(c.cached.CheckedAt) < maxAge {
+		return c.cached
+	}
+	c.cached = c.Run(ctx)
+	return c.cached
+}
+
+// checkConfig validates the configuration as the daemon would on startup
+func (c *Checker) checkConfig() Result {
+	result := Result{Name: "configuration"}
+	if err := c.config.Validate(); err != nil {
+		return fail(result, err.Error(), "Fix the setting named above; see configs/config.example.yaml")
+	}
+	return ok(result, "valid")
+}
+
+// checkOutputDir checks that the ScreenPipe output directory can be listed
+func (c *Checker) checkOutputDir() Result {
+	path := c.config.ScreenPipe.OutputPath
+	result := Result{Name: "screenpipe output"}
+
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return fail(result, fmt.Sprintf("%s does not exist", c.describePath("screenpipe.output_path", path)),
+			"Set screenpipe.output_path to the directory ScreenPipe writes to (e.g. ~/.screenpipe/data)")
+	}
+	if err != nil {
+		return fail(result, fmt.Sprintf("cannot read %s: %v", path, err),
+			"Grant the bridge read access to the directory")
+	}
+
+	matching := 0
+	for _, entry := range entries {
+		if !entry.IsDir() && matchesAny(c.config.ScreenPipe.WatchPatterns, entry.Name()) {
+			matching++
+		}
+	}
+	if matching == 0 {
+		return warn(result, fmt.Sprintf("%s is readable but has no files matching %v", c.describePath("screenpipe.output_path", path), c.config.ScreenPipe.WatchPatterns),
+			"Check that ScreenPipe is running and screenpipe.watch_patterns matches its output")
+	}
+	return ok(result, fmt.Sprintf("%s readable, %d matching files", c.describePath("screenpipe.output_path", path), matching))
+}
+
+// checkWatchCapacity opens a watcher on the output directory and compares
+// the directories to watch against the platform's watch limit
+func (c *Checker) checkWatchCapacity() Result {
+	path := c.config.ScreenPipe.OutputPath
+	result := Result{Name: "file watching"}
+
+	if _, err := os.Stat(path); err != nil {
+		return warn(result, "skipped until the ScreenPipe output directory is accessible", "")
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fail(result, fmt.Sprintf("cannot create watcher: %v", err), watchLimitFix)
+	}
+	defer fsWatcher.Close()
+
+	if err := fsWatcher.Add(path); err != nil {
+		return fail(result, fmt.Sprintf("cannot watch %s: %v", path, err), watchLimitFix)
+	}
+
+	dirs := len(watcher.Directories(&c.config.ScreenPipe))
+	limit, known := watchLimit()
+	if !known {
+		return ok(result, fmt.Sprintf("watching %s (%d directories)", path, dirs))
+	}
+	if dirs > limit {
+		return warn(result, fmt.Sprintf("%d directories exceed the watch limit of %d; the rest will be polled every %s",
+			dirs, limit, c.config.ScreenPipe.PollInterval), watchLimitFix)
+	}
+	if dirs > limit/2 {
+		return warn(result, fmt.Sprintf("%d directories use over half the watch limit of %d", dirs, limit), watchLimitFix)
+	}
+	return ok(result, fmt.Sprintf("watching %s (%d directories, limit %d)", path, dirs, limit))
+}
+
+// checkVault checks that the vault exists, looks like a vault and that the
+// notes directory is writable
+func (c *Checker) checkVault() Result {
+	vault := c.config.Obsidian.VaultPath
+	result := Result{Name: "obsidian vault"}
+
+	if _, err := os.Stat(vault); err != nil {
+		fix := "Set obsidian.vault_path to your vault's root folder"
+		if vaults := paths.Vaults(); len(vaults) > 0 {
+			fix += fmt.Sprintf(", e.g. %s (registered with Obsidian)", vaults[0].Path)
+		}
+		return fail(result, fmt.Sprintf("%s is not accessible: %v", c.describePath("obsidian.vault_path", vault), err), fix)
+	}
+
+	notesPath := c.config.GetObsidianNotesPath()
+	if err := os.MkdirAll(notesPath, 0755); err != nil {
+		return fail(result, fmt.Sprintf("cannot create %s: %v", notesPath, err),
+			"Grant the bridge write access to the vault")
+	}
+	probe, err := os.CreateTemp(notesPath, ".bridge-doctor-*")
+	if err != nil {
+		return fail(result, fmt.Sprintf("%s is not writable: %v", notesPath, err),
+			"Grant the bridge write access to the vault")
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if _, err := os.Stat(filepath.Join(vault, ".obsidian")); err != nil {
+		return warn(result, fmt.Sprintf("%s is writable but has no .obsidian folder", vault),
+			"Point obsidian.vault_path at the vault root, or open the folder once in Obsidian")
+	}
+	return ok(result, fmt.Sprintf("%s writable", c.describePath("obsidian.vault_path", notesPath)))
+}
+
+// describePath adds why a path was chosen when it was detected rather than
+// configured
+func (c *Checker) describePath(key, path string) string {
+	if source := c.config.Source(key); config.IsDefault(source) && source != config.SourceDefault {
+		return fmt.Sprintf("%s (%s)", path, source)
+	}
+	return path
+}
+
+// checkFilenameTemplate checks obsidian.filename_template
+func (c *Checker) checkFilenameTemplate() Result {
+	result := Result{Name: "filename template"}
+	if err := config.ValidateFilenameTemplate(c.config.Obsidian.FilenameTemplate); err != nil {
+		return fail(result, err.Error(),
+			"Use only {{.Timestamp}}, {{.Hash}}, {{.Date}} and {{.Time}} in obsidian.filename_template")
+	}
+	return ok(result, c.config.Obsidian.FilenameTemplate)
+}
+
+// checkLLM makes a cheap authenticated call to the provider
+func (c *Checker) checkLLM(ctx context.Context) Result {
+	result := Result{Name: "llm"}
+
+	keys, err := credentials.New(&c.config.LLM, &c.config.Security)
+	if err != nil {
+		return fail(result, err.Error(), "Check llm.credentials")
+	}
+	client, err := llm.NewClient(&c.config.LLM, keys, nil)
+	if err != nil {
+		return fail(result, err.Error(), "Set llm.provider to a supported provider (openai)")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := client.Ping(ctx); err != nil {
+		message := err.Error()
+		fix := "Check llm.endpoint and network access to the provider"
+		if strings.Contains(message, "401") || strings.Contains(strings.ToLower(message), "api key") {
+			fix = "The API key was rejected; update llm.api_key, llm.credentials or OPENAI_API_KEY"
+		}
+		return fail(result, message, fix)
+	}
+	return ok(result, fmt.Sprintf("%s reachable in %s", client.GetProvider(), time.Since(start).Round(time.Millisecond)))
+}
+
+// checkTranscription checks that audio files can be transcribed when the
+// watch patterns pick them up
+func (c *Checker) checkTranscription() Result {
+	result := Result{Name: "transcription"}
+	cfg := c.config.Transcription
+
+	switch cfg.Provider {
+	case "":
+		if pattern := c.watchPatternFor(".wav", ".mp3", ".m4a", ".flac"); pattern != "" {
+			return warn(result, fmt.Sprintf("watch pattern %q matches audio files but transcription is disabled", pattern),
+				"Set transcription.provider to openai or whisper_cpp, or stop watching audio files")
+		}
+		return ok(result, "disabled")
+	case "whisper_cpp":
+		whisper, err := transcribe.NewWhisperCpp(&cfg)
+		if err != nil {
+			return fail(result, err.Error(), "Install whisper.cpp and set transcription.binary to its whisper-cli executable")
+		}
+		if _, err := os.Stat(cfg.ModelPath); err != nil {
+			return fail(result, fmt.Sprintf("model %s: %v", cfg.ModelPath, err), "Download a ggml model, e.g. with whisper.cpp's models/download-ggml-model.sh, and set transcription.model_path")
+		}
+		return ok(result, fmt.Sprintf("%s with %s", whisper.Binary(), cfg.ModelPath))
+	default:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = c.config.LLM.Endpoint
+		}
+		if endpoint == "" {
+			endpoint = "https://api.openai.com/v1"
+		}
+		return ok(result, fmt.Sprintf("%s %s at %s, using the LLM credentials", cfg.Provider, cfg.Model, endpoint))
+	}
+}
+
+// checkVideo checks that ffmpeg and tesseract are installed when the watch
+// patterns pick up screen recordings
+func (c *Checker) checkVideo() Result {
+	result := Result{Name: "video"}
+
+	pattern := c.watchPatternFor(".mp4", ".mkv", ".mov")
+	if pattern == "" {
+		return ok(result, "no watch pattern matches video files")
+	}
+	extractor := video.New(&c.config.Video, &c.config.OCR)
+	if err := extractor.Available(); err != nil {
+		return warn(result, fmt.Sprintf("watch pattern %q matches video files, which are skipped: %v", pattern, err),
+			"Install ffmpeg and tesseract, or set video.ffmpeg and ocr.tesseract to their paths")
+	}
+	return ok(result, fmt.Sprintf("%s, sampling by %s", extractor.FFmpeg(), c.config.Video.Sampling))
+}
+
+// checkImages checks that screenshots can be read by OCR or a vision model
+// when the watch patterns pick them up
+func (c *Checker) checkImages() Result {
+	result := Result{Name: "images"}
+
+	pattern := c.watchPatternFor(".png", ".jpg", ".jpeg")
+	if pattern == "" {
+		return ok(result, "no watch pattern matches image files")
+	}
+
+	readers := []string{}
+	tesseract, ocrErr := ocr.NewTesseract(&c.config.OCR)
+	if ocrErr == nil {
+		readers = append(readers, "OCR with "+tesseract.Binary())
+	}
+	if vision := c.config.Vision; vision.Provider != "" {
+		readers = append(readers, fmt.Sprintf("%s %s", vision.Provider, vision.Model))
+	}
+	if len(readers) == 0 {
+		return warn(result, fmt.Sprintf("watch pattern %q matches image files, which are skipped: %v and vision is disabled", pattern, ocrErr),
+			"Install tesseract or set ocr.tesseract to its path, or set vision.provider")
+	}
+	return ok(result, strings.Join(readers, " and "))
+}
+
+// watchPatternFor returns the first watch pattern that matches a file with
+// one of the extensions, or "" if none does
+func (c *Checker) watchPatternFor(extensions ...string) string {
+	for _, pattern := range c.config.ScreenPipe.WatchPatterns {
+		for _, ext := range extensions {
+			if matched, _ := filepath.Match(pattern, "capture"+ext); matched {
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// checkDiskSpace checks the free space on the filesystem holding path
+func (c *Checker) checkDiskSpace(name, path string) Result {
+	result := Result{Name: name}
+
+	free, err := freeSpace(existingParent(path))
+	if err != nil {
+		return warn(result, fmt.Sprintf("cannot determine free space for %s: %v", path, err), "")
+	}
+
+	detail := fmt.Sprintf("%s free on %s", formatBytes(free), path)
+	switch {
+	case free < criticalDiskSpace:
+		return fail(result, detail, "Free up disk space; notes and state cannot be written reliably")
+	case free < lowDiskSpace:
+		return warn(result, detail, "Free up disk space soon")
+	}
+	return ok(result, detail)
+}
+
+// ok, warn and fail complete a result with its status
+func ok(result Result, detail string) Result {
+	result.Status, result.Detail = StatusOK, detail
+	return result
+}
+
+func warn(result Result, detail, fix string) Result {
+	result.Status, result.Detail, result.Fix = StatusWarn, detail, fix
+	return result
+}
+
+func fail(result Result, detail, fix string) Result {
+	result.Status, result.Detail, result.Fix = StatusFail, detail, fix
+	return result
+}
+
+// matchesAny reports whether name matches one of the watch patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// existingParent returns path or its nearest existing ancestor, so free space
+// can be checked before a directory is created
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// formatBytes renders a byte count for humans
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
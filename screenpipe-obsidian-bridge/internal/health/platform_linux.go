@@ -0,0 +1,23 @@
+package health
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// watchLimitFix explains how to raise the inotify watch limit
+const watchLimitFix = "Raise the inotify limit: sudo sysctl fs.inotify.max_user_watches=524288 (persist it in /etc/sysctl.d/)"
+
+// watchLimit returns the per-user inotify watch limit
+func watchLimit() (int, bool) {
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package health
+
+// watchLimitFix explains how to resolve watcher failures
+const watchLimitFix = "Check that the bridge can open file system notifications for screenpipe.output_path"
+
+// watchLimit reports that the platform has no per-user watch limit to check
+func watchLimit() (int, bool) {
+	return 0, false
+}
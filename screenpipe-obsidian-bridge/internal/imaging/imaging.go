@@ -0,0 +1,74 @@
+// Package imaging scales screenshots and video frames down for uploads and
+// vault attachments.
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// JPEGQuality is used for images that are re-encoded after scaling
+const JPEGQuality = 85
+
+// Fit scales width and height so the longer side is at most dimension,
+// keeping the aspect ratio
+func Fit(width, height, dimension int) (int, int) {
+	longest := max(width, height)
+	if longest <= dimension {
+		return width, height
+	}
+	return max(1, width*dimension/longest), max(1, height*dimension/longest)
+}
+
+// Resize scales img to width x height, averaging the source pixels that fall
+// into each target pixel so small text is blurred rather than dropped
+func Resize(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return img
+	}
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * bounds.Dy() / height
+		y1 := max(y0+1, (y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := x * bounds.Dx() / width
+			x1 := max(x0+1, (x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					a += int(row[sx*4+3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Downscale fits img into dimension on its longer side and encodes it as
+// JPEG
+func Downscale(img image.Image, dimension int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := Fit(bounds.Dx(), bounds.Dy(), dimension)
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, Resize(img, width, height), &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
@@ -2,6 +2,14 @@ package llm
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
 )
 
 // Client represents an LLM client interface
@@ -10,56 +18,169 @@ type Client interface {
 	ProcessContent(ctx context.Context, content string, sourceFile string) (*ProcessingResult, error)
 	// GetProvider returns the name of the LLM provider
 	GetProvider() string
+	// Ping checks that the provider is reachable and accepts the API key
+	// with a call that consumes no tokens
+	Ping(ctx context.Context) error
+}
+
+// Embedder is implemented by clients whose provider can embed text
+type Embedder interface {
+	// Embed returns the embeddings of texts, in order, computed with model
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// NewClient creates the client for the configured provider
+func NewClient(cfg *config.LLMConfig, keys credentials.Provider, auditLog *audit.Logger) (Client, error) {
+	templates, err := LoadPromptTemplates(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Provider {
+	case "openai":
+		return NewOpenAIClient(cfg, keys, auditLog, templates), nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
 }
 
 // ProcessingResult contains the structured output from LLM processing
 type ProcessingResult struct {
 	// Summary of the activity/content
 	ActivitySummary string `json:"activity_summary"`
-	
+
 	// List of actionable tasks extracted from the content
 	ActionableTasks []string `json:"actionable_tasks"`
-	
+
+	// TaskDetails holds the dates, priority and recurrence given for
+	// ActionableTasks[i]; nil when no task has any
+	TaskDetails []TaskDetail `json:"task_details,omitempty"`
+
 	// Doctrine compliance check results
 	DoctrineCompliance DoctrineCheck `json:"doctrine_compliance"`
-	
+
+	// People, projects and apps named in the content, when llm.extract_entities is set
+	Entities []Entity `json:"entities,omitempty"`
+
 	// Metadata about the processing
 	Metadata ProcessingMetadata `json:"metadata"`
 }
 
+// TaskDetail is what the LLM found about a task besides its text. Dates are
+// YYYY-MM-DD.
+type TaskDetail struct {
+	Due       string `json:"due,omitempty"`
+	Scheduled string `json:"scheduled,omitempty"`
+	Start     string `json:"start,omitempty"`
+	// Priority is highest, high, medium, low or lowest
+	Priority string `json:"priority,omitempty"`
+	// Recurrence is a rule such as "every week"
+	Recurrence string `json:"recurrence,omitempty"`
+}
+
+// IsZero reports whether nothing is known about the task
+func (d TaskDetail) IsZero() bool {
+	return d == TaskDetail{}
+}
+
+// DetailOf returns the details of ActionableTasks[i]
+func (r *ProcessingResult) DetailOf(i int) TaskDetail {
+	if i < len(r.TaskDetails) {
+		return r.TaskDetails[i]
+	}
+	return TaskDetail{}
+}
+
 // DoctrineCheck contains compliance analysis
 type DoctrineCheck struct {
 	// Whether the content appears to follow naming conventions
 	NamingConventionCompliant bool `json:"naming_convention_compliant"`
-	
+
 	// Issues found during compliance check
 	Issues []string `json:"issues"`
-	
+
 	// Suggestions for improvement
 	Suggestions []string `json:"suggestions"`
-	
+
 	// Overall compliance score (0-100)
 	ComplianceScore int `json:"compliance_score"`
 }
 
+// Entity is a person, project or app named in the content
+type Entity struct {
+	Name string `json:"name"`
+	// Kind is one of the EntityKind constants
+	Kind string `json:"kind"`
+}
+
+// Entity kinds
+const (
+	EntityPerson  = "person"
+	EntityProject = "project"
+	EntityApp     = "app"
+)
+
 // ProcessingMetadata contains information about the processing
 type ProcessingMetadata struct {
 	// Model used for processing
 	Model string `json:"model"`
-	
+
 	// Provider that processed the content
 	Provider string `json:"provider"`
-	
+
 	// Processing timestamp
 	ProcessedAt string `json:"processed_at"`
-	
+
 	// Source file that was processed
 	SourceFile string `json:"source_file"`
-	
+
+	// Source type reported by extraction (text, json, markdown, ...)
+	SourceType string `json:"source_type"`
+
+	// Length and spoken language of audio sources, length and frame size
+	// of video sources
+	Duration   string `json:"duration,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+
+	// Attachments are source media stored in the vault and embedded in the
+	// note
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Related are the vault paths of the entity notes the note links to,
+	// set when the note is written
+	Related []string `json:"related,omitempty"`
+
+	// RepeatedTasks are the action items merged into ones from recent
+	// notes instead of being listed again
+	RepeatedTasks []RepeatedTask `json:"repeated_tasks,omitempty"`
+
 	// Token usage information
 	TokenUsage TokenUsage `json:"token_usage"`
 }
 
+// RepeatedTask is an action item that repeats one from a recent note
+type RepeatedTask struct {
+	Text string `json:"text"`
+	// Note is the vault-relative path, without extension, of the note
+	// the action item was first written to
+	Note string `json:"note"`
+}
+
+// Attachment is a piece of source media embedded in a note
+type Attachment struct {
+	// Kind is "image", "keyframe" or "audio"
+	Kind string `json:"kind"`
+	// Source is the captured file; for keyframes, the recording
+	Source string `json:"source"`
+	// Data holds media that only exists in memory, such as keyframes
+	Data []byte `json:"-"`
+	// Offset is where a keyframe is in its recording
+	Offset time.Duration `json:"offset,omitempty"`
+	// Name is the file name in the vault, set when the note is written
+	Name string `json:"name,omitempty"`
+}
+
 // TokenUsage tracks token consumption
 type TokenUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
@@ -67,11 +188,46 @@ type TokenUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
-// PromptTemplates contains the system prompts for different tasks
+// Prompt template identifiers recorded in the audit log
+const (
+	TemplateActivityAnalysis   = "activity_analysis"
+	TemplateTaskExtraction     = "task_extraction"
+	TemplateDoctrineCompliance = "doctrine_compliance"
+	TemplateEntityExtraction   = "entity_extraction"
+	// TemplateTaskEmbedding marks embedding requests, which have no template
+	TemplateTaskEmbedding = "task_embedding"
+	// TemplateTranscription marks audio transcription requests and
+	// TemplateScreenshotDescription vision requests
+	TemplateTranscription         = "transcription"
+	TemplateScreenshotDescription = "screenshot_description"
+)
+
+// doctrineJSONInstruction asks for the JSON shape parsed into DoctrineCheck
+const doctrineJSONInstruction = "\n\nPlease respond in JSON format with fields: naming_convention_compliant (boolean), issues (array), suggestions (array), compliance_score (integer 0-100)."
+
+// entityJSONInstruction asks for the JSON shape parsed by parseEntities
+const entityJSONInstruction = "\n\nRespond only with a JSON object with the fields people, projects and apps, each an array of names as they appear in the content."
+
+// taskDetailsInstruction asks for the task fields parsed by splitTaskDetails;
+// the date lets the LLM resolve "by Friday"
+func taskDetailsInstruction(now time.Time) string {
+	return fmt.Sprintf("\n\nWrite one task per line. When the content says so, add any of these fields after a task, each after \" | \": due, scheduled or start, as YYYY-MM-DD (today is %s); priority, as highest, high, medium, low or lowest; recurrence, as \"every ...\" (for example \"every week\"). For example:\n- Send the quarterly report to Dana | due: 2025-01-31 | priority: high",
+		now.Format("Monday, 2006-01-02"))
+}
+
+// Prompt is a rendered prompt as sent to the provider
+type Prompt struct {
+	Template string `json:"template"`
+	Text     string `json:"text"`
+}
+
+// PromptTemplates contains the system prompts for different tasks. An empty
+// template is not sent.
 type PromptTemplates struct {
 	ActivityAnalysis   string
 	TaskExtraction     string
 	DoctrineCompliance string
+	EntityExtraction   string
 }
 
 // DefaultPromptTemplates returns the default prompts used for processing
@@ -110,5 +266,80 @@ Content:
 
 Provide a compliance analysis with specific issues found and suggestions for improvement.
 Rate compliance on a scale of 0-100.`,
+
+		EntityExtraction: `List the people, projects and applications named in the following ScreenPipe content.
+- People: names of colleagues, contacts and authors
+- Projects: projects, products and code repositories being worked on
+- Apps: applications and websites being used
+Leave out generic terms and anything you are unsure about.
+
+Content:
+%s`,
 	}
-} 
\ No newline at end of file
+}
+
+// Render fills the template identified by templateID with content, exactly
+// as it is sent to the provider
+func (t PromptTemplates) Render(templateID, content string) string {
+	switch templateID {
+	case TemplateActivityAnalysis:
+		return fmt.Sprintf(t.ActivityAnalysis, content)
+	case TemplateTaskExtraction:
+		return fmt.Sprintf(t.TaskExtraction, content) + taskDetailsInstruction(time.Now())
+	case TemplateDoctrineCompliance:
+		return fmt.Sprintf(t.DoctrineCompliance, content) + doctrineJSONInstruction
+	case TemplateEntityExtraction:
+		if t.EntityExtraction == "" {
+			return ""
+		}
+		return fmt.Sprintf(t.EntityExtraction, content) + entityJSONInstruction
+	}
+	return ""
+}
+
+// RenderAll returns every prompt sent for content, in call order
+func (t PromptTemplates) RenderAll(content string) []Prompt {
+	ids := []string{TemplateActivityAnalysis, TemplateTaskExtraction, TemplateDoctrineCompliance, TemplateEntityExtraction}
+	prompts := make([]Prompt, 0, len(ids))
+	for _, id := range ids {
+		if text := t.Render(id, content); text != "" {
+			prompts = append(prompts, Prompt{Template: id, Text: text})
+		}
+	}
+	return prompts
+}
+
+// LoadPromptTemplates returns the built-in templates with any configured
+// template files substituted. Entity extraction is only sent when
+// llm.extract_entities is set.
+func LoadPromptTemplates(llmCfg *config.LLMConfig) (PromptTemplates, error) {
+	cfg := &llmCfg.Prompts
+	templates := DefaultPromptTemplates()
+	if !llmCfg.ExtractEntities {
+		templates.EntityExtraction = ""
+	}
+	for _, prompt := range []struct {
+		key    string
+		path   string
+		target *string
+	}{
+		{"llm.prompts.activity_analysis", cfg.ActivityAnalysis, &templates.ActivityAnalysis},
+		{"llm.prompts.task_extraction", cfg.TaskExtraction, &templates.TaskExtraction},
+		{"llm.prompts.doctrine_compliance", cfg.DoctrineCompliance, &templates.DoctrineCompliance},
+		{"llm.prompts.entity_extraction", cfg.EntityExtraction, &templates.EntityExtraction},
+	} {
+		if prompt.path == "" || *prompt.target == "" {
+			continue
+		}
+		data, err := os.ReadFile(prompt.path)
+		if err != nil {
+			return templates, fmt.Errorf("%s: failed to read template: %w", prompt.key, err)
+		}
+		text := string(data)
+		if strings.Count(text, "%s") != 1 || strings.Count(text, "%") != 1 {
+			return templates, fmt.Errorf("%s: %s must contain exactly one %%s for the content and no other %% signs", prompt.key, prompt.path)
+		}
+		*prompt.target = text
+	}
+	return templates, nil
+}
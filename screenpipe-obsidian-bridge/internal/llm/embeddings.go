@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/logging"
+)
+
+// embeddingRequest and embeddingResponse are the embeddings API's bodies
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Embed implements Embedder with the embeddings API, which OpenAI and local
+// servers such as Ollama accept. Requests are audited like completions.
+func (c *OpenAIClient) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	start := time.Now()
+	embeddings, usage, err := c.embed(ctx, model, texts)
+
+	source, _ := ctx.Value(sourceKey{}).(sourceInfo)
+	record := audit.Record{
+		Timestamp:      start.UTC(),
+		Provider:       c.GetProvider(),
+		Model:          model,
+		PromptTemplate: TemplateTaskEmbedding,
+		SourceFile:     source.file,
+		ContentHash:    source.hash,
+		CorrelationID:  logging.CorrelationID(ctx),
+		Prompt:         strings.Join(texts, "\n"),
+		LatencyMS:      time.Since(start).Milliseconds(),
+		Outcome:        audit.OutcomeSuccess,
+		TokenUsage:     audit.TokenUsage{PromptTokens: usage.PromptTokens, TotalTokens: usage.TotalTokens},
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	}
+	RecordCall(ctx, c.auditLog, c.config.Pricing, record)
+
+	return embeddings, err
+}
+
+// embed requests the embeddings of texts
+func (c *OpenAIClient) embed(ctx context.Context, model string, texts []string) ([][]float32, TokenUsage, error) {
+	body, err := json.Marshal(embeddingRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, TokenUsage{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, TokenUsage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, TokenUsage{}, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, TokenUsage{}, fmt.Errorf("failed to read embeddings: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, TokenUsage{}, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var parsed embeddingResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, TokenUsage{}, fmt.Errorf("failed to parse embeddings: %w", err)
+	}
+	usage := TokenUsage{PromptTokens: parsed.Usage.PromptTokens, TotalTokens: parsed.Usage.TotalTokens}
+	embeddings := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, usage, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, usage, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return embeddings, usage, nil
+}
@@ -2,36 +2,56 @@ package llm
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
+	"screenpipe-obsidian-bridge/internal/audit"
 	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
 )
 
+var logger = logging.For(logging.ComponentLLM)
+
 // OpenAIClient implements the Client interface for OpenAI
 type OpenAIClient struct {
-	client    *openai.Client
+	client *openai.Client
+	// embeddings are requested directly, since the client library only
+	// knows older embedding models
+	http      *http.Client
+	baseURL   string
 	config    *config.LLMConfig
 	templates PromptTemplates
+	auditLog  *audit.Logger
 }
 
-// NewOpenAIClient creates a new OpenAI client
-func NewOpenAIClient(cfg *config.LLMConfig) *OpenAIClient {
-	client := openai.NewClient(cfg.APIKey)
-	
+// NewOpenAIClient creates a new OpenAI client. The API key is not captured
+// here; keys asks the credential provider for one on every request.
+func NewOpenAIClient(cfg *config.LLMConfig, keys credentials.Provider, auditLog *audit.Logger, templates PromptTemplates) *OpenAIClient {
+	clientConfig := openai.DefaultConfig("")
+	clientConfig.HTTPClient = credentials.NewHTTPClient(keys)
+
 	// Set custom base URL if provided
 	if cfg.Endpoint != "" && cfg.Endpoint != "https://api.openai.com/v1" {
-		config := openai.DefaultConfig(cfg.APIKey)
-		config.BaseURL = cfg.Endpoint
-		client = openai.NewClientWithConfig(config)
+		clientConfig.BaseURL = cfg.Endpoint
 	}
 
+	client := openai.NewClientWithConfig(clientConfig)
+
 	return &OpenAIClient{
 		client:    client,
+		http:      clientConfig.HTTPClient,
+		baseURL:   strings.TrimSuffix(clientConfig.BaseURL, "/"),
 		config:    cfg,
-		templates: DefaultPromptTemplates(),
+		templates: templates,
+		auditLog:  auditLog,
 	}
 }
 
@@ -39,33 +59,45 @@ func NewOpenAIClient(cfg *config.LLMConfig) *OpenAIClient {
 func (c *OpenAIClient) ProcessContent(ctx context.Context, content string, sourceFile string) (*ProcessingResult, error) {
 	// TODO: In a production version, we might want to process these in parallel
 	// For now, we'll do them sequentially to stay within rate limits
-	
+
+	ctx = withSource(ctx, sourceFile, content)
+
 	activitySummary, tokenUsage1, err := c.generateActivitySummary(ctx, content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate activity summary: %w", err)
+		return nil, fmt.Errorf("failed to generate activity summary: %w", credentials.RedactError(err))
 	}
 
-	actionableTasks, tokenUsage2, err := c.extractActionableTasks(ctx, content)
+	actionableTasks, taskDetails, tokenUsage2, err := c.extractActionableTasks(ctx, content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract actionable tasks: %w", err)
+		return nil, fmt.Errorf("failed to extract actionable tasks: %w", credentials.RedactError(err))
 	}
 
 	doctrineCheck, tokenUsage3, err := c.checkDoctrineCompliance(ctx, content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check doctrine compliance: %w", err)
+		return nil, fmt.Errorf("failed to check doctrine compliance: %w", credentials.RedactError(err))
+	}
+
+	var entities []Entity
+	var tokenUsage4 TokenUsage
+	if c.templates.EntityExtraction != "" {
+		if entities, tokenUsage4, err = c.extractEntities(ctx, content); err != nil {
+			return nil, fmt.Errorf("failed to extract entities: %w", credentials.RedactError(err))
+		}
 	}
 
 	// Combine token usage
 	totalTokenUsage := TokenUsage{
-		PromptTokens:     tokenUsage1.PromptTokens + tokenUsage2.PromptTokens + tokenUsage3.PromptTokens,
-		CompletionTokens: tokenUsage1.CompletionTokens + tokenUsage2.CompletionTokens + tokenUsage3.CompletionTokens,
-		TotalTokens:      tokenUsage1.TotalTokens + tokenUsage2.TotalTokens + tokenUsage3.TotalTokens,
+		PromptTokens:     tokenUsage1.PromptTokens + tokenUsage2.PromptTokens + tokenUsage3.PromptTokens + tokenUsage4.PromptTokens,
+		CompletionTokens: tokenUsage1.CompletionTokens + tokenUsage2.CompletionTokens + tokenUsage3.CompletionTokens + tokenUsage4.CompletionTokens,
+		TotalTokens:      tokenUsage1.TotalTokens + tokenUsage2.TotalTokens + tokenUsage3.TotalTokens + tokenUsage4.TotalTokens,
 	}
 
 	result := &ProcessingResult{
 		ActivitySummary:    activitySummary,
 		ActionableTasks:    actionableTasks,
+		TaskDetails:        taskDetails,
 		DoctrineCompliance: *doctrineCheck,
+		Entities:           entities,
 		Metadata: ProcessingMetadata{
 			Model:       c.config.Model,
 			Provider:    c.GetProvider(),
@@ -83,11 +115,19 @@ func (c *OpenAIClient) GetProvider() string {
 	return "openai"
 }
 
+// Ping implements Client.Ping by listing the available models
+func (c *OpenAIClient) Ping(ctx context.Context) error {
+	if _, err := c.client.ListModels(ctx); err != nil {
+		return credentials.RedactError(err)
+	}
+	return nil
+}
+
 // generateActivitySummary creates a summary of the user's activity
 func (c *OpenAIClient) generateActivitySummary(ctx context.Context, content string) (string, TokenUsage, error) {
-	prompt := fmt.Sprintf(c.templates.ActivityAnalysis, content)
-	
-	response, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	prompt := c.templates.Render(TemplateActivityAnalysis, content)
+
+	response, err := c.complete(ctx, TemplateActivityAnalysis, openai.ChatCompletionRequest{
 		Model: c.config.Model,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -116,11 +156,12 @@ func (c *OpenAIClient) generateActivitySummary(ctx context.Context, content stri
 	return response.Choices[0].Message.Content, tokenUsage, nil
 }
 
-// extractActionableTasks extracts tasks from the content
-func (c *OpenAIClient) extractActionableTasks(ctx context.Context, content string) ([]string, TokenUsage, error) {
-	prompt := fmt.Sprintf(c.templates.TaskExtraction, content)
-	
-	response, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+// extractActionableTasks extracts tasks, and any dates, priority and
+// recurrence given for them, from the content
+func (c *OpenAIClient) extractActionableTasks(ctx context.Context, content string) ([]string, []TaskDetail, TokenUsage, error) {
+	prompt := c.templates.Render(TemplateTaskExtraction, content)
+
+	response, err := c.complete(ctx, TemplateTaskExtraction, openai.ChatCompletionRequest{
 		Model: c.config.Model,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -133,7 +174,7 @@ func (c *OpenAIClient) extractActionableTasks(ctx context.Context, content strin
 	})
 
 	if err != nil {
-		return nil, TokenUsage{}, err
+		return nil, nil, TokenUsage{}, err
 	}
 
 	tokenUsage := TokenUsage{
@@ -143,27 +184,27 @@ func (c *OpenAIClient) extractActionableTasks(ctx context.Context, content strin
 	}
 
 	if len(response.Choices) == 0 {
-		return nil, tokenUsage, fmt.Errorf("no response choices returned")
+		return nil, nil, tokenUsage, fmt.Errorf("no response choices returned")
 	}
 
 	// TODO: Parse the response more intelligently
 	// For now, we'll split by lines and clean up
 	content = response.Choices[0].Message.Content
-	tasks := parseTaskList(content)
+	tasks, details := splitTaskDetails(parseTaskList(content))
 
-	return tasks, tokenUsage, nil
+	return tasks, details, tokenUsage, nil
 }
 
 // checkDoctrineCompliance analyzes content for compliance
 func (c *OpenAIClient) checkDoctrineCompliance(ctx context.Context, content string) (*DoctrineCheck, TokenUsage, error) {
-	prompt := fmt.Sprintf(c.templates.DoctrineCompliance, content)
-	
-	response, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	prompt := c.templates.Render(TemplateDoctrineCompliance, content)
+
+	response, err := c.complete(ctx, TemplateDoctrineCompliance, openai.ChatCompletionRequest{
 		Model: c.config.Model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleUser,
-				Content: prompt + "\n\nPlease respond in JSON format with fields: naming_convention_compliant (boolean), issues (array), suggestions (array), compliance_score (integer 0-100).",
+				Content: prompt,
 			},
 		},
 		MaxTokens:   c.config.MaxTokens / 3,
@@ -187,20 +228,182 @@ func (c *OpenAIClient) checkDoctrineCompliance(ctx context.Context, content stri
 	// Try to parse JSON response
 	var doctrineCheck DoctrineCheck
 	responseContent := response.Choices[0].Message.Content
-	
+
 	if err := json.Unmarshal([]byte(responseContent), &doctrineCheck); err != nil {
 		// Fallback to default if JSON parsing fails
 		doctrineCheck = DoctrineCheck{
 			NamingConventionCompliant: true, // Default to compliant
-			Issues:                   []string{},
-			Suggestions:              []string{"Unable to parse compliance check response"},
-			ComplianceScore:          50, // Neutral score
+			Issues:                    []string{},
+			Suggestions:               []string{"Unable to parse compliance check response"},
+			ComplianceScore:           50, // Neutral score
 		}
 	}
 
 	return &doctrineCheck, tokenUsage, nil
 }
 
+// extractEntities lists the people, projects and apps named in the content
+func (c *OpenAIClient) extractEntities(ctx context.Context, content string) ([]Entity, TokenUsage, error) {
+	prompt := c.templates.Render(TemplateEntityExtraction, content)
+
+	response, err := c.complete(ctx, TemplateEntityExtraction, openai.ChatCompletionRequest{
+		Model: c.config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt,
+			},
+		},
+		MaxTokens:   c.config.MaxTokens / 3,
+		Temperature: 0.1,
+	})
+	if err != nil {
+		return nil, TokenUsage{}, err
+	}
+
+	tokenUsage := TokenUsage{
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+	}
+	if len(response.Choices) == 0 {
+		return nil, tokenUsage, fmt.Errorf("no response choices returned")
+	}
+
+	entities, err := parseEntities(response.Choices[0].Message.Content)
+	if err != nil {
+		// Links are an extra; the note is still worth writing
+		logger.WarnContext(ctx, "cannot parse entity list", "error", err)
+	}
+	return entities, tokenUsage, nil
+}
+
+// parseEntities reads the entity list answered to the entity extraction
+// prompt, which models often wrap in a code fence
+func parseEntities(content string) ([]Entity, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+
+	var lists struct {
+		People   []string `json:"people"`
+		Projects []string `json:"projects"`
+		Apps     []string `json:"apps"`
+	}
+	if err := json.Unmarshal([]byte(content), &lists); err != nil {
+		return nil, err
+	}
+
+	entities := []Entity{}
+	seen := map[string]bool{}
+	for _, group := range []struct {
+		kind  string
+		names []string
+	}{
+		{EntityPerson, lists.People},
+		{EntityProject, lists.Projects},
+		{EntityApp, lists.Apps},
+	} {
+		for _, name := range group.names {
+			name = strings.TrimSpace(name)
+			key := group.kind + "/" + strings.ToLower(name)
+			if name == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			entities = append(entities, Entity{Name: name, Kind: group.kind})
+		}
+	}
+	return entities, nil
+}
+
+// complete sends a chat completion request and writes an audit record for it
+func (c *OpenAIClient) complete(ctx context.Context, templateID string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	start := time.Now()
+	response, err := c.client.CreateChatCompletion(ctx, req)
+
+	source, _ := ctx.Value(sourceKey{}).(sourceInfo)
+	record := audit.Record{
+		Timestamp:      start.UTC(),
+		Provider:       c.GetProvider(),
+		Model:          req.Model,
+		PromptTemplate: templateID,
+		SourceFile:     source.file,
+		ContentHash:    source.hash,
+		CorrelationID:  logging.CorrelationID(ctx),
+		Redaction:      audit.Redaction{ExcludedItems: audit.ExcludedItems(ctx)},
+		LatencyMS:      time.Since(start).Milliseconds(),
+		Outcome:        audit.OutcomeSuccess,
+	}
+	if len(req.Messages) > 0 {
+		record.Prompt = req.Messages[len(req.Messages)-1].Content
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	} else {
+		record.TokenUsage = audit.TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		}
+		if len(response.Choices) > 0 {
+			record.Response = response.Choices[0].Message.Content
+		}
+	}
+
+	RecordCall(ctx, c.auditLog, c.config.Pricing, record)
+
+	logger.DebugContext(ctx, "chat completion",
+		"template", templateID,
+		"model", req.Model,
+		"latency_ms", record.LatencyMS,
+		"outcome", record.Outcome,
+		"total_tokens", record.TokenUsage.TotalTokens)
+
+	return response, err
+}
+
+// RecordCall updates the LLM request, latency, token and cost metrics for a
+// model call and writes its audit record. Calls made outside the client,
+// such as screenshot descriptions, are recorded through it too.
+func RecordCall(ctx context.Context, auditLog *audit.Logger, pricing map[string]config.ModelPrice, record audit.Record) {
+	provider, model := record.Provider, record.Model
+	metrics.LLMRequests.With(provider, model, record.Outcome).Inc()
+	metrics.LLMDuration.With(provider, model, record.PromptTemplate).Observe(float64(record.LatencyMS) / 1000)
+	metrics.LLMTokens.With(provider, model, "prompt").Add(float64(record.TokenUsage.PromptTokens))
+	metrics.LLMTokens.With(provider, model, "completion").Add(float64(record.TokenUsage.CompletionTokens))
+	metrics.LLMCost.With(provider, model).Add(EstimateCost(model, TokenUsage{
+		PromptTokens:     record.TokenUsage.PromptTokens,
+		CompletionTokens: record.TokenUsage.CompletionTokens,
+	}, pricing))
+
+	if err := auditLog.Log(record); err != nil {
+		logger.WarnContext(ctx, "failed to write audit record", "error", err)
+	}
+}
+
+// sourceKey keys the sourceInfo carried in a request context
+type sourceKey struct{}
+
+// sourceInfo identifies the content being processed for audit records
+type sourceInfo struct {
+	file string
+	hash string
+}
+
+// withSource stores the source file and content hash in ctx
+func withSource(ctx context.Context, sourceFile, content string) context.Context {
+	sum := sha256.Sum256([]byte(content))
+	return context.WithValue(ctx, sourceKey{}, sourceInfo{
+		file: sourceFile,
+		hash: hex.EncodeToString(sum[:]),
+	})
+}
+
 // parseTaskList extracts tasks from LLM response
 func parseTaskList(content string) []string {
 	// TODO: Implement more sophisticated parsing
@@ -219,6 +422,58 @@ func parseTaskList(content string) []string {
 	return lines
 }
 
+// taskPriorities are the priorities the Obsidian Tasks plugin knows
+var taskPriorities = map[string]bool{"highest": true, "high": true, "medium": true, "low": true, "lowest": true}
+
+// splitTaskDetails separates the fields asked for by taskDetailsInstruction
+// from the tasks' text. Parts that are not a known, well-formed field stay in
+// the text. It returns nil details when no task has any.
+func splitTaskDetails(lines []string) ([]string, []TaskDetail) {
+	tasks := make([]string, len(lines))
+	details := make([]TaskDetail, len(lines))
+	found := false
+	for i, line := range lines {
+		parts := strings.Split(line, " | ")
+		text := []string{parts[0]}
+		for _, part := range parts[1:] {
+			key, value, _ := strings.Cut(part, ":")
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.TrimSpace(value)
+			detail := &details[i]
+			switch {
+			case (key == "due" || key == "scheduled" || key == "start") && validDate(value):
+				switch key {
+				case "due":
+					detail.Due = value
+				case "scheduled":
+					detail.Scheduled = value
+				default:
+					detail.Start = value
+				}
+			case key == "priority" && taskPriorities[strings.ToLower(value)]:
+				detail.Priority = strings.ToLower(value)
+			case key == "recurrence" && strings.HasPrefix(strings.ToLower(value), "every "):
+				detail.Recurrence = strings.ToLower(value)
+			default:
+				text = append(text, part)
+				continue
+			}
+			found = true
+		}
+		tasks[i] = strings.Join(text, " | ")
+	}
+	if !found {
+		return tasks, nil
+	}
+	return tasks, details
+}
+
+// validDate reports whether s is a YYYY-MM-DD date
+func validDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
 // Helper functions (simplified implementations)
 func splitLines(s string) []string {
 	// Simple line splitting - in production you might want to use strings.Split
@@ -242,14 +497,14 @@ func trimSpace(s string) string {
 	// Simple trim implementation
 	start := 0
 	end := len(s)
-	
+
 	for start < end && (s[start] == ' ' || s[start] == '\t' || s[start] == '\n' || s[start] == '\r') {
 		start++
 	}
-	
+
 	for end > start && (s[end-1] == ' ' || s[end-1] == '\t' || s[end-1] == '\n' || s[end-1] == '\r') {
 		end--
 	}
-	
+
 	return s[start:end]
-} 
\ No newline at end of file
+}
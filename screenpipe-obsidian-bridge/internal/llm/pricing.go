@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// defaultPricing holds list prices in US dollars per million tokens. Models
+// are matched by the longest prefix, so dated snapshots share a price.
+var defaultPricing = map[string]config.ModelPrice{
+	"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.60},
+	"gpt-4o":        {Prompt: 2.50, Completion: 10.00},
+	"gpt-4-turbo":   {Prompt: 10.00, Completion: 30.00},
+	"gpt-4":         {Prompt: 30.00, Completion: 60.00},
+	"gpt-3.5-turbo": {Prompt: 0.50, Completion: 1.50},
+}
+
+// EstimateCost returns the dollar cost of a call from its token usage.
+// Prices in overrides take precedence; unknown models cost nothing.
+func EstimateCost(model string, usage TokenUsage, overrides map[string]config.ModelPrice) float64 {
+	price, ok := lookupPrice(model, overrides)
+	if !ok {
+		price, ok = lookupPrice(model, defaultPricing)
+	}
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+// lookupPrice finds the entry whose name is the longest prefix of model
+func lookupPrice(model string, prices map[string]config.ModelPrice) (config.ModelPrice, bool) {
+	var best string
+	for name := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return config.ModelPrice{}, false
+	}
+	return prices[best], true
+}
@@ -0,0 +1,204 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/rotate"
+)
+
+// Component names used for per-component levels
+const (
+	ComponentMain        = "main"
+	ComponentWatcher     = "watcher"
+	ComponentProcessor   = "processor"
+	ComponentLLM         = "llm"
+	ComponentTranscribe  = "transcribe"
+	ComponentVideo       = "video"
+	ComponentVision      = "vision"
+	ComponentObsidian    = "obsidian"
+	ComponentTasks       = "tasks"
+	ComponentCredentials = "credentials"
+	ComponentServer      = "server"
+	ComponentDeerflow    = "deerflow"
+)
+
+var (
+	mutex         sync.RWMutex
+	defaultLevel               = slog.LevelInfo
+	componentLvls              = map[string]slog.Level{}
+	base          slog.Handler = slog.NewTextHandler(credentials.RedactingWriter(os.Stderr), &slog.HandlerOptions{Level: slog.LevelDebug})
+)
+
+// Setup configures the process-wide logger from the logging configuration
+// and returns a closer for the log file, if any. Output always passes through
+// API key redaction.
+func Setup(cfg *config.LoggingConfig) (io.Closer, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("logging.level: %w", err)
+	}
+
+	levels := map[string]slog.Level{}
+	for component, text := range cfg.Components {
+		componentLevel, err := ParseLevel(text)
+		if err != nil {
+			return nil, fmt.Errorf("logging.components.%s: %w", component, err)
+		}
+		levels[component] = componentLevel
+	}
+
+	var output io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+	if cfg.File != "" {
+		file, err := rotate.New(cfg.File, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxBackups)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		output = file
+		closer = file
+	}
+	output = credentials.RedactingWriter(output)
+
+	// The base handler passes everything; filtering happens per component
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(output, options)
+	case "json":
+		handler = slog.NewJSONHandler(output, options)
+	default:
+		return nil, fmt.Errorf("logging.format must be \"text\" or \"json\", got %q", cfg.Format)
+	}
+
+	mutex.Lock()
+	defaultLevel = level
+	componentLvls = levels
+	base = handler
+	mutex.Unlock()
+
+	slog.SetDefault(For(ComponentMain))
+	return closer, nil
+}
+
+// For returns the logger for a component. Loggers obtained before Setup
+// pick up the configuration once Setup runs.
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component}).With("component", component)
+}
+
+// ParseLevel converts debug, info, warn or error to a slog level
+func ParseLevel(text string) (slog.Level, error) {
+	switch strings.ToLower(text) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown level %q (want debug, info, warn or error)", text)
+	}
+}
+
+// nopCloser is returned by Setup when logging to stderr
+type nopCloser struct{}
+
+// Close implements io.Closer
+func (nopCloser) Close() error { return nil }
+
+// correlationKey keys the correlation ID carried in a context
+type correlationKey struct{}
+
+// NewCorrelationID returns a short random ID for following one source file
+// from detection to the written note
+func NewCorrelationID() string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// WithCorrelationID stores a correlation ID in ctx; log calls made with the
+// context include it as correlation_id
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID stored in ctx, if any
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// componentHandler applies the component's level and adds the correlation
+// ID from the context before delegating to the configured base handler
+type componentHandler struct {
+	component string
+	// scopes replays With and WithGroup calls on the base handler in order
+	scopes []func(slog.Handler) slog.Handler
+}
+
+// Enabled implements slog.Handler
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	threshold, ok := componentLvls[h.component]
+	if !ok {
+		threshold = defaultLevel
+	}
+	return level >= threshold
+}
+
+// Handle implements slog.Handler
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		record.AddAttrs(slog.String("correlation_id", id))
+	}
+
+	mutex.RLock()
+	handler := base
+	mutex.RUnlock()
+
+	for _, scope := range h.scopes {
+		handler = scope(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.withScope(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+// WithGroup implements slog.Handler
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.withScope(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+// withScope returns a copy of the handler with one more scope applied
+func (h *componentHandler) withScope(scope func(slog.Handler) slog.Handler) slog.Handler {
+	return &componentHandler{
+		component: h.component,
+		scopes:    append(append([]func(slog.Handler) slog.Handler{}, h.scopes...), scope),
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+// Pipeline metrics. File metrics are labelled with the source type reported
+// by extraction (text, json, markdown, ...).
+var (
+	FilesDetected = NewCounterVec("bridge_files_detected_total",
+		"Files reported by the watcher.", "type")
+	FilesProcessed = NewCounterVec("bridge_files_processed_total",
+		"Files turned into notes.", "type")
+	FilesSkipped = NewCounterVec("bridge_files_skipped_total",
+		"Files skipped without writing a note, by reason.", "type", "reason")
+	FilesFailed = NewCounterVec("bridge_files_failed_total",
+		"Files that failed to process, by pipeline stage.", "type", "stage")
+
+	QueueDepth = NewGaugeVec("bridge_queue_depth",
+		"Files waiting in the processing queue.")
+	QueueOldestAge = NewGaugeVec("bridge_queue_oldest_seconds",
+		"How long the oldest queued file has been waiting.")
+	FallingBehind = NewGaugeVec("bridge_falling_behind",
+		"1 while the queue is over its alert depth or age, otherwise 0.")
+	FallingBehindTotal = NewCounterVec("bridge_falling_behind_total",
+		"Times the queue went over its alert depth or age.")
+	FilesReconciled = NewCounterVec("bridge_files_reconciled_total",
+		"Files the watcher missed that the reconciliation scan queued.", "type")
+
+	ExtractionDuration = NewHistogramVec("bridge_extraction_duration_seconds",
+		"Time spent extracting capture items from a file.", DefaultBuckets, "type")
+
+	TranscriptionRequests = NewCounterVec("bridge_transcription_requests_total",
+		"Audio transcriptions by outcome.", "provider", "outcome")
+	TranscriptionDuration = NewHistogramVec("bridge_transcription_duration_seconds",
+		"Time spent transcribing one audio file.", []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}, "provider")
+	AudioTranscribed = NewCounterVec("bridge_audio_transcribed_seconds_total",
+		"Length of the audio transcribed.", "provider")
+
+	VideoFrames = NewCounterVec("bridge_video_frames_total",
+		"Frames sampled from screen recordings; result is kept, duplicate or no_new_text.", "result")
+
+	VisionRequests = NewCounterVec("bridge_vision_requests_total",
+		"Screenshot descriptions by outcome.", "provider", "model", "outcome")
+	VisionDuration = NewHistogramVec("bridge_vision_request_duration_seconds",
+		"Time spent describing one screenshot.", DefaultBuckets, "provider", "model")
+
+	LLMRequests = NewCounterVec("bridge_llm_requests_total",
+		"LLM calls by outcome.", "provider", "model", "outcome")
+	LLMDuration = NewHistogramVec("bridge_llm_request_duration_seconds",
+		"LLM call latency.", DefaultBuckets, "provider", "model", "template")
+	LLMTokens = NewCounterVec("bridge_llm_tokens_total",
+		"Tokens consumed by LLM calls; kind is prompt or completion.", "provider", "model", "kind")
+	LLMCost = NewCounterVec("bridge_llm_cost_usd_total",
+		"Estimated LLM spend in US dollars.", "provider", "model")
+
+	AttachmentsStored = NewCounterVec("bridge_attachments_total",
+		"Source media stored in the vault; result is stored, duplicate or error.", "kind", "result")
+	EntityNotes = NewCounterVec("bridge_entity_notes_total",
+		"Entity note updates; result is created, updated or error.", "kind", "result")
+	TasksExported = NewCounterVec("bridge_tasks_exported_total",
+		"Action items exported to task managers; result is created, duplicate or error.", "sink", "result")
+	TasksCompleted = NewCounterVec("bridge_tasks_completed_total",
+		"Exported tasks completed in a task manager and ticked in their note.", "sink")
+	TasksMerged = NewCounterVec("bridge_tasks_merged_total",
+		"Action items merged into a recent one; match is exact, words or embedding.", "match")
+
+	DeerflowDeliveries = NewCounterVec("bridge_deerflow_deliveries_total",
+		"Deerflow webhook delivery attempts; outcome is delivered, retrying or failed.", "outcome")
+	DeerflowRequests = NewCounterVec("bridge_deerflow_requests_total",
+		"Inbound Deerflow requests; result is accepted, rejected or error.", "action", "result")
+
+	NoteWriteErrors = NewCounterVec("bridge_note_write_errors_total",
+		"Failed attempts to write a note to the vault.")
+	WatcherErrors = NewCounterVec("bridge_watcher_errors_total",
+		"Errors reported by the file system watcher.")
+	EventsDeferred = NewCounterVec("bridge_watcher_events_deferred_total",
+		"File events held back and re-checked because the event buffer was full.")
+)
+
+// Unlabelled series are exported from startup rather than on first use
+func init() {
+	QueueDepth.With()
+	QueueOldestAge.With()
+	FallingBehind.With()
+	FallingBehindTotal.With()
+	NoteWriteErrors.With()
+	WatcherErrors.With()
+	EventsDeferred.With()
+}
+
+// Reasons a file is skipped
+const (
+	SkipTooLarge      = "too_large"
+	SkipTooNew        = "too_new"
+	SkipNoTranscriber = "no_transcriber"
+	SkipMissingTool   = "missing_tool"
+	SkipPrivacy       = "privacy"
+	SkipEmpty         = "empty"
+	SkipUnchanged     = "unchanged"
+)
+
+// Stages at which processing a file fails
+const (
+	StageExtract    = "extract"
+	StageTranscribe = "transcribe"
+	StageLLM        = "llm"
+	StageWrite      = "write"
+)
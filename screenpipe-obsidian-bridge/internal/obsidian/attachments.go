@@ -0,0 +1,299 @@
+package obsidian
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/imaging"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+// appSettings is the part of Obsidian's .obsidian/app.json that is read
+type appSettings struct {
+	// AttachmentFolderPath is "Default location for new attachments"; unset
+	// means the vault root
+	AttachmentFolderPath string `json:"attachmentFolderPath"`
+}
+
+// attachmentFolder returns the folder attachments of a note in noteDir are
+// stored in: obsidian.attachments.folder when set, otherwise the location
+// configured in Obsidian
+func (w *Writer) attachmentFolder(noteDir string) string {
+	location := w.config.Attachments.Folder
+	if location == "" {
+		location = "/"
+		data, err := os.ReadFile(filepath.Join(w.config.VaultPath, ".obsidian", "app.json"))
+		if err == nil {
+			var settings appSettings
+			if err := json.Unmarshal(data, &settings); err != nil {
+				logger.Warn("cannot read Obsidian settings", "path", filepath.Join(w.config.VaultPath, ".obsidian", "app.json"), "error", err)
+			} else if settings.AttachmentFolderPath != "" {
+				location = settings.AttachmentFolderPath
+			}
+		}
+	}
+
+	switch {
+	case location == "/":
+		return w.config.VaultPath
+	case location == "." || location == "./":
+		return noteDir
+	case strings.HasPrefix(location, "./"):
+		return filepath.Join(noteDir, filepath.FromSlash(location[2:]))
+	default:
+		return filepath.Join(w.config.VaultPath, filepath.FromSlash(strings.Trim(location, "/")))
+	}
+}
+
+// storeAttachments stores a note's attachments and names them for the
+// embeds. Media that cannot be stored is logged and left out of the note
+// rather than failing it.
+func (w *Writer) storeAttachments(ctx context.Context, noteDir string, result *llm.ProcessingResult) {
+	if len(result.Metadata.Attachments) == 0 {
+		return
+	}
+	dir := w.attachmentFolder(noteDir)
+
+	stored := make([]llm.Attachment, 0, len(result.Metadata.Attachments))
+	for _, attachment := range result.Metadata.Attachments {
+		outcome, err := w.storeAttachment(dir, &attachment)
+		if err != nil {
+			metrics.AttachmentsStored.With(attachment.Kind, "error").Inc()
+			logger.WarnContext(ctx, "cannot store attachment", "source", attachment.Source, "kind", attachment.Kind, "error", err)
+			continue
+		}
+		metrics.AttachmentsStored.With(attachment.Kind, outcome).Inc()
+		logger.DebugContext(ctx, "stored attachment", "source", attachment.Source, "name", attachment.Name, "result", outcome)
+		stored = append(stored, attachment)
+	}
+	result.Metadata.Attachments = stored
+}
+
+// storeAttachment writes one attachment into dir under a name that ends in
+// its content hash, so identical media is stored once however often and
+// under whatever name it is captured. It reports "stored" or "duplicate".
+func (w *Writer) storeAttachment(dir string, attachment *llm.Attachment) (string, error) {
+	hash, err := contentHash(attachment)
+	if err != nil {
+		return "", err
+	}
+
+	// Downscaling only applies to images larger than the limit; everything
+	// else keeps its format
+	var downscaled image.Image
+	ext := strings.ToLower(filepath.Ext(attachment.Source))
+	if attachment.Kind == "keyframe" {
+		ext = ".png"
+	}
+	if w.config.Attachments.Mode == "downscale" && attachment.Kind != "audio" {
+		if downscaled, err = w.oversized(attachment); err != nil {
+			return "", err
+		}
+		if downscaled != nil {
+			ext = ".jpg"
+		}
+	}
+
+	// The same media captured under another name is already stored
+	suffix := hash[:12] + ext
+	if existing := w.attachments.find(dir, suffix); existing != "" {
+		attachment.Name = existing
+		return "duplicate", nil
+	}
+	attachment.Name = attachmentName(attachment, hash, ext)
+	target := filepath.Join(dir, attachment.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create attachment folder %s: %w", dir, err)
+	}
+
+	if err := writeAttachment(target, attachment, downscaled, w.config.Attachments); err != nil {
+		return "", err
+	}
+	w.attachments.add(dir, suffix, attachment.Name)
+	return "stored", nil
+}
+
+// writeAttachment writes an attachment's media to target: downscaled when
+// an image was decoded for it, hard linked in link mode where possible and
+// copied otherwise
+func writeAttachment(target string, attachment *llm.Attachment, downscaled image.Image, cfg config.AttachmentsConfig) error {
+	switch {
+	case downscaled != nil:
+		data, err := imaging.Downscale(downscaled, cfg.MaxDimension)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(target, bytes.NewReader(data))
+	case attachment.Data != nil:
+		return writeFileAtomic(target, bytes.NewReader(attachment.Data))
+	case cfg.Mode == "link":
+		if err := os.Link(attachment.Source, target); err == nil {
+			return nil
+		}
+		// Hard links cannot cross filesystems; fall back to a copy
+	}
+
+	in, err := os.Open(attachment.Source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileAtomic(target, in)
+}
+
+// oversized decodes an image attachment when it is larger than the
+// downscale limit, and returns nil when it can be stored as it is
+func (w *Writer) oversized(attachment *llm.Attachment) (image.Image, error) {
+	data := attachment.Data
+	if data == nil {
+		var err error
+		if data, err = os.ReadFile(attachment.Source); err != nil {
+			return nil, err
+		}
+	}
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if max(header.Width, header.Height) <= w.config.Attachments.MaxDimension {
+		return nil, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// contentHash returns the SHA-256 of an attachment's media
+func contentHash(attachment *llm.Attachment) (string, error) {
+	h := sha256.New()
+	if attachment.Data != nil {
+		h.Write(attachment.Data)
+	} else {
+		f, err := os.Open(attachment.Source)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// attachmentIndex remembers the attachments stored in each attachment
+// folder by the end of their names, "<hash prefix><ext>", so a duplicate
+// is found without listing the folder for every attachment
+type attachmentIndex struct {
+	mu sync.Mutex
+	// folders maps a folder, listed the first time it is used, to its
+	// attachment names by hash suffix
+	folders map[string]map[string]string
+}
+
+// newAttachmentIndex creates an empty attachment index
+func newAttachmentIndex() *attachmentIndex {
+	return &attachmentIndex{folders: make(map[string]map[string]string)}
+}
+
+// find returns the name of the file in dir whose name ends in "-" and
+// suffix, or "" if there is none. Files removed since are forgotten.
+func (x *attachmentIndex) find(dir, suffix string) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	names := x.folders[dir]
+	if names == nil {
+		names = listAttachments(dir)
+		x.folders[dir] = names
+	}
+	name := names[suffix]
+	if name == "" {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+		delete(names, suffix)
+		return ""
+	}
+	return name
+}
+
+// add records an attachment stored in dir
+func (x *attachmentIndex) add(dir, suffix, name string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if names := x.folders[dir]; names != nil {
+		names[suffix] = name
+	}
+}
+
+// listAttachments maps the files in dir by the part of their names after
+// the last "-", where attachment names keep their hash prefix
+func listAttachments(dir string) map[string]string {
+	names := map[string]string{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if i := strings.LastIndexByte(name, '-'); i >= 0 && !entry.IsDir() {
+			names[name[i+1:]] = name
+		}
+	}
+	return names
+}
+
+// attachmentName names an attachment after its source, keyframes also after
+// their offset, followed by a prefix of the content hash, e.g.
+// "standup-03m12s-9f86d081884c.png"
+func attachmentName(attachment *llm.Attachment, hash, ext string) string {
+	stem := strings.TrimSuffix(filepath.Base(attachment.Source), filepath.Ext(attachment.Source))
+	if attachment.Kind == "keyframe" {
+		offset := attachment.Offset.Truncate(time.Second)
+		stem += fmt.Sprintf("-%02dm%02ds", int(offset.Minutes()), int(offset.Seconds())%60)
+	}
+	return stem + "-" + hash[:12] + ext
+}
+
+// writeFileAtomic writes through a temporary file, so a failed copy never
+// leaves a truncated attachment behind
+func writeFileAtomic(target string, content io.Reader) error {
+	tmp := target + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to store %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+func TestAttachmentFolder(t *testing.T) {
+	vault := t.TempDir()
+	noteDir := filepath.Join(vault, "ScreenPipe", "2025")
+
+	tests := []struct {
+		name     string
+		folder   string
+		settings string
+		want     string
+	}{
+		{"no settings", "", "", vault},
+		{"obsidian root", "", `{"attachmentFolderPath": "/"}`, vault},
+		{"obsidian vault folder", "", `{"attachmentFolderPath": "Assets/Media"}`, filepath.Join(vault, "Assets", "Media")},
+		{"obsidian next to note", "", `{"attachmentFolderPath": "./"}`, noteDir},
+		{"obsidian below note", "", `{"attachmentFolderPath": "./media"}`, filepath.Join(noteDir, "media")},
+		{"obsidian unset", "", `{"alwaysUpdateLinks": true}`, vault},
+		{"broken settings", "", `{"attachmentFolderPath": `, vault},
+		{"config overrides obsidian", "_media/", `{"attachmentFolderPath": "./"}`, filepath.Join(vault, "_media")},
+		{"config next to note", ".", "", noteDir},
+		{"config below note", "./_media", "", filepath.Join(noteDir, "_media")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := filepath.Join(vault, ".obsidian", "app.json")
+			os.Remove(settings)
+			if test.settings != "" {
+				if err := os.MkdirAll(filepath.Dir(settings), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(settings, []byte(test.settings), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := New(&config.ObsidianConfig{
+				VaultPath:   vault,
+				Attachments: config.AttachmentsConfig{Folder: test.folder},
+			}, &config.EncryptionConfig{}, nil)
+			if got := w.attachmentFolder(noteDir); got != test.want {
+				t.Errorf("attachmentFolder = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStoreAttachmentDeduplicates(t *testing.T) {
+	vault := t.TempDir()
+	captures := t.TempDir()
+	dir := filepath.Join(vault, "_media")
+	w := New(&config.ObsidianConfig{
+		VaultPath:   vault,
+		Attachments: config.AttachmentsConfig{Mode: "copy"},
+	}, &config.EncryptionConfig{}, nil)
+
+	capture := func(name, content string) *llm.Attachment {
+		path := filepath.Join(captures, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return &llm.Attachment{Kind: "audio", Source: path}
+	}
+	store := func(attachment *llm.Attachment, want string) string {
+		t.Helper()
+		outcome, err := w.storeAttachment(dir, attachment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outcome != want {
+			t.Errorf("%s: outcome = %q, want %q", attachment.Source, outcome, want)
+		}
+		return attachment.Name
+	}
+
+	first := store(capture("meeting.wav", "audio"), "stored")
+	if !strings.HasPrefix(first, "meeting-") || !strings.HasSuffix(first, ".wav") {
+		t.Errorf("name = %q, want meeting-<hash>.wav", first)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, first)); err != nil || string(data) != "audio" {
+		t.Errorf("stored content = %q, %v", data, err)
+	}
+
+	// The same media under another name is stored once
+	if name := store(capture("copy.wav", "audio"), "duplicate"); name != first {
+		t.Errorf("duplicate named %q, want %q", name, first)
+	}
+	store(capture("other.wav", "other audio"), "stored")
+
+	// Media stored by an earlier writer is found too
+	w = New(w.config, &config.EncryptionConfig{}, nil)
+	if name := store(capture("again.wav", "audio"), "duplicate"); name != first {
+		t.Errorf("duplicate named %q, want %q", name, first)
+	}
+
+	// An attachment removed from the vault is stored again
+	if err := os.Remove(filepath.Join(dir, first)); err != nil {
+		t.Fatal(err)
+	}
+	if name := store(capture("restored.wav", "audio"), "stored"); !strings.HasPrefix(name, "restored-") {
+		t.Errorf("name = %q, want restored-<hash>.wav", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("attachment folder holds %q, want 2 files", names)
+	}
+}
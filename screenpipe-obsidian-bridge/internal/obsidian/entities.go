@@ -0,0 +1,322 @@
+package obsidian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+// entityNotesMu serializes updates to entity notes, which notes written
+// concurrently or by a reloaded writer may share
+var entityNotesMu sync.Mutex
+
+// entity is a person, project or app notes can link to
+type entity struct {
+	name string
+	kind string
+	// terms are the spellings matched in text: the name and its aliases
+	terms []string
+	// named is set when the LLM reported the entity for the current note
+	named bool
+}
+
+var (
+	// unlinkable matches the parts of a text that must not get links: fenced
+	// code blocks, inline code, wikilinks, markdown links and URLs
+	unlinkable = regexp.MustCompile("(?s)```.*?(?:```|$)|~~~.*?(?:~~~|$)|`[^`\n]+`|\\[\\[[^\\]]*\\]\\]|\\[[^\\]]*\\]\\([^)]*\\)|https?://\\S+")
+	// wikilink matches a wikilink, capturing the text it shows
+	wikilink = regexp.MustCompile(`\[\[(?:[^\]|]*\|)?([^\]]*)\]\]`)
+	// unsafeNameChars are characters Obsidian does not allow in note names
+	unsafeNameChars = regexp.MustCompile(`[\\/:*?"<>|#^\[\]]`)
+)
+
+// entities returns the entities a note can link to: obsidian.entities.known
+// and those the LLM named. A named entity that matches a known name or alias
+// is the known entity. Entities whose names have nothing left to name a note
+// with, such as "#", are left out.
+func (w *Writer) entities(result *llm.ProcessingResult) []*entity {
+	var all []*entity
+	byTerm := make(map[string]*entity)
+	for _, known := range w.config.Entities.Known {
+		if noteName(known.Name) == "" {
+			continue
+		}
+		e := &entity{name: known.Name, kind: known.Kind}
+		for _, term := range append([]string{known.Name}, known.Aliases...) {
+			term = strings.TrimSpace(term)
+			if term == "" || byTerm[strings.ToLower(term)] != nil {
+				continue
+			}
+			e.terms = append(e.terms, term)
+			byTerm[strings.ToLower(term)] = e
+		}
+		all = append(all, e)
+	}
+
+	for _, named := range result.Entities {
+		name := strings.TrimSpace(named.Name)
+		if name == "" {
+			continue
+		}
+		if e := byTerm[strings.ToLower(name)]; e != nil {
+			e.named = true
+			continue
+		}
+		if noteName(name) == "" {
+			continue
+		}
+		e := &entity{name: name, kind: named.Kind, terms: []string{name}, named: true}
+		byTerm[strings.ToLower(name)] = e
+		all = append(all, e)
+	}
+	return all
+}
+
+// entityPath returns the vault-relative path of an entity's note, without
+// the .md extension
+func (w *Writer) entityPath(e *entity) string {
+	folder := w.config.Entities.PeopleFolder
+	switch e.kind {
+	case llm.EntityProject:
+		folder = w.config.Entities.ProjectsFolder
+	case llm.EntityApp:
+		folder = w.config.Entities.AppsFolder
+	}
+	return path.Join(filepath.ToSlash(folder), noteName(e.name))
+}
+
+// noteName returns the name of an entity's note: its name without the
+// characters Obsidian does not allow
+func noteName(name string) string {
+	return strings.TrimSpace(unsafeNameChars.ReplaceAllString(name, " "))
+}
+
+// linkEntities turns the first mention of each entity in the summary and in
+// each task into a wikilink to the entity's note, and records in
+// Metadata.Related the notes linked plus those of entities the LLM named.
+func (w *Writer) linkEntities(result *llm.ProcessingResult) {
+	entities := w.entities(result)
+	if len(entities) == 0 {
+		return
+	}
+
+	mentioned := make(map[*entity]bool)
+	result.ActivitySummary = w.linkMentions(result.ActivitySummary, entities, mentioned)
+	tasks := make([]string, len(result.ActionableTasks))
+	for i, task := range result.ActionableTasks {
+		tasks[i] = w.linkMentions(task, entities, mentioned)
+	}
+	result.ActionableTasks = tasks
+
+	result.Metadata.Related = nil
+	seen := make(map[string]bool)
+	for _, e := range entities {
+		if entityPath := w.entityPath(e); (e.named || mentioned[e]) && !seen[entityPath] {
+			result.Metadata.Related = append(result.Metadata.Related, entityPath)
+			seen[entityPath] = true
+		}
+	}
+}
+
+// linkMentions links the first mention of each entity in text. Longer terms
+// are matched first, so "Project Atlas" wins over "Atlas".
+func (w *Writer) linkMentions(text string, entities []*entity, mentioned map[*entity]bool) string {
+	type candidate struct {
+		term   string
+		entity *entity
+	}
+	var candidates []candidate
+	for _, e := range entities {
+		for _, term := range e.terms {
+			candidates = append(candidates, candidate{term, e})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].term) > len(candidates[j].term)
+	})
+
+	linked := make(map[*entity]bool)
+	for _, c := range candidates {
+		if linked[c.entity] {
+			continue
+		}
+		start, end := findMention(text, c.term)
+		if start < 0 {
+			continue
+		}
+		link := fmt.Sprintf("[[%s|%s]]", w.entityPath(c.entity), text[start:end])
+		text = text[:start] + link + text[end:]
+		linked[c.entity] = true
+		mentioned[c.entity] = true
+	}
+	return text
+}
+
+// findMention returns the position of the first case-insensitive, whole-word
+// occurrence of term in text outside code and existing links, or -1, -1
+func findMention(text, term string) (int, int) {
+	skip := unlinkable.FindAllStringIndex(text, -1)
+	pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(term))
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		if insideAny(match, skip) || !wordBoundary(text, match[0], match[1]) {
+			continue
+		}
+		return match[0], match[1]
+	}
+	return -1, -1
+}
+
+// insideAny reports whether span overlaps any of spans
+func insideAny(span []int, spans [][]int) bool {
+	for _, s := range spans {
+		if span[0] < s[1] && s[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// wordBoundary reports whether text[start:end] is not part of a longer word
+func wordBoundary(text string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// updateEntityNotes adds a backlink to the written note at notePath to the
+// note of each related entity, creating entity notes that do not exist yet.
+// Failures are logged; they never fail the note itself.
+func (w *Writer) updateEntityNotes(ctx context.Context, notePath string, result *llm.ProcessingResult) {
+	if len(result.Metadata.Related) == 0 {
+		return
+	}
+	rel, err := filepath.Rel(w.config.VaultPath, notePath)
+	if err != nil {
+		logger.WarnContext(ctx, "cannot link entity notes", "path", notePath, "error", err)
+		return
+	}
+	noteLink := strings.TrimSuffix(filepath.ToSlash(rel), ".md")
+
+	processedAt, err := time.Parse(time.RFC3339, result.Metadata.ProcessedAt)
+	if err != nil {
+		processedAt = time.Now()
+	}
+	line := fmt.Sprintf("- %s [[%s|%s]]\n", processedAt.Format("2006-01-02 15:04"), noteLink, backlinkText(result.ActivitySummary))
+
+	kinds := make(map[string]string)
+	for _, e := range w.entities(result) {
+		kinds[w.entityPath(e)] = e.kind
+	}
+	aliases := make(map[string][]string)
+	for _, known := range w.config.Entities.Known {
+		aliases[w.entityPath(&entity{name: known.Name, kind: known.Kind})] = known.Aliases
+	}
+
+	entityNotesMu.Lock()
+	defer entityNotesMu.Unlock()
+	for _, related := range result.Metadata.Related {
+		kind := kinds[related]
+		outcome, err := w.appendBacklink(related, kind, aliases[related], noteLink, line)
+		if err != nil {
+			metrics.EntityNotes.With(kind, "error").Inc()
+			logger.WarnContext(ctx, "cannot update entity note", "entity", related, "error", err)
+			continue
+		}
+		if outcome != "" {
+			metrics.EntityNotes.With(kind, outcome).Inc()
+			logger.DebugContext(ctx, "linked entity note", "entity", related, "result", outcome)
+		}
+	}
+}
+
+// appendBacklink adds line to the Activity list of an entity note unless it
+// already links to noteLink. It reports "created", "updated", or "" when
+// the note already had the backlink.
+func (w *Writer) appendBacklink(entityPath, kind string, aliases []string, noteLink, line string) (string, error) {
+	fullPath := filepath.Join(w.config.VaultPath, filepath.FromSlash(entityPath)+".md")
+	existing, err := os.ReadFile(fullPath)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return "", fmt.Errorf("failed to create folder for %s: %w", fullPath, err)
+		}
+		content := newEntityNote(path.Base(entityPath), kind, aliases) + line
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			return "", err
+		}
+		return "created", nil
+	case err != nil:
+		return "", err
+	}
+
+	if strings.Contains(string(existing), "[["+noteLink+"|") || strings.Contains(string(existing), "[["+noteLink+"]]") {
+		return "", nil
+	}
+	f, err := os.OpenFile(fullPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		line = "\n" + line
+	}
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return "", err
+	}
+	return "updated", f.Close()
+}
+
+// newEntityNote returns the start of a new entity note, ending in the
+// Activity list backlinks are appended to
+func newEntityNote(name, kind string, aliases []string) string {
+	var content strings.Builder
+	content.WriteString("---\n")
+	content.WriteString(fmt.Sprintf("type: %s\n", kind))
+	if len(aliases) > 0 {
+		content.WriteString("aliases:\n")
+		for _, alias := range aliases {
+			content.WriteString(fmt.Sprintf("  - \"%s\"\n", strings.ReplaceAll(alias, `"`, `\"`)))
+		}
+	}
+	content.WriteString("tags:\n")
+	content.WriteString(fmt.Sprintf("  - %s\n", kind))
+	content.WriteString("---\n\n")
+	content.WriteString(fmt.Sprintf("# %s\n\n", name))
+	content.WriteString("## Activity\n\n")
+	return content.String()
+}
+
+// backlinkText shortens a summary to the display text of a backlink,
+// without link syntax
+func backlinkText(summary string) string {
+	text := wikilink.ReplaceAllString(summary, "$1")
+	text = strings.NewReplacer("[", "", "]", "", "|", " ").Replace(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > 80 {
+		text = string([]rune(text)[:80]) + "…"
+	}
+	if text == "" {
+		text = "ScreenPipe activity"
+	}
+	return text
+}
@@ -0,0 +1,53 @@
+package obsidian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+func TestEntitiesWithoutNoteName(t *testing.T) {
+	vault := t.TempDir()
+	w := New(&config.ObsidianConfig{
+		VaultPath: vault,
+		Entities: config.EntitiesConfig{
+			Enabled:        true,
+			PeopleFolder:   "People",
+			ProjectsFolder: "Projects",
+			AppsFolder:     "Apps",
+			Known:          []config.KnownEntity{{Name: "[]", Kind: llm.EntityProject}},
+		},
+	}, &config.EncryptionConfig{}, nil)
+
+	result := &llm.ProcessingResult{
+		ActivitySummary: "Paired with Alice in # and []",
+		Entities: []llm.Entity{
+			{Name: "#", Kind: llm.EntityPerson},
+			{Name: " ? ", Kind: llm.EntityPerson},
+			{Name: "Alice", Kind: llm.EntityPerson},
+		},
+	}
+	w.linkEntities(result)
+
+	if want := "Paired with [[People/Alice|Alice]] in # and []"; result.ActivitySummary != want {
+		t.Errorf("summary = %q, want %q", result.ActivitySummary, want)
+	}
+	if want := []string{"People/Alice"}; !reflect.DeepEqual(result.Metadata.Related, want) {
+		t.Errorf("related = %q, want %q", result.Metadata.Related, want)
+	}
+
+	w.updateEntityNotes(context.Background(), filepath.Join(vault, "ScreenPipe", "note.md"), result)
+	if _, err := os.Stat(filepath.Join(vault, "People", "Alice.md")); err != nil {
+		t.Errorf("entity note not written: %v", err)
+	}
+	for _, stray := range []string{"People.md", "Projects.md"} {
+		if _, err := os.Stat(filepath.Join(vault, stray)); !os.IsNotExist(err) {
+			t.Errorf("%s written at the vault root", stray)
+		}
+	}
+}
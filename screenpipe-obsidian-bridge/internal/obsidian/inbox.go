@@ -0,0 +1,144 @@
+package obsidian
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// inboxMu serializes inbox updates, which follow note writes and task sync
+var inboxMu sync.Mutex
+
+// inboxNote is a generated note with open action items
+type inboxNote struct {
+	// rel is the note's vault-relative path without extension
+	rel     string
+	created string
+	// ids are the block IDs of its open action items
+	ids []string
+}
+
+// updateInbox rewrites the tasks inbox, if obsidian.tasks.inbox is set, to
+// embed the open action items of all generated notes, newest note first.
+// Failures are logged; the next update retries.
+func (w *Writer) updateInbox() {
+	if w.config.Tasks.Inbox == "" {
+		return
+	}
+	inboxMu.Lock()
+	defer inboxMu.Unlock()
+
+	inboxPath := filepath.Join(w.config.VaultPath, filepath.FromSlash(w.config.Tasks.Inbox))
+	notesPath := filepath.Join(w.config.VaultPath, w.config.NotesSubdirectory)
+	// Encrypted notes are in a sub-folder and stay out of the inbox
+	entries, err := os.ReadDir(notesPath)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warn("failed to list notes for the tasks inbox", "path", notesPath, "error", err)
+		return
+	}
+
+	var notes []inboxNote
+	for _, entry := range entries {
+		notePath := filepath.Join(notesPath, entry.Name())
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") || notePath == inboxPath {
+			continue
+		}
+		note, err := readOpenTasks(notePath)
+		if err != nil {
+			logger.Warn("failed to read note for the tasks inbox", "path", notePath, "error", err)
+			continue
+		}
+		if len(note.ids) > 0 {
+			note.rel = w.RelativePath(notePath)
+			notes = append(notes, note)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].created != notes[j].created {
+			return notes[i].created > notes[j].created
+		}
+		return notes[i].rel < notes[j].rel
+	})
+
+	content := renderInbox(notes)
+	if existing, err := os.ReadFile(inboxPath); err == nil && bytes.Equal(existing, content) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(inboxPath), 0755); err != nil {
+		logger.Warn("failed to create tasks inbox folder", "path", inboxPath, "error", err)
+		return
+	}
+	if err := writeFileAtomic(inboxPath, bytes.NewReader(content)); err != nil {
+		logger.Warn("failed to write tasks inbox", "path", inboxPath, "error", err)
+		return
+	}
+	logger.Debug("updated tasks inbox", "path", inboxPath, "notes", len(notes))
+}
+
+// readOpenTasks reads a note's creation time and the block IDs of its open
+// action items
+func readOpenTasks(notePath string) (inboxNote, error) {
+	file, err := os.Open(notePath)
+	if err != nil {
+		return inboxNote{}, err
+	}
+	defer file.Close()
+
+	var note inboxNote
+	frontmatter := false
+	scanner := bufio.NewScanner(file)
+	for n := 0; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case line == "---" && (n == 0 || frontmatter):
+			frontmatter = n == 0
+		case frontmatter && strings.HasPrefix(line, "created:"):
+			note.created = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "created:")), `"`)
+		case strings.HasPrefix(strings.TrimLeft(line, " \t"), "- [ ] "):
+			if m := blockID.FindStringSubmatch(line); m != nil {
+				note.ids = append(note.ids, m[1])
+			}
+		}
+	}
+	return note, scanner.Err()
+}
+
+// renderInbox renders the tasks inbox note
+func renderInbox(notes []inboxNote) []byte {
+	var content bytes.Buffer
+	content.WriteString("---\n")
+	content.WriteString("tags:\n")
+	content.WriteString("  - screenpipe\n")
+	content.WriteString("  - tasks-inbox\n")
+	content.WriteString("---\n\n")
+	content.WriteString("# Tasks Inbox\n\n")
+	content.WriteString("Open action items from the ScreenPipe notes, newest first. Items leave the inbox once ticked in their note.\n\n")
+	if len(notes) == 0 {
+		content.WriteString("*No open action items.*\n")
+	}
+
+	for _, note := range notes {
+		title := note.created
+		if created, err := time.Parse(time.RFC3339, note.created); err == nil {
+			title = created.Local().Format("2006-01-02 15:04")
+		}
+		if title == "" {
+			title = filepath.Base(note.rel)
+		}
+		content.WriteString(fmt.Sprintf("## [[%s|%s]]\n\n", note.rel, title))
+		for _, id := range note.ids {
+			content.WriteString(fmt.Sprintf("![[%s#^%s]]\n", note.rel, id))
+		}
+		content.WriteString("\n")
+	}
+
+	content.WriteString("---\n")
+	content.WriteString("*This note was automatically generated by ScreenPipe Obsidian Bridge*\n")
+	return content.Bytes()
+}
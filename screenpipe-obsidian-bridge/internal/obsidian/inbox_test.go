@@ -0,0 +1,83 @@
+package obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadOpenTasks(t *testing.T) {
+	note := `---
+created: "2025-01-16T09:30:00Z"
+tags:
+  - screenpipe
+---
+
+# Standup
+
+created: not the frontmatter
+
+## Action Items
+
+- [ ] Reply to Anna #task ^task-1a2b3c4d
+- [x] Book the room ^task-0000aaaa
+- [ ] Without a block ID
+	- [ ] Draft the agenda ^task-5e6f7a8b
+- [ ] Send the [[Q3 report]] ⏫ 📅 2025-01-20 ^task-0f0f0f0f
+
+---
+*generated*
+`
+	path := filepath.Join(t.TempDir(), "standup.md")
+	if err := os.WriteFile(path, []byte(note), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readOpenTasks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2025-01-16T09:30:00Z"; got.created != want {
+		t.Errorf("created = %q, want %q", got.created, want)
+	}
+	if want := []string{"task-1a2b3c4d", "task-5e6f7a8b", "task-0f0f0f0f"}; !reflect.DeepEqual(got.ids, want) {
+		t.Errorf("ids = %q, want %q", got.ids, want)
+	}
+
+	if _, err := readOpenTasks(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("expected an error for a missing note")
+	}
+}
+
+func TestRenderInbox(t *testing.T) {
+	empty := string(renderInbox(nil))
+	if !strings.Contains(empty, "*No open action items.*") {
+		t.Errorf("empty inbox does not say so:\n%s", empty)
+	}
+
+	created := time.Date(2025, 1, 16, 9, 30, 0, 0, time.UTC)
+	got := string(renderInbox([]inboxNote{
+		{rel: "ScreenPipe/standup", created: created.Format(time.RFC3339), ids: []string{"task-1a2b3c4d", "task-5e6f7a8b"}},
+		{rel: "ScreenPipe/old", created: "last week", ids: []string{"task-0f0f0f0f"}},
+		{rel: "ScreenPipe/untitled", ids: []string{"task-00000001"}},
+	}))
+	want := "## [[ScreenPipe/standup|" + created.Local().Format("2006-01-02 15:04") + "]]\n\n" +
+		"![[ScreenPipe/standup#^task-1a2b3c4d]]\n" +
+		"![[ScreenPipe/standup#^task-5e6f7a8b]]\n\n" +
+		"## [[ScreenPipe/old|last week]]\n\n" +
+		"![[ScreenPipe/old#^task-0f0f0f0f]]\n\n" +
+		"## [[ScreenPipe/untitled|untitled]]\n\n" +
+		"![[ScreenPipe/untitled#^task-00000001]]\n\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("inbox =\n%s\nwant it to contain\n%s", got, want)
+	}
+	if strings.Contains(got, "No open action items") {
+		t.Error("inbox with action items says it has none")
+	}
+	if !strings.HasPrefix(got, "---\ntags:\n") {
+		t.Errorf("inbox does not start with frontmatter:\n%s", got)
+	}
+}
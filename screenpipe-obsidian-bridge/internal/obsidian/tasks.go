@@ -0,0 +1,238 @@
+package obsidian
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+// priorityEmoji maps priorities to the Tasks plugin's markers
+var priorityEmoji = map[string]string{
+	"highest": "🔺",
+	"high":    "⏫",
+	"medium":  "🔼",
+	"low":     "🔽",
+	"lowest":  "⏬",
+}
+
+// taskSignifiers are the markers of the Tasks plugin's fields. A task's text
+// ends at the first of them.
+var taskSignifiers = []string{"🔺", "⏫", "🔼", "🔽", "⏬", "🔁", "🛫", "⏳", "📅", "➕", "✅", "❌"}
+
+// blockID matches a block ID at the end of a line
+var blockID = regexp.MustCompile(`\s\^([A-Za-z0-9-]+)\s*$`)
+
+// lastSeenField matches the inline field MarkTaskSeen adds to a task
+var lastSeenField = regexp.MustCompile(`\s*\[last seen:: [^\]]*\]`)
+
+// taskList renders a note's action items as checkboxes. In the tasks
+// format they carry the Tasks plugin's fields; in that format or with an
+// inbox they end with block IDs, so they can be embedded elsewhere.
+func (w *Writer) taskList(result *llm.ProcessingResult) string {
+	tasksFormat := w.config.Tasks.Format == "tasks"
+	withIDs := tasksFormat || w.config.Tasks.Inbox != ""
+	created := ""
+	if processedAt, err := time.Parse(time.RFC3339, result.Metadata.ProcessedAt); err == nil {
+		created = processedAt.Local().Format("2006-01-02")
+	}
+
+	var out strings.Builder
+	ids := make(map[string]int)
+	for i, text := range result.ActionableTasks {
+		out.WriteString("- [ ] " + text)
+		if w.config.Tasks.Tag != "" {
+			out.WriteString(" " + w.config.Tasks.Tag)
+		}
+		if tasksFormat {
+			out.WriteString(taskFields(result.DetailOf(i), created))
+		}
+		if withIDs {
+			// Repeated items in one note still need distinct IDs
+			id := taskBlockID(text)
+			ids[id]++
+			if n := ids[id]; n > 1 {
+				id = fmt.Sprintf("%s-%d", id, n)
+			}
+			out.WriteString(" ^" + id)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// taskFields renders a task's details in the Tasks plugin's emoji format,
+// in the order the plugin writes them
+func taskFields(detail llm.TaskDetail, created string) string {
+	var fields strings.Builder
+	if emoji, ok := priorityEmoji[detail.Priority]; ok {
+		fields.WriteString(" " + emoji)
+	}
+	for _, field := range []struct{ emoji, value string }{
+		{"🔁", detail.Recurrence},
+		{"➕", created},
+		{"🛫", detail.Start},
+		{"⏳", detail.Scheduled},
+		{"📅", detail.Due},
+	} {
+		if field.value != "" {
+			fields.WriteString(" " + field.emoji + " " + field.value)
+		}
+	}
+	return fields.String()
+}
+
+// taskBlockID derives a task's block ID from its text, so the same task
+// keeps its ID when its note is regenerated
+func taskBlockID(text string) string {
+	sum := sha256.Sum256([]byte(plainTask(text)))
+	return "task-" + hex.EncodeToString(sum[:4])
+}
+
+// NoteURI returns an obsidian:// URI that opens the note at notePath in the
+// vault named vaultName, or in the vault folder's name when it is empty
+func (w *Writer) NoteURI(notePath, vaultName string) string {
+	if vaultName == "" {
+		vaultName = filepath.Base(w.config.VaultPath)
+	}
+	return "obsidian://open?vault=" + uriEscape(vaultName) + "&file=" + uriEscape(w.RelativePath(notePath))
+}
+
+// RelativePath returns a note's vault-relative path without extension, as
+// wikilinks name it
+func (w *Writer) RelativePath(notePath string) string {
+	rel, err := filepath.Rel(w.config.VaultPath, notePath)
+	if err != nil {
+		rel = filepath.Base(notePath)
+	}
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".md")
+}
+
+// uriEscape escapes a query value the way Obsidian expects, with %20 for
+// spaces
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// CompleteTask ticks the checkbox of the open task in a note whose text is
+// text, ignoring case, spacing and what was added when the note was
+// written: links, the tag, the Tasks plugin's fields and the block ID. In
+// the tasks format, the completion date is added too. It reports whether a
+// checkbox was ticked. Encrypted notes are left alone.
+func (w *Writer) CompleteTask(notePath, text string) (bool, error) {
+	if strings.HasSuffix(notePath, EncryptedExtension) {
+		return false, nil
+	}
+	data, err := os.ReadFile(notePath)
+	if err != nil {
+		return false, err
+	}
+
+	want := plainTask(text)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(string(line), " \t")
+		if !strings.HasPrefix(trimmed, "- [ ] ") || plainTask(w.untagged(trimmed[len("- [ ] "):])) != want {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		body := strings.TrimRight(trimmed[len("- [ ] "):], "\r\n")
+		eol := trimmed[len("- [ ] ")+len(body):]
+		if w.config.Tasks.Format == "tasks" {
+			done := " ✅ " + time.Now().Format("2006-01-02")
+			if loc := blockID.FindStringIndex(body); loc != nil {
+				body = body[:loc[0]] + done + body[loc[0]:]
+			} else {
+				body += done
+			}
+		}
+		lines[i] = append(append([]byte{}, line[:indent]...), "- [x] "+body+eol...)
+		if err := writeFileAtomic(notePath, bytes.NewReader(bytes.Join(lines, nil))); err != nil {
+			return false, fmt.Errorf("failed to update note %s: %w", notePath, err)
+		}
+		w.updateInbox()
+		return true, nil
+	}
+	return false, nil
+}
+
+// MarkTaskSeen records on the open task in a note whose text is text,
+// matched as CompleteTask does, that it was seen again: a "last seen"
+// inline field next to its block ID is added or moved to seen's date. It
+// reports whether the task was found. Encrypted notes are left alone.
+func (w *Writer) MarkTaskSeen(notePath, text string, seen time.Time) (bool, error) {
+	if strings.HasSuffix(notePath, EncryptedExtension) {
+		return false, nil
+	}
+	data, err := os.ReadFile(notePath)
+	if err != nil {
+		return false, err
+	}
+
+	want := plainTask(text)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(string(line), " \t")
+		if !strings.HasPrefix(trimmed, "- [ ] ") || plainTask(w.untagged(trimmed[len("- [ ] "):])) != want {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		body := strings.TrimRight(trimmed[len("- [ ] "):], "\r\n")
+		eol := trimmed[len("- [ ] ")+len(body):]
+		field := " [last seen:: " + seen.Local().Format("2006-01-02") + "]"
+		updated := lastSeenField.ReplaceAllString(body, "")
+		if loc := blockID.FindStringIndex(updated); loc != nil {
+			updated = updated[:loc[0]] + field + updated[loc[0]:]
+		} else {
+			updated += field
+		}
+		if updated == body {
+			return true, nil
+		}
+		lines[i] = append(append([]byte{}, line[:indent]...), "- [ ] "+updated+eol...)
+		if err := writeFileAtomic(notePath, bytes.NewReader(bytes.Join(lines, nil))); err != nil {
+			return false, fmt.Errorf("failed to update note %s: %w", notePath, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// untagged removes the configured tag from a task line
+func (w *Writer) untagged(text string) string {
+	if w.config.Tasks.Tag == "" {
+		return text
+	}
+	words := strings.Fields(text)
+	kept := words[:0]
+	for _, word := range words {
+		if word != w.config.Tasks.Tag {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// plainTask reduces a task line to comparable text: the Tasks plugin's
+// fields, the last-seen field and the block ID dropped, links replaced by
+// the text they show, lower-cased, with single spaces
+func plainTask(text string) string {
+	text = blockID.ReplaceAllString(text, "")
+	text = lastSeenField.ReplaceAllString(text, "")
+	for _, signifier := range taskSignifiers {
+		if i := strings.Index(text, signifier); i >= 0 {
+			text = text[:i]
+		}
+	}
+	text = wikilink.ReplaceAllString(text, "$1")
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	return strings.TrimRight(text, ".!;")
+}
@@ -0,0 +1,86 @@
+package obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+func TestMarkTaskSeen(t *testing.T) {
+	seen := time.Date(2025, 1, 16, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		text   string
+		want   string
+		found  bool
+	}{
+		{
+			name:  "adds the field before the block ID",
+			line:  "- [ ] Reply to Anna #task ^task-1a2b3c4d",
+			text:  "Reply to Anna",
+			want:  "- [ ] Reply to Anna #task [last seen:: 2025-01-16] ^task-1a2b3c4d",
+			found: true,
+		},
+		{
+			name:  "moves an earlier date",
+			line:  "- [ ] Reply to Anna #task [last seen:: 2025-01-10] ^task-1a2b3c4d",
+			text:  "reply to anna.",
+			want:  "- [ ] Reply to Anna #task [last seen:: 2025-01-16] ^task-1a2b3c4d",
+			found: true,
+		},
+		{
+			name:   "keeps the Tasks plugin's fields",
+			format: "tasks",
+			line:   "- [ ] Send the [[Q3 report]] #task ⏫ ➕ 2025-01-15 ^task-0f0f0f0f",
+			text:   "Send the Q3 report",
+			want:   "- [ ] Send the [[Q3 report]] #task ⏫ ➕ 2025-01-15 [last seen:: 2025-01-16] ^task-0f0f0f0f",
+			found:  true,
+		},
+		{
+			name:  "appends without a block ID",
+			line:  "- [ ] Book flights #task",
+			text:  "Book flights",
+			want:  "- [ ] Book flights #task [last seen:: 2025-01-16]",
+			found: true,
+		},
+		{
+			name: "leaves ticked tasks alone",
+			line: "- [x] Book flights #task",
+			text: "Book flights",
+			want: "- [x] Book flights #task",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vault := t.TempDir()
+			notePath := filepath.Join(vault, "note.md")
+			if err := os.WriteFile(notePath, []byte("# Note\n\n"+test.line+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			w := New(&config.ObsidianConfig{
+				VaultPath: vault,
+				Tasks:     config.NoteTasksConfig{Format: test.format, Tag: "#task"},
+			}, &config.EncryptionConfig{}, nil)
+
+			found, err := w.MarkTaskSeen(notePath, test.text, seen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != test.found {
+				t.Errorf("found = %v, want %v", found, test.found)
+			}
+			data, err := os.ReadFile(notePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "# Note\n\n" + test.want + "\n"; string(data) != want {
+				t.Errorf("note =\n%s\nwant\n%s", data, want)
+			}
+		})
+	}
+}
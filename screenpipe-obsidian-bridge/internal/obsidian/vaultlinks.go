@@ -0,0 +1,429 @@
+package obsidian
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+// wikilinkTarget matches a wikilink, capturing the note it links to
+var wikilinkTarget = regexp.MustCompile(`\[\[([^\]|#^]*)`)
+
+// fuzzyMinLength is the shortest term, in characters, that also matches
+// with one typo; shorter ones collide with ordinary words too easily
+const fuzzyMinLength = 8
+
+// indexMaxAge is how long the vault index is used before the vault is listed
+// again, so busy periods do not walk the vault for every note
+const indexMaxAge = 30 * time.Second
+
+// vaultIndex maps the titles and frontmatter aliases of the notes in the
+// vault to those notes. It is refreshed by listing the vault when it is used
+// and older than indexMaxAge, and only notes that changed since are read
+// again, so notes created, renamed or deleted in Obsidian are picked up
+// without a restart.
+type vaultIndex struct {
+	config *config.ObsidianConfig
+
+	mu sync.Mutex
+	// files holds what was read from each note, by vault-relative path
+	files map[string]indexedFile
+	// terms maps the normalized titles and aliases to the notes they name,
+	// as vault-relative paths without extension
+	terms map[string][]string
+	// byWords holds the terms by their number of words, for fuzzy matching
+	byWords map[int][]string
+	// maxWords is the number of words of the longest term
+	maxWords int
+	// refreshedAt is when the vault was last listed
+	refreshedAt time.Time
+}
+
+// indexedFile is a note as last read
+type indexedFile struct {
+	size    int64
+	modTime time.Time
+	aliases []string
+}
+
+func newVaultIndex(cfg *config.ObsidianConfig) *vaultIndex {
+	return &vaultIndex{
+		config: cfg,
+		files:  make(map[string]indexedFile),
+	}
+}
+
+// refreshStale refreshes the index when it is older than indexMaxAge at now
+func (x *vaultIndex) refreshStale(now time.Time) {
+	if x.terms != nil && now.Sub(x.refreshedAt) < indexMaxAge {
+		return
+	}
+	x.refresh()
+	x.refreshedAt = now
+}
+
+// refresh re-reads the notes that changed since the last refresh and
+// rebuilds the terms when any did
+func (x *vaultIndex) refresh() {
+	start := time.Now()
+	vault := filepath.Clean(x.config.VaultPath)
+	// Generated notes link to the vault, not the other way round
+	generated := filepath.Join(vault, x.config.NotesSubdirectory)
+
+	changed := x.terms == nil
+	seen := make(map[string]bool, len(x.files))
+	filepath.WalkDir(vault, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are left out of the index
+			return nil
+		}
+		if d.IsDir() {
+			if p != vault && (strings.HasPrefix(d.Name(), ".") || p == generated) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(p), ".md") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(vault, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == x.config.Tasks.Inbox {
+			return nil
+		}
+		seen[rel] = true
+
+		cached, ok := x.files[rel]
+		if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		x.files[rel] = indexedFile{size: info.Size(), modTime: info.ModTime(), aliases: readAliases(p)}
+		changed = true
+		return nil
+	})
+	for rel := range x.files {
+		if !seen[rel] {
+			delete(x.files, rel)
+			changed = true
+		}
+	}
+
+	if changed {
+		x.rebuild()
+		logger.Debug("indexed vault notes",
+			"notes", len(x.files),
+			"terms", len(x.terms),
+			"duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// rebuild derives the terms from the notes read
+func (x *vaultIndex) rebuild() {
+	x.terms = make(map[string][]string)
+	x.byWords = make(map[int][]string)
+	x.maxWords = 0
+	for rel, file := range x.files {
+		target := strings.TrimSuffix(rel, path.Ext(rel))
+		for _, name := range append([]string{path.Base(target)}, file.aliases...) {
+			name = strings.TrimSpace(name)
+			if utf8.RuneCountInString(name) < x.config.Links.MinLength || x.excluded(rel, name) {
+				continue
+			}
+			words := normalizeWords(name)
+			if len(words) == 0 {
+				continue
+			}
+			term := strings.Join(words, " ")
+			if !containsString(x.terms[term], target) {
+				if x.terms[term] == nil {
+					x.byWords[len(words)] = append(x.byWords[len(words)], term)
+				}
+				x.terms[term] = append(x.terms[term], target)
+			}
+			x.maxWords = max(x.maxWords, len(words))
+		}
+	}
+}
+
+// excluded reports whether obsidian.links.exclude rules out linking to the
+// note at rel by name. Path patterns also match the folders a note is in.
+func (x *vaultIndex) excluded(rel, name string) bool {
+	for _, pattern := range x.config.Links.Exclude {
+		if strings.EqualFold(pattern, name) {
+			return true
+		}
+		for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+			if matched, _ := path.Match(pattern, p); matched {
+				return true
+			}
+			if matched, _ := path.Match(pattern, strings.TrimSuffix(p, path.Ext(p))); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookup returns the note a run of normalized words names: exactly, as a
+// singular or plural, or for long terms with one typo. Terms naming more
+// than one note are ambiguous and match nothing.
+func (x *vaultIndex) lookup(words []string) string {
+	last := words[len(words)-1]
+	prefix := strings.Join(words[:len(words)-1], " ")
+	if prefix != "" {
+		prefix += " "
+	}
+	variants := []string{last, last + "s", last + "es"}
+	if strings.HasSuffix(last, "es") {
+		variants = append(variants, strings.TrimSuffix(last, "es"))
+	}
+	if strings.HasSuffix(last, "s") {
+		variants = append(variants, strings.TrimSuffix(last, "s"))
+	}
+	for _, variant := range variants {
+		if targets := x.terms[prefix+variant]; len(targets) > 0 {
+			if len(targets) == 1 {
+				return targets[0]
+			}
+			return ""
+		}
+	}
+
+	phrase := prefix + last
+	if utf8.RuneCountInString(phrase) < fuzzyMinLength {
+		return ""
+	}
+	match := ""
+	for _, term := range x.byWords[len(words)] {
+		if utf8.RuneCountInString(term) < fuzzyMinLength || !withinOneEdit(phrase, term) {
+			continue
+		}
+		if match != "" || len(x.terms[term]) > 1 {
+			return ""
+		}
+		match = x.terms[term][0]
+	}
+	return match
+}
+
+// linkVaultNotes turns the first mention of an existing vault note in the
+// summary and in each task into a wikilink to it
+func (w *Writer) linkVaultNotes(result *llm.ProcessingResult) {
+	w.index.mu.Lock()
+	defer w.index.mu.Unlock()
+	w.index.refreshStale(time.Now())
+	if len(w.index.terms) == 0 {
+		return
+	}
+
+	links := 0
+	var n int
+	result.ActivitySummary, n = w.index.link(result.ActivitySummary)
+	links += n
+	tasks := make([]string, len(result.ActionableTasks))
+	for i, task := range result.ActionableTasks {
+		tasks[i], n = w.index.link(task)
+		links += n
+	}
+	result.ActionableTasks = tasks
+	if links > 0 {
+		logger.Debug("linked vault notes", "source", result.Metadata.SourceFile, "links", links)
+	}
+}
+
+// mention is a run of words in a text that names a note
+type mention struct {
+	start, end int
+	target     string
+}
+
+// link links the first mention of each note in text that is not linked in
+// it already, and reports the number of links added
+func (x *vaultIndex) link(text string) (string, int) {
+	skip := unlinkable.FindAllStringIndex(text, -1)
+	linked := make(map[string]bool)
+	for _, existing := range wikilinkTarget.FindAllStringSubmatch(text, -1) {
+		linked[strings.TrimSuffix(existing[1], ".md")] = true
+	}
+
+	words := splitWords(text)
+	var mentions []mention
+	for i := range words {
+		for n := min(x.maxWords, len(words)-i); n >= 1; n-- {
+			window := words[i : i+n]
+			if !joined(text, window) {
+				continue
+			}
+			span := []int{window[0].start, window[n-1].end}
+			if insideAny(span, skip) {
+				continue
+			}
+			normalized := make([]string, n)
+			for j, word := range window {
+				normalized[j] = word.normalized
+			}
+			if target := x.lookup(normalized); target != "" {
+				mentions = append(mentions, mention{start: span[0], end: span[1], target: target})
+				break
+			}
+		}
+	}
+
+	// Leftmost mentions win, the longest at the same position
+	var chosen []mention
+	for _, m := range mentions {
+		// Links may name a note by path or, when unique, by title alone
+		if linked[m.target] || linked[path.Base(m.target)] || (len(chosen) > 0 && m.start < chosen[len(chosen)-1].end) {
+			continue
+		}
+		chosen = append(chosen, m)
+		linked[m.target] = true
+	}
+	for i := len(chosen) - 1; i >= 0; i-- {
+		m := chosen[i]
+		text = text[:m.start] + "[[" + m.target + "|" + text[m.start:m.end] + "]]" + text[m.end:]
+	}
+	return text, len(chosen)
+}
+
+// word is a run of letters and digits in a text
+type word struct {
+	start, end int
+	normalized string
+}
+
+// splitWords returns the words of text, lower-cased
+func splitWords(text string) []word {
+	var words []word
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, word{start: start, end: i, normalized: strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{start: start, end: len(text), normalized: strings.ToLower(text[start:])})
+	}
+	return words
+}
+
+// normalizeWords returns the lower-cased words of a title or alias
+func normalizeWords(name string) []string {
+	words := splitWords(name)
+	normalized := make([]string, len(words))
+	for i, w := range words {
+		normalized[i] = w.normalized
+	}
+	return normalized
+}
+
+// joined reports whether consecutive words are separated only as words of a
+// title are: by spaces, or by one hyphen, underscore, dot or slash, so that
+// "command-center" matches "Command Center" but a match never runs across
+// punctuation such as "Alice, Bob"
+func joined(text string, words []word) bool {
+	for i := 1; i < len(words); i++ {
+		separator := text[words[i-1].end:words[i].start]
+		switch {
+		case len(separator) == 1 && strings.ContainsAny(separator, "-_./"):
+		case strings.Trim(separator, " \t") == "":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted or substituted character
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra)-len(rb) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(rb) && ra[i] == rb[i] {
+		i++
+	}
+	if len(ra) == len(rb) {
+		return string(ra[i+min(1, len(ra)-i):]) == string(rb[i+min(1, len(rb)-i):])
+	}
+	return string(ra[i+1:]) == string(rb[i:])
+}
+
+// readAliases returns the aliases in a note's frontmatter
+func readAliases(notePath string) []string {
+	data, err := os.ReadFile(notePath)
+	if err != nil || !bytes.HasPrefix(data, []byte("---")) {
+		return nil
+	}
+	data = bytes.TrimLeft(data[3:], "\r")
+	if !bytes.HasPrefix(data, []byte("\n")) {
+		return nil
+	}
+	end := bytes.Index(data, []byte("\n---"))
+	if end < 0 {
+		return nil
+	}
+
+	var frontmatter struct {
+		Aliases interface{} `yaml:"aliases"`
+		Alias   interface{} `yaml:"alias"`
+	}
+	if err := yaml.Unmarshal(data[:end], &frontmatter); err != nil {
+		logger.Debug("cannot read frontmatter", "path", notePath, "error", err)
+		return nil
+	}
+	var aliases []string
+	for _, value := range []interface{}{frontmatter.Aliases, frontmatter.Alias} {
+		switch value := value.(type) {
+		case string:
+			aliases = append(aliases, value)
+		case []interface{}:
+			for _, alias := range value {
+				if s, ok := alias.(string); ok {
+					aliases = append(aliases, s)
+				}
+			}
+		}
+	}
+	return aliases
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package obsidian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"kubernetes", "kubernetes", true},
+		{"kubernetes", "kubernets", true},
+		{"kubernets", "kubernetes", true},
+		{"kubernetes", "kubernetez", true},
+		{"kubernetes", "xubernetes", true},
+		{"kubernetes", "kubernetess", true},
+		{"kubernetes", "ubernetes", true},
+		{"kubernetes", "kubrenetes", false},
+		{"kubernetes", "kuberne", false},
+		{"kubernetes", "openshift", false},
+		{"straße", "strasse", false},
+		{"straße", "strase", true},
+		{"", "a", true},
+		{"", "ab", false},
+	}
+	for _, test := range tests {
+		if got := withinOneEdit(test.a, test.b); got != test.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	vault := t.TempDir()
+	notes := map[string]string{
+		"Projects/Command Center.md":     "---\naliases: [CC-Dashboard]\n---\n",
+		"People/Alice Johnson.md":        "",
+		"Kubernetes Migration.md":        "",
+		"Work/Roadmap.md":                "",
+		"Personal/Roadmap.md":            "",
+		"Daily/2025-01-01.md":            "",
+		"AI.md":                          "",
+		"Bridge/Standup Summary.md":      "",
+		".trash/Deleted Project Plan.md": "",
+	}
+	for rel, content := range notes {
+		p := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := newVaultIndex(&config.ObsidianConfig{
+		VaultPath:         vault,
+		NotesSubdirectory: "Bridge",
+		Links:             config.LinksConfig{Enabled: true, MinLength: 3, Exclude: []string{"Daily/*"}},
+	})
+	index.refresh()
+
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		links int
+	}{
+		{"title", "Reviewed the Command Center", "Reviewed the [[Projects/Command Center|Command Center]]", 1},
+		{"hyphenated", "Worked on the command-center today", "Worked on the [[Projects/Command Center|command-center]] today", 1},
+		{"plural", "Compared both Command Centers", "Compared both [[Projects/Command Center|Command Centers]]", 1},
+		{"alias", "Opened the CC dashboard", "Opened the [[Projects/Command Center|CC dashboard]]", 1},
+		{"typo", "Planned the Kubernets Migration", "Planned the [[Kubernetes Migration|Kubernets Migration]]", 1},
+		{"first mention only", "Alice Johnson called Alice Johnson", "[[People/Alice Johnson|Alice Johnson]] called Alice Johnson", 1},
+		{"several notes", "Alice Johnson showed the command center", "[[People/Alice Johnson|Alice Johnson]] showed the [[Projects/Command Center|command center]]", 2},
+		{"already linked", "See [[Command Center]] and the command center", "See [[Command Center]] and the command center", 0},
+		{"inside code", "Ran `command center` again", "Ran `command center` again", 0},
+		{"across punctuation", "Met Alice, Johnson and others", "Met Alice, Johnson and others", 0},
+		{"ambiguous title", "Updated the Roadmap", "Updated the Roadmap", 0},
+		{"excluded folder", "Notes from 2025-01-01", "Notes from 2025-01-01", 0},
+		{"too short", "Read about AI", "Read about AI", 0},
+		{"generated note", "Wrote the Standup Summary", "Wrote the Standup Summary", 0},
+		{"hidden folder", "Found the Deleted Project Plan", "Found the Deleted Project Plan", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, links := index.link(test.text)
+			if got != test.want || links != test.links {
+				t.Errorf("link(%q) = %q, %d\nwant %q, %d", test.text, got, links, test.want, test.links)
+			}
+		})
+	}
+}
+
+func TestRefreshStale(t *testing.T) {
+	vault := t.TempDir()
+	write := func(rel string) {
+		if err := os.WriteFile(filepath.Join(vault, rel), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("Command Center.md")
+	index := newVaultIndex(&config.ObsidianConfig{
+		VaultPath: vault,
+		Links:     config.LinksConfig{Enabled: true, MinLength: 3},
+	})
+
+	start := time.Now()
+	index.refreshStale(start)
+	write("Kubernetes Migration.md")
+
+	// Within indexMaxAge the vault is not listed again
+	index.refreshStale(start.Add(indexMaxAge / 2))
+	if _, links := index.link("Planned the Kubernetes Migration"); links != 0 {
+		t.Error("vault listed again before the index was stale")
+	}
+
+	index.refreshStale(start.Add(indexMaxAge))
+	if _, links := index.link("Planned the Kubernetes Migration"); links != 1 {
+		t.Error("new note not indexed once the index was stale")
+	}
+}
@@ -1,6 +1,7 @@
 package obsidian
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,53 +9,180 @@ import (
 	"time"
 
 	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
 	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/secure"
 )
 
+var logger = logging.For(logging.ComponentObsidian)
+
+// EncryptedExtension is appended to notes written into the encrypted sub-folder
+const EncryptedExtension = ".enc"
+
 // Writer handles creating Obsidian-compatible markdown files
 type Writer struct {
-	config *config.ObsidianConfig
+	config     *config.ObsidianConfig
+	encryption *config.EncryptionConfig
+	codec      *secure.Codec
+	// index holds the vault's note titles when obsidian.links is enabled
+	index *vaultIndex
+	// attachments remembers the attachments already stored
+	attachments *attachmentIndex
+	// scratch is set for writers that write notes outside the vault, which
+	// leave the entity notes and the inbox alone
+	scratch bool
 }
 
-// New creates a new Obsidian writer
-func New(cfg *config.ObsidianConfig) *Writer {
+// New creates a new Obsidian writer. Notes whose source type is listed in
+// encryption.EncryptedNoteTypes are sealed with codec.
+func New(cfg *config.ObsidianConfig, encryption *config.EncryptionConfig, codec *secure.Codec) *Writer {
+	w := &Writer{
+		config:      cfg,
+		encryption:  encryption,
+		codec:       codec,
+		attachments: newAttachmentIndex(),
+	}
+	if cfg.Links.Enabled {
+		w.index = newVaultIndex(cfg)
+	}
+	return w
+}
+
+// Scratch returns a writer that writes notes into dir, rendered as w
+// renders them and linking to the notes in w's vault, without touching the
+// vault: entity notes and the inbox are not updated
+func (w *Writer) Scratch(dir string) *Writer {
+	cfg := *w.config
+	cfg.VaultPath = dir
 	return &Writer{
-		config: cfg,
+		config:      &cfg,
+		encryption:  w.encryption,
+		codec:       w.codec,
+		index:       w.index,
+		attachments: newAttachmentIndex(),
+		scratch:     true,
 	}
 }
 
-// WriteNote creates an Obsidian note from processing results
-func (w *Writer) WriteNote(result *llm.ProcessingResult) error {
+// WriteNote creates an Obsidian note from processing results and returns its path
+func (w *Writer) WriteNote(ctx context.Context, result *llm.ProcessingResult) (string, error) {
 	// Ensure the notes directory exists
 	notesPath := filepath.Join(w.config.VaultPath, w.config.NotesSubdirectory)
+	encrypt := w.shouldEncrypt(result)
+	if encrypt {
+		notesPath = filepath.Join(notesPath, w.encryption.EncryptedSubfolder)
+	}
 	if err := os.MkdirAll(notesPath, 0755); err != nil {
-		return fmt.Errorf("failed to create notes directory %s: %w", notesPath, err)
+		return "", fmt.Errorf("failed to create notes directory %s: %w", notesPath, err)
 	}
 
 	// Generate filename
 	filename, err := w.generateFilename(result)
 	if err != nil {
-		return fmt.Errorf("failed to generate filename: %w", err)
+		return "", fmt.Errorf("failed to generate filename: %w", err)
 	}
 
 	fullPath := filepath.Join(notesPath, filename)
+	if encrypt {
+		fullPath += EncryptedExtension
+	}
+
+	if err := w.writeNoteFile(ctx, fullPath, encrypt, result); err != nil {
+		return "", err
+	}
+
+	logger.InfoContext(ctx, "created note", "path", fullPath, "encrypted", encrypt)
+	return fullPath, nil
+}
+
+// ReplaceNote regenerates an existing note in place, keeping its file name.
+// Encrypted notes stay encrypted.
+func (w *Writer) ReplaceNote(ctx context.Context, notePath string, result *llm.ProcessingResult) error {
+	encrypt := strings.HasSuffix(notePath, EncryptedExtension)
+	if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+		return fmt.Errorf("failed to create notes directory %s: %w", filepath.Dir(notePath), err)
+	}
+
+	if err := w.writeNoteFile(ctx, notePath, encrypt, result); err != nil {
+		return err
+	}
+
+	logger.InfoContext(ctx, "replaced note", "path", notePath, "encrypted", encrypt)
+	return nil
+}
+
+// writeNoteFile renders a note and writes it, sealed when encrypt is set
+func (w *Writer) writeNoteFile(ctx context.Context, fullPath string, encrypt bool, result *llm.ProcessingResult) error {
+	if encrypt {
+		// Stored media would sit readable next to the sealed note
+		unembedded := *result
+		unembedded.Metadata.Attachments = nil
+		result = &unembedded
+	} else {
+		w.storeAttachments(ctx, filepath.Dir(fullPath), result)
+		result = w.withLinks(result)
+	}
 
 	// Generate markdown content
 	content := w.generateMarkdownContent(result)
 
-	// Write the file
+	if encrypt {
+		if err := w.codec.WriteFile(fullPath, []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to write encrypted note to %s: %w", fullPath, err)
+		}
+		return nil
+	}
+
 	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write note to %s: %w", fullPath, err)
 	}
-
-	fmt.Printf("✅ Created Obsidian note: %s\n", fullPath)
+	if !w.scratch {
+		w.updateEntityNotes(ctx, fullPath, result)
+		w.updateInbox()
+	}
 	return nil
 }
 
+// withLinks returns a copy of result whose summary and tasks link to entity
+// notes and existing vault notes, or result itself when neither
+// obsidian.entities nor obsidian.links is enabled
+func (w *Writer) withLinks(result *llm.ProcessingResult) *llm.ProcessingResult {
+	if !w.config.Entities.Enabled && w.index == nil {
+		return result
+	}
+	linked := *result
+	if w.config.Entities.Enabled {
+		w.linkEntities(&linked)
+	}
+	if w.index != nil {
+		w.linkVaultNotes(&linked)
+	}
+	return &linked
+}
+
+// shouldEncrypt reports whether a note's source type is configured for encryption
+func (w *Writer) shouldEncrypt(result *llm.ProcessingResult) bool {
+	if !w.codec.Enabled() {
+		return false
+	}
+	for _, noteType := range w.encryption.EncryptedNoteTypes {
+		if noteType == "*" || noteType == result.Metadata.SourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// EncryptedNotesPath returns the folder holding encrypted notes
+func (w *Writer) EncryptedNotesPath() string {
+	return filepath.Join(w.config.VaultPath, w.config.NotesSubdirectory, w.encryption.EncryptedSubfolder)
+}
+
 // generateFilename creates a filename based on the template
 func (w *Writer) generateFilename(result *llm.ProcessingResult) (string, error) {
 	template := w.config.FilenameTemplate
-	
+
 	// Parse timestamp
 	processedTime, err := time.Parse(time.RFC3339, result.Metadata.ProcessedAt)
 	if err != nil {
@@ -79,53 +207,105 @@ func (w *Writer) generateFilename(result *llm.ProcessingResult) (string, error)
 	return filename, nil
 }
 
+// RenderNote returns a note's markdown without writing it, for previews
+func (w *Writer) RenderNote(result *llm.ProcessingResult) string {
+	return w.generateMarkdownContent(w.withLinks(result))
+}
+
+// Tags returns the tags in a note's frontmatter
+func Tags(result *llm.ProcessingResult) []string {
+	tags := []string{"screenpipe", "automated", "analysis"}
+
+	// Add compliance tag if not compliant
+	if !result.DoctrineCompliance.NamingConventionCompliant {
+		tags = append(tags, "compliance-issues")
+	}
+	return tags
+}
+
 // generateMarkdownContent creates the full markdown content with frontmatter
 func (w *Writer) generateMarkdownContent(result *llm.ProcessingResult) string {
 	var content strings.Builder
 
 	// Write frontmatter
 	content.WriteString("---\n")
-	content.WriteString(fmt.Sprintf("title: \"ScreenPipe Analysis - %s\"\n", 
+	content.WriteString(fmt.Sprintf("title: \"ScreenPipe Analysis - %s\"\n",
 		time.Now().Format("2006-01-02 15:04")))
 	content.WriteString(fmt.Sprintf("created: %s\n", result.Metadata.ProcessedAt))
 	content.WriteString(fmt.Sprintf("source_file: \"%s\"\n", result.Metadata.SourceFile))
+	if result.Metadata.SourceType != "" {
+		content.WriteString(fmt.Sprintf("source_type: \"%s\"\n", result.Metadata.SourceType))
+	}
+	if result.Metadata.Duration != "" {
+		content.WriteString(fmt.Sprintf("duration: \"%s\"\n", result.Metadata.Duration))
+	}
+	if result.Metadata.Language != "" {
+		content.WriteString(fmt.Sprintf("language: \"%s\"\n", result.Metadata.Language))
+	}
+	if result.Metadata.Resolution != "" {
+		content.WriteString(fmt.Sprintf("resolution: \"%s\"\n", result.Metadata.Resolution))
+	}
 	content.WriteString(fmt.Sprintf("llm_model: \"%s\"\n", result.Metadata.Model))
 	content.WriteString(fmt.Sprintf("llm_provider: \"%s\"\n", result.Metadata.Provider))
 	content.WriteString(fmt.Sprintf("compliance_score: %d\n", result.DoctrineCompliance.ComplianceScore))
+	if len(result.Metadata.Attachments) > 0 {
+		content.WriteString("attachments:\n")
+		for _, attachment := range result.Metadata.Attachments {
+			content.WriteString(fmt.Sprintf("  - \"[[%s]]\"\n", attachment.Name))
+		}
+	}
+	if len(result.Metadata.Related) > 0 {
+		content.WriteString("related:\n")
+		for _, related := range result.Metadata.Related {
+			content.WriteString(fmt.Sprintf("  - \"[[%s]]\"\n", related))
+		}
+	}
 	content.WriteString("tags:\n")
-	content.WriteString("  - screenpipe\n")
-	content.WriteString("  - automated\n")
-	content.WriteString("  - analysis\n")
-	
-	// Add compliance tag if not compliant
-	if !result.DoctrineCompliance.NamingConventionCompliant {
-		content.WriteString("  - compliance-issues\n")
+	for _, tag := range Tags(result) {
+		content.WriteString(fmt.Sprintf("  - %s\n", tag))
 	}
-	
 	content.WriteString("---\n\n")
 
 	// Write main content
 	content.WriteString("# ScreenPipe Activity Analysis\n\n")
 
+	// Embedded source media
+	if len(result.Metadata.Attachments) > 0 {
+		content.WriteString("## 📎 Source Media\n\n")
+		for _, attachment := range result.Metadata.Attachments {
+			if attachment.Kind == "keyframe" {
+				content.WriteString(fmt.Sprintf("**[%s]**\n", extract.FormatOffset(attachment.Offset)))
+			}
+			content.WriteString(fmt.Sprintf("![[%s]]\n\n", attachment.Name))
+		}
+	}
+
 	// Activity Summary
 	content.WriteString("## 📋 Activity Summary\n\n")
 	content.WriteString(result.ActivitySummary)
 	content.WriteString("\n\n")
 
 	// Actionable Tasks
-	if len(result.ActionableTasks) > 0 {
+	if len(result.ActionableTasks) > 0 || len(result.Metadata.RepeatedTasks) > 0 {
 		content.WriteString("## ✅ Actionable Tasks\n\n")
-		for _, task := range result.ActionableTasks {
-			content.WriteString(fmt.Sprintf("- [ ] %s\n", task))
+		if len(result.ActionableTasks) > 0 {
+			content.WriteString(w.taskList(result))
+			content.WriteString("\n")
+		}
+		if len(result.Metadata.RepeatedTasks) > 0 {
+			content.WriteString("Seen again, tracked in earlier notes:\n\n")
+			for _, repeated := range result.Metadata.RepeatedTasks {
+				content.WriteString(fmt.Sprintf("- %s ([[%s]])\n", repeated.Text, repeated.Note))
+			}
+			content.WriteString("\n")
 		}
-		content.WriteString("\n")
 	}
 
 	// Doctrine Compliance
 	content.WriteString("## 🔍 Doctrine Compliance Check\n\n")
-	content.WriteString(fmt.Sprintf("**Compliance Score:** %d/100\n\n", 
+	content.WriteString(fmt.Sprintf("**Compliance Score:** %d/100\n\n",
 		result.DoctrineCompliance.ComplianceScore))
-	
+
 	if result.DoctrineCompliance.NamingConventionCompliant {
 		content.WriteString("✅ **Naming Convention:** Compliant\n\n")
 	} else {
@@ -180,7 +360,7 @@ func generateSimpleHash(input string) string {
 // ValidateVaultPath checks if the Obsidian vault path is valid
 func (w *Writer) ValidateVaultPath() error {
 	vaultPath := w.config.VaultPath
-	
+
 	// Check if vault directory exists
 	if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
 		return fmt.Errorf("obsidian vault path does not exist: %s", vaultPath)
@@ -189,7 +369,7 @@ func (w *Writer) ValidateVaultPath() error {
 	// Check if it looks like an Obsidian vault (has .obsidian directory)
 	obsidianDir := filepath.Join(vaultPath, ".obsidian")
 	if _, err := os.Stat(obsidianDir); os.IsNotExist(err) {
-		fmt.Printf("Warning: %s doesn't appear to be an Obsidian vault (missing .obsidian directory)\n", vaultPath)
+		logger.Warn("vault doesn't appear to be an Obsidian vault (missing .obsidian directory)", "path", vaultPath)
 	}
 
 	return nil
@@ -198,7 +378,7 @@ func (w *Writer) ValidateVaultPath() error {
 // CreateVaultStructure creates the necessary directory structure in the vault
 func (w *Writer) CreateVaultStructure() error {
 	notesPath := filepath.Join(w.config.VaultPath, w.config.NotesSubdirectory)
-	
+
 	if err := os.MkdirAll(notesPath, 0755); err != nil {
 		return fmt.Errorf("failed to create notes directory structure: %w", err)
 	}
@@ -232,5 +412,8 @@ Generated by ScreenPipe Obsidian Bridge
 		}
 	}
 
+	// Catch the inbox up with notes written or ticked while stopped
+	w.updateInbox()
+
 	return nil
-} 
\ No newline at end of file
+}
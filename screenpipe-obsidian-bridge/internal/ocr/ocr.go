@@ -0,0 +1,60 @@
+// Package ocr recognizes text in images with the tesseract binary.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// Tesseract runs the tesseract command line tool
+type Tesseract struct {
+	binary    string
+	languages string
+}
+
+// NewTesseract resolves the tesseract binary from ocr.tesseract, searching
+// PATH for bare names
+func NewTesseract(cfg *config.OCRConfig) (*Tesseract, error) {
+	binary, err := exec.LookPath(cfg.Tesseract)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract binary %q not found: %w", cfg.Tesseract, err)
+	}
+	return &Tesseract{binary: binary, languages: cfg.Languages}, nil
+}
+
+// Recognize returns the text in an image, with blank lines and trailing
+// whitespace removed
+func (t *Tesseract) Recognize(ctx context.Context, imagePath string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.binary, imagePath, "stdout", "-l", t.languages)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("tesseract timed out on %s: %w", imagePath, ctx.Err())
+		}
+		return "", fmt.Errorf("tesseract failed on %s: %w: %s", imagePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return Clean(stdout.String()), nil
+}
+
+// Binary returns the resolved path of the tesseract executable
+func (t *Tesseract) Binary() string {
+	return t.binary
+}
+
+// Clean drops empty lines and surrounding whitespace from recognized text
+func Clean(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
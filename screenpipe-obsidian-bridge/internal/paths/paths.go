@@ -0,0 +1,128 @@
+// Package paths resolves file system paths the same way on every platform:
+// it expands ~ and environment variables, supplies per-OS default locations
+// for ScreenPipe data and the Obsidian vault, and records why a path was
+// chosen so it can be reported.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Choice is a resolved path and the reason it was chosen
+type Choice struct {
+	Path   string
+	Reason string
+}
+
+// Expand replaces a leading ~ with the home directory and expands $VAR and
+// ${VAR} (and %VAR% on Windows). References to unset variables are left as
+// they are rather than silently becoming empty.
+func Expand(path string) string {
+	if path == "" {
+		return path
+	}
+
+	path = os.Expand(path, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return "${" + name + "}"
+	})
+	if runtime.GOOS == "windows" {
+		path = expandPercent(path)
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// expandPercent expands Windows-style %VAR% references to set variables
+func expandPercent(path string) string {
+	var expanded strings.Builder
+	for {
+		start := strings.IndexByte(path, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		expanded.WriteString(path[:start])
+		if value, ok := os.LookupEnv(path[start+1 : end]); ok && end > start+1 {
+			expanded.WriteString(value)
+		} else {
+			expanded.WriteString(path[start : end+1])
+		}
+		path = path[end+1:]
+	}
+	expanded.WriteString(path)
+	return expanded.String()
+}
+
+// ScreenPipeData returns the directory ScreenPipe most likely writes to: the
+// first of the platform's usual locations that exists, or ScreenPipe's own
+// default when none does yet
+func ScreenPipeData() Choice {
+	candidates := screenPipeCandidates()
+	for _, candidate := range candidates {
+		if isDir(candidate) {
+			return Choice{Path: candidate, Reason: "first existing of the usual ScreenPipe locations"}
+		}
+	}
+	return Choice{
+		Path:   candidates[0],
+		Reason: fmt.Sprintf("ScreenPipe's default location; none of %s exist yet", strings.Join(candidates, ", ")),
+	}
+}
+
+// ObsidianVault returns the vault Obsidian itself has open, or was last
+// using, falling back to the location Obsidian suggests for a new vault
+func ObsidianVault() Choice {
+	if vaults := Vaults(); len(vaults) > 0 {
+		vault := vaults[0]
+		if vault.Open {
+			return Choice{Path: vault.Path, Reason: fmt.Sprintf("open in Obsidian, from %s", vault.Registry)}
+		}
+		return Choice{Path: vault.Path, Reason: fmt.Sprintf("most recently opened in Obsidian, from %s", vault.Registry)}
+	}
+
+	path := filepath.Join(documentsDir(), "Obsidian Vault")
+	return Choice{
+		Path:   path,
+		Reason: fmt.Sprintf("Obsidian's default vault location; no vaults registered in %s", strings.Join(obsidianConfigFiles(), ", ")),
+	}
+}
+
+// home returns the user's home directory, or "." if it cannot be determined
+func home() string {
+	if dir, err := os.UserHomeDir(); err == nil {
+		return dir
+	}
+	return "."
+}
+
+// xdgDir returns the XDG base directory in env, or fallback below the home
+// directory when it is unset or not absolute, as the spec requires
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(home(), fallback)
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
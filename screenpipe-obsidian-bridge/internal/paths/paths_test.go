@@ -0,0 +1,103 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("BRIDGE_DATA", "/data")
+	os.Unsetenv("BRIDGE_UNSET")
+
+	tests := []struct {
+		path, want string
+	}{
+		{"", ""},
+		{"~", home},
+		{"~/vault", filepath.Join(home, "vault")},
+		{"~user/vault", "~user/vault"},
+		{"$BRIDGE_DATA/screenpipe", "/data/screenpipe"},
+		{"${BRIDGE_DATA}/screenpipe", "/data/screenpipe"},
+		{"$BRIDGE_UNSET/screenpipe", "${BRIDGE_UNSET}/screenpipe"},
+		{"/srv/~/vault", "/srv/~/vault"},
+	}
+	for _, test := range tests {
+		if got := Expand(test.path); got != test.want {
+			t.Errorf("Expand(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestExpandPercent(t *testing.T) {
+	t.Setenv("BRIDGE_DATA", `C:\data`)
+	os.Unsetenv("BRIDGE_UNSET")
+
+	tests := []struct {
+		path, want string
+	}{
+		{`%BRIDGE_DATA%\screenpipe`, `C:\data\screenpipe`},
+		{`%BRIDGE_UNSET%\screenpipe`, `%BRIDGE_UNSET%\screenpipe`},
+		{`100%\done`, `100%\done`},
+		{`%%BRIDGE_DATA%`, `%%BRIDGE_DATA%`},
+	}
+	for _, test := range tests {
+		if got := expandPercent(test.path); got != test.want {
+			t.Errorf("expandPercent(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestReadVaults(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "Work")
+	personal := filepath.Join(dir, "Personal")
+	archive := filepath.Join(dir, "Archive")
+	for _, vault := range []string{work, personal, archive} {
+		if err := os.Mkdir(vault, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := filepath.Join(dir, "obsidian.json")
+	writeJSON(t, registry, `{"vaults": {
+		"a": {"path": "`+archive+`", "ts": 1000},
+		"b": {"path": "`+work+`", "ts": 2000, "open": true},
+		"c": {"path": "`+personal+`", "ts": 3000},
+		"d": {"path": "`+filepath.Join(dir, "Deleted")+`", "ts": 4000},
+		"e": {"path": "", "ts": 5000}
+	}}`)
+
+	vaults, err := ReadVaults(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, vault := range vaults {
+		got = append(got, vault.Path)
+		if vault.Registry != registry {
+			t.Errorf("%s: registry = %q, want %q", vault.Path, vault.Registry, registry)
+		}
+	}
+	if want := []string{work, personal, archive}; !reflect.DeepEqual(got, want) {
+		t.Errorf("vaults = %q, want %q", got, want)
+	}
+
+	writeJSON(t, registry, `{"vaults": `)
+	if _, err := ReadVaults(registry); err == nil {
+		t.Error("expected an error for a malformed registry")
+	}
+	if _, err := ReadVaults(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing registry")
+	}
+}
+
+func writeJSON(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
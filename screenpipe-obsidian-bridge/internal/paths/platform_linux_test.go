@@ -0,0 +1,80 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocumentsDir(t *testing.T) {
+	home := t.TempDir()
+	configDir := filepath.Join(home, "config")
+	if err := os.Mkdir(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	tests := []struct {
+		name, env, userDirs, want string
+	}{
+		{"default", "", "", filepath.Join(home, "Documents")},
+		{"environment", "/srv/docs", `XDG_DOCUMENTS_DIR="$HOME/Dokumente"`, "/srv/docs"},
+		{"relative environment", "docs", "", filepath.Join(home, "Documents")},
+		{"home relative", "", "# written by xdg-user-dirs-update\nXDG_DESKTOP_DIR=\"$HOME/Desktop\"\nXDG_DOCUMENTS_DIR=\"$HOME/Dokumente\"\n", filepath.Join(home, "Dokumente")},
+		{"absolute", "", `XDG_DOCUMENTS_DIR="/srv/docs"`, "/srv/docs"},
+		{"unquoted", "", `XDG_DOCUMENTS_DIR=$HOME/Docs`, filepath.Join(home, "Docs")},
+		{"relative", "", `XDG_DOCUMENTS_DIR="Docs"`, filepath.Join(home, "Documents")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("XDG_DOCUMENTS_DIR", test.env)
+			userDirs := filepath.Join(configDir, "user-dirs.dirs")
+			os.Remove(userDirs)
+			if test.userDirs != "" {
+				if err := os.WriteFile(userDirs, []byte(test.userDirs), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := documentsDir(); got != test.want {
+				t.Errorf("documentsDir() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestObsidianVault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DOCUMENTS_DIR", "")
+
+	if got, want := ObsidianVault().Path, filepath.Join(home, "Documents", "Obsidian Vault"); got != want {
+		t.Errorf("without vaults: path = %q, want %q", got, want)
+	}
+
+	// The Flatpak registry has the open vault, the native one an older one
+	native := filepath.Join(home, "Native")
+	flatpak := filepath.Join(home, "Flatpak")
+	for _, vault := range []string{native, flatpak} {
+		if err := os.Mkdir(vault, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registries := obsidianConfigFiles()
+	for _, registry := range registries[:2] {
+		if err := os.MkdirAll(filepath.Dir(registry), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeJSON(t, registries[0], `{"vaults": {"a": {"path": "`+native+`", "ts": 2000}}}`)
+	writeJSON(t, registries[1], `{"vaults": {"b": {"path": "`+flatpak+`", "ts": 1000, "open": true}}}`)
+
+	choice := ObsidianVault()
+	if choice.Path != flatpak {
+		t.Errorf("path = %q, want %q", choice.Path, flatpak)
+	}
+	if want := "open in Obsidian, from " + registries[1]; choice.Reason != want {
+		t.Errorf("reason = %q, want %q", choice.Reason, want)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !windows
+
+package paths
+
+import "path/filepath"
+
+// screenPipeCandidates lists where ScreenPipe keeps its data
+func screenPipeCandidates() []string {
+	return []string{filepath.Join(home(), ".screenpipe", "data")}
+}
+
+// obsidianConfigFiles lists where Obsidian keeps its vault registry, using
+// the XDG layout common to other Unix systems
+func obsidianConfigFiles() []string {
+	return []string{filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "obsidian", "obsidian.json")}
+}
+
+// documentsDir returns the user's Documents folder
+func documentsDir() string {
+	return filepath.Join(home(), "Documents")
+}
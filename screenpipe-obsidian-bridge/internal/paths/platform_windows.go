@@ -0,0 +1,25 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// screenPipeCandidates lists where ScreenPipe keeps its data on Windows
+func screenPipeCandidates() []string {
+	return []string{filepath.Join(home(), ".screenpipe", "data")}
+}
+
+// obsidianConfigFiles lists where Obsidian keeps its vault registry
+func obsidianConfigFiles() []string {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		appData = filepath.Join(home(), "AppData", "Roaming")
+	}
+	return []string{filepath.Join(appData, "obsidian", "obsidian.json")}
+}
+
+// documentsDir returns the user's Documents folder
+func documentsDir() string {
+	return filepath.Join(home(), "Documents")
+}
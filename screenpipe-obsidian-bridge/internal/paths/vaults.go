@@ -0,0 +1,80 @@
+package paths
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Vault is an Obsidian vault known to the Obsidian app
+type Vault struct {
+	Path string
+	// Open is set for the vault Obsidian currently has open
+	Open       bool
+	LastOpened time.Time
+	// Registry is the obsidian.json the vault is registered in
+	Registry string
+}
+
+// Vaults lists the vaults registered with Obsidian that still exist, from
+// every obsidian.json found, open vaults first and then by when they were
+// last opened
+func Vaults() []Vault {
+	vaults := []Vault{}
+	for _, file := range obsidianConfigFiles() {
+		found, err := ReadVaults(file)
+		if err != nil {
+			continue
+		}
+		vaults = append(vaults, found...)
+	}
+	sortVaults(vaults)
+	return vaults
+}
+
+// ReadVaults reads the vaults registered in an obsidian.json file, skipping
+// ones whose folder no longer exists, sorted like Vaults
+func ReadVaults(file string) ([]Vault, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var registry struct {
+		Vaults map[string]struct {
+			Path string `json:"path"`
+			TS   int64  `json:"ts"`
+			Open bool   `json:"open"`
+		} `json:"vaults"`
+	}
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	vaults := []Vault{}
+	for _, entry := range registry.Vaults {
+		if entry.Path == "" || !isDir(entry.Path) {
+			continue
+		}
+		vaults = append(vaults, Vault{
+			Path:       entry.Path,
+			Open:       entry.Open,
+			LastOpened: time.UnixMilli(entry.TS),
+			Registry:   file,
+		})
+	}
+	sortVaults(vaults)
+	return vaults, nil
+}
+
+// sortVaults orders open vaults first, then the most recently opened
+func sortVaults(vaults []Vault) {
+	sort.SliceStable(vaults, func(i, j int) bool {
+		if vaults[i].Open != vaults[j].Open {
+			return vaults[i].Open
+		}
+		return vaults[i].LastOpened.After(vaults[j].LastOpened)
+	})
+}
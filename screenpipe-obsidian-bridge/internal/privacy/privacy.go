@@ -0,0 +1,369 @@
+package privacy
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
+)
+
+// Rule actions: include keeps an item, exclude drops it
+const (
+	actionInclude = "include"
+	actionExclude = "exclude"
+)
+
+// Filter decides which captured items may be processed
+type Filter struct {
+	defaultAction string
+	rules         []rule
+}
+
+// rule is a compiled config.PrivacyRule
+type rule struct {
+	name    string
+	action  string
+	apps    []pattern
+	windows []pattern
+	domains []pattern
+	times   []clockRange
+}
+
+// pattern is a case-insensitive glob where * and ? also match "/"
+type pattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+// clockRange is a time-of-day window in minutes since midnight. Ranges whose
+// end is before their start wrap around midnight (e.g. 22:00-06:00).
+type clockRange struct {
+	start, end int
+	text       string
+}
+
+// Decision is the outcome of evaluating one item against the rules
+type Decision struct {
+	Keep   bool
+	Rule   string // Name of the deciding rule, empty when the default applied
+	Action string
+	Trace  []RuleTrace
+}
+
+// RuleTrace records how a single rule evaluated, for explain-rule output
+type RuleTrace struct {
+	Rule    string
+	Action  string
+	Matched bool
+	Detail  string
+}
+
+// New compiles the privacy rules from configuration
+func New(cfg *config.PrivacyConfig) (*Filter, error) {
+	f := &Filter{defaultAction: actionInclude}
+	if cfg.DefaultAction != "" {
+		f.defaultAction = cfg.DefaultAction
+	}
+	if err := checkAction(f.defaultAction); err != nil {
+		return nil, fmt.Errorf("privacy default action: %w", err)
+	}
+
+	for i, rc := range cfg.Rules {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i+1)
+		}
+
+		if err := checkAction(rc.Action); err != nil {
+			return nil, fmt.Errorf("privacy rule %q: %w", name, err)
+		}
+
+		r := rule{
+			name:    name,
+			action:  rc.Action,
+			apps:    compilePatterns(rc.Apps),
+			windows: compilePatterns(rc.WindowTitles),
+			domains: compilePatterns(rc.Domains),
+		}
+
+		for _, text := range rc.TimeRanges {
+			cr, err := parseClockRange(text)
+			if err != nil {
+				return nil, fmt.Errorf("privacy rule %q: %w", name, err)
+			}
+			r.times = append(r.times, cr)
+		}
+
+		f.rules = append(f.rules, r)
+	}
+
+	return f, nil
+}
+
+// checkAction rejects actions other than include and exclude, so a typo is
+// not silently treated as exclude
+func checkAction(action string) error {
+	if action != actionInclude && action != actionExclude {
+		return fmt.Errorf("action must be %q or %q, got %q", actionInclude, actionExclude, action)
+	}
+	return nil
+}
+
+// Evaluate runs an item through the rules in order; the first matching rule
+// decides, otherwise the default action applies
+func (f *Filter) Evaluate(item extract.Item) Decision {
+	decision := Decision{Action: f.defaultAction}
+
+	for _, r := range f.rules {
+		matched, detail := r.match(item)
+		decision.Trace = append(decision.Trace, RuleTrace{
+			Rule:    r.name,
+			Action:  r.action,
+			Matched: matched,
+			Detail:  detail,
+		})
+		if matched && decision.Rule == "" {
+			decision.Rule = r.name
+			decision.Action = r.action
+		}
+	}
+
+	decision.Keep = decision.Action == actionInclude
+	return decision
+}
+
+// Apply splits items into the ones that may be processed and the number that
+// were excluded, keyed by the rule (or "default") that excluded them
+func (f *Filter) Apply(items []extract.Item) ([]extract.Item, map[string]int) {
+	kept := make([]extract.Item, 0, len(items))
+	excluded := map[string]int{}
+
+	for _, item := range items {
+		decision := f.Evaluate(item)
+		if decision.Keep {
+			kept = append(kept, item)
+			continue
+		}
+		name := decision.Rule
+		if name == "" {
+			name = "default"
+		}
+		excluded[name]++
+	}
+
+	return kept, excluded
+}
+
+// Stretch is part of a recording, as offsets from its start
+type Stretch struct {
+	From, To time.Duration
+}
+
+// Stretches decides a recording before its content is read, from the
+// metadata in item and its length: every minute of the day it spans is
+// evaluated as if item were captured then. It returns the stretches the
+// rules keep, in order, and the number of excluded stretches, keyed by the
+// rule (or "default") that excluded them.
+func (f *Filter) Stretches(item extract.Item, length time.Duration) ([]Stretch, map[string]int) {
+	kept := []Stretch{}
+	excluded := map[string]int{}
+
+	start := item.CapturedAt
+	previous := ""
+	for from := time.Duration(0); from == 0 || from < length; {
+		at := start.Add(from)
+		to := at.Truncate(time.Minute).Add(time.Minute).Sub(start)
+		if to > length {
+			to = length
+		}
+
+		probe := item
+		probe.CapturedAt = at
+		decision := f.Evaluate(probe)
+		switch {
+		case decision.Keep && previous == "" && len(kept) > 0 && kept[len(kept)-1].To == from:
+			kept[len(kept)-1].To = to
+		case decision.Keep:
+			kept = append(kept, Stretch{From: from, To: to})
+		default:
+			name := decision.Rule
+			if name == "" {
+				name = "default"
+			}
+			if name != previous {
+				excluded[name]++
+			}
+			previous = name
+		}
+		if decision.Keep {
+			previous = ""
+		}
+
+		if to <= from {
+			break
+		}
+		from = to
+	}
+
+	return kept, excluded
+}
+
+// match reports whether every criterion of the rule matches the item
+func (r rule) match(item extract.Item) (bool, string) {
+	details := []string{}
+
+	if len(r.apps) > 0 {
+		pattern, ok := matchAny(r.apps, item.App)
+		if !ok {
+			return false, fmt.Sprintf("app %q matches none of %s", item.App, patternList(r.apps))
+		}
+		details = append(details, fmt.Sprintf("app %q matches %q", item.App, pattern))
+	}
+
+	if len(r.windows) > 0 {
+		pattern, ok := matchAny(r.windows, item.Window)
+		if !ok {
+			return false, fmt.Sprintf("window %q matches none of %s", item.Window, patternList(r.windows))
+		}
+		details = append(details, fmt.Sprintf("window %q matches %q", item.Window, pattern))
+	}
+
+	if len(r.domains) > 0 {
+		host := hostOf(item.URL)
+		pattern, ok := matchDomain(r.domains, host)
+		if !ok {
+			return false, fmt.Sprintf("domain %q matches none of %s", host, patternList(r.domains))
+		}
+		details = append(details, fmt.Sprintf("domain %q matches %q", host, pattern))
+	}
+
+	if len(r.times) > 0 {
+		// Time-of-day rules are written in the user's local time
+		local := item.CapturedAt.Local()
+		minute := local.Hour()*60 + local.Minute()
+		var hit *clockRange
+		for i := range r.times {
+			if r.times[i].contains(minute) {
+				hit = &r.times[i]
+				break
+			}
+		}
+		if hit == nil {
+			return false, fmt.Sprintf("time %s outside %s", local.Format("15:04"), r.timeList())
+		}
+		details = append(details, fmt.Sprintf("time %s within %s", local.Format("15:04"), hit.text))
+	}
+
+	if len(details) == 0 {
+		return true, "rule has no criteria and matches everything"
+	}
+	return true, strings.Join(details, ", ")
+}
+
+// timeList formats the rule's time ranges for explanations
+func (r rule) timeList() string {
+	texts := make([]string, len(r.times))
+	for i, t := range r.times {
+		texts[i] = t.text
+	}
+	return strings.Join(texts, ", ")
+}
+
+// contains reports whether a minute of the day falls inside the range
+func (c clockRange) contains(minute int) bool {
+	if c.start <= c.end {
+		return minute >= c.start && minute <= c.end
+	}
+	return minute >= c.start || minute <= c.end
+}
+
+// parseClockRange parses "HH:MM-HH:MM"
+func parseClockRange(text string) (clockRange, error) {
+	parts := strings.Split(text, "-")
+	if len(parts) != 2 {
+		return clockRange{}, fmt.Errorf("invalid time range %q (want HH:MM-HH:MM)", text)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	if err != nil {
+		return clockRange{}, fmt.Errorf("invalid time range %q: %w", text, err)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err != nil {
+		return clockRange{}, fmt.Errorf("invalid time range %q: %w", text, err)
+	}
+
+	return clockRange{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+		text:  text,
+	}, nil
+}
+
+// compilePatterns turns glob patterns into anchored case-insensitive regexps
+func compilePatterns(globs []string) []pattern {
+	patterns := make([]pattern, len(globs))
+	for i, glob := range globs {
+		expr := regexp.QuoteMeta(glob)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		patterns[i] = pattern{
+			text: glob,
+			re:   regexp.MustCompile("(?i)^" + expr + "$"),
+		}
+	}
+	return patterns
+}
+
+// matchAny returns the first pattern matching value
+func matchAny(patterns []pattern, value string) (string, bool) {
+	for _, p := range patterns {
+		if p.re.MatchString(value) {
+			return p.text, true
+		}
+	}
+	return "", false
+}
+
+// matchDomain matches a host against domain patterns; a plain domain also
+// matches its subdomains so "chase.com" covers "secure.chase.com"
+func matchDomain(patterns []pattern, host string) (string, bool) {
+	if host == "" {
+		return "", false
+	}
+	for _, p := range patterns {
+		domain := strings.ToLower(p.text)
+		if p.re.MatchString(host) || strings.HasSuffix(host, "."+domain) {
+			return p.text, true
+		}
+	}
+	return "", false
+}
+
+// patternList formats patterns for explanations
+func patternList(patterns []pattern) string {
+	texts := make([]string, len(patterns))
+	for i, p := range patterns {
+		texts[i] = fmt.Sprintf("%q", p.text)
+	}
+	return "[" + strings.Join(texts, ", ") + "]"
+}
+
+// hostOf extracts the lowercase host from a URL, tolerating missing schemes
+func hostOf(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
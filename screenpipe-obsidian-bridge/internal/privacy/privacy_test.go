@@ -0,0 +1,176 @@
+package privacy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
+)
+
+// at returns a time on a fixed day in local time, as rules are written
+func at(hour, minute int) time.Time {
+	return time.Date(2025, 1, 15, hour, minute, 0, 0, time.Local)
+}
+
+func TestEvaluate(t *testing.T) {
+	filter, err := New(&config.PrivacyConfig{
+		Rules: []config.PrivacyRule{
+			{Name: "banking", Action: "exclude", Domains: []string{"chase.com"}},
+			{Name: "passwords", Action: "exclude", Apps: []string{"1Password*"}},
+			{Name: "work-chat", Action: "include", Apps: []string{"Slack"}},
+			{Name: "night", Action: "exclude", TimeRanges: []string{"22:00-06:00"}},
+			{Name: "lunch", Action: "exclude", TimeRanges: []string{"12:00-13:00"}, WindowTitles: []string{"*personal*"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		item extract.Item
+		keep bool
+		rule string
+	}{
+		{"domain", extract.Item{URL: "https://chase.com/login", CapturedAt: at(10, 0)}, false, "banking"},
+		{"subdomain", extract.Item{URL: "https://secure.chase.com/accounts", CapturedAt: at(10, 0)}, false, "banking"},
+		{"URL without scheme", extract.Item{URL: "secure.chase.com", CapturedAt: at(10, 0)}, false, "banking"},
+		{"lookalike domain", extract.Item{URL: "https://notchase.com", CapturedAt: at(10, 0)}, true, ""},
+		{"app glob ignoring case", extract.Item{App: "1password 8", CapturedAt: at(10, 0)}, false, "passwords"},
+		{"first matching rule decides", extract.Item{App: "Slack", CapturedAt: at(23, 0)}, true, "work-chat"},
+		{"before midnight", extract.Item{App: "Terminal", CapturedAt: at(22, 0)}, false, "night"},
+		{"after midnight", extract.Item{App: "Terminal", CapturedAt: at(5, 59)}, false, "night"},
+		{"end minute is included", extract.Item{App: "Terminal", CapturedAt: at(6, 0)}, false, "night"},
+		{"outside the wrapping range", extract.Item{App: "Terminal", CapturedAt: at(6, 1)}, true, ""},
+		{"every criterion must match", extract.Item{Window: "My personal notes", CapturedAt: at(14, 0)}, true, ""},
+		{"window and time match", extract.Item{Window: "My personal notes", CapturedAt: at(12, 30)}, false, "lunch"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := filter.Evaluate(test.item)
+			if decision.Keep != test.keep || decision.Rule != test.rule {
+				t.Errorf("Evaluate = keep %v by %q, want keep %v by %q", decision.Keep, decision.Rule, test.keep, test.rule)
+			}
+			if len(decision.Trace) != 5 {
+				t.Errorf("trace has %d rules, want 5", len(decision.Trace))
+			}
+		})
+	}
+}
+
+func TestDefaultAction(t *testing.T) {
+	filter, err := New(&config.PrivacyConfig{
+		DefaultAction: "exclude",
+		Rules:         []config.PrivacyRule{{Name: "editor", Action: "include", Apps: []string{"Code"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, excluded := filter.Apply([]extract.Item{
+		{Text: "a", App: "Code"},
+		{Text: "b", App: "Mail"},
+		{Text: "c", App: "Notes"},
+	})
+	if len(kept) != 1 || kept[0].Text != "a" {
+		t.Errorf("kept %v, want the Code item", kept)
+	}
+	if want := map[string]int{"default": 2}; !reflect.DeepEqual(excluded, want) {
+		t.Errorf("excluded %v, want %v", excluded, want)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PrivacyConfig
+	}{
+		{"default action", config.PrivacyConfig{DefaultAction: "drop"}},
+		{"rule action", config.PrivacyConfig{Rules: []config.PrivacyRule{{Action: "Exclude"}}}},
+		{"missing rule action", config.PrivacyConfig{Rules: []config.PrivacyRule{{Apps: []string{"Mail"}}}}},
+		{"time range", config.PrivacyConfig{Rules: []config.PrivacyRule{{Action: "exclude", TimeRanges: []string{"9-17"}}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New(&test.cfg); err == nil {
+				t.Error("New succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestStretches(t *testing.T) {
+	filter, err := New(&config.PrivacyConfig{
+		Rules: []config.PrivacyRule{
+			{Name: "standup", Action: "exclude", TimeRanges: []string{"09:30-09:44"}},
+			{Name: "night", Action: "exclude", TimeRanges: []string{"23:00-00:59"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		length   time.Duration
+		kept     []Stretch
+		excluded map[string]int
+	}{
+		{
+			name:     "nothing excluded",
+			start:    at(10, 0).Add(30 * time.Second),
+			length:   5 * time.Minute,
+			kept:     []Stretch{{0, 5 * time.Minute}},
+			excluded: map[string]int{},
+		},
+		{
+			name:   "excluded in the middle",
+			start:  at(9, 20),
+			length: 40 * time.Minute,
+			kept: []Stretch{
+				{0, 10 * time.Minute},
+				{25 * time.Minute, 40 * time.Minute},
+			},
+			excluded: map[string]int{"standup": 1},
+		},
+		{
+			name:   "starting part-way through a minute",
+			start:  at(9, 29).Add(30 * time.Second),
+			length: 2 * time.Minute,
+			kept: []Stretch{
+				{0, 30 * time.Second},
+			},
+			excluded: map[string]int{"standup": 1},
+		},
+		{
+			name:     "excluded throughout, across midnight",
+			start:    at(23, 50),
+			length:   20 * time.Minute,
+			kept:     []Stretch{},
+			excluded: map[string]int{"night": 1},
+		},
+		{
+			name:     "unknown length",
+			start:    at(9, 35),
+			length:   0,
+			kept:     []Stretch{},
+			excluded: map[string]int{"standup": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, excluded := filter.Stretches(extract.Item{CapturedAt: test.start}, test.length)
+			if !reflect.DeepEqual(kept, test.kept) {
+				t.Errorf("kept %v, want %v", kept, test.kept)
+			}
+			if !reflect.DeepEqual(excluded, test.excluded) {
+				t.Errorf("excluded %v, want %v", excluded, test.excluded)
+			}
+		})
+	}
+}
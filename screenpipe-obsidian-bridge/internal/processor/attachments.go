@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"os"
+
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+// attaches reports whether notes for a source type store its media in the
+// vault
+func (p *pipeline) attaches(sourceType string) bool {
+	cfg := p.config.Obsidian.Attachments
+	if cfg.Mode == "off" {
+		return false
+	}
+	for _, attached := range cfg.Types {
+		if attached == sourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentsFor lists the media of an extracted file to store with its
+// note. Keyframes whose text a privacy rule excluded are never stored, nor
+// are recordings and screenshots any part of which was excluded.
+func (p *pipeline) attachmentsFor(ctx context.Context, extracted *extract.Result, excluded map[string]int) []llm.Attachment {
+	if !p.attaches(extracted.Type) {
+		return nil
+	}
+
+	if extracted.Type == "video" {
+		keyframes := []llm.Attachment{}
+		for _, item := range extracted.Items {
+			if item.Image != nil {
+				keyframes = append(keyframes, llm.Attachment{
+					Kind:   "keyframe",
+					Source: extracted.Path,
+					Data:   item.Image,
+					Offset: item.Offset,
+				})
+			}
+		}
+		return keyframes
+	}
+
+	if len(excluded) > 0 {
+		logger.InfoContext(ctx, "not attaching file with excluded content", "path", extracted.Path)
+		return nil
+	}
+	info, err := os.Stat(extracted.Path)
+	if err != nil {
+		logger.WarnContext(ctx, "cannot attach file", "path", extracted.Path, "error", err)
+		return nil
+	}
+	if info.Size() > int64(p.config.Obsidian.Attachments.MaxFileMB)<<20 {
+		logger.InfoContext(ctx, "not attaching large file", "path", extracted.Path, "bytes", info.Size())
+		return nil
+	}
+	return []llm.Attachment{{Kind: extracted.Type, Source: extracted.Path}}
+}
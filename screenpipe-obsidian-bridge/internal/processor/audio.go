@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/transcribe"
+)
+
+// readAudio transcribes the parts of a recording the privacy rules keep.
+// The rules are decided on the recording's time span before anything is
+// sent: a recording excluded throughout is not transcribed, and excluded
+// stretches of a WAV recording are cut out first. Other formats have no
+// length to go by until they are transcribed, so they are decided at the
+// time they ended. Offline, the requests are listed instead of made and a
+// placeholder stands in for the transcript.
+func (p *pipeline) readAudio(ctx context.Context, path string, offline bool) (*extract.Result, map[string]int, []string, error) {
+	if p.transcriber == nil {
+		return nil, nil, nil, fmt.Errorf("transcription is disabled; set transcription.provider to process %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Recordings are written when they end
+	length, err := transcribe.WAVDuration(path)
+	if err != nil {
+		length = 0
+	}
+	kept, excluded := p.privacyFilter.Stretches(extract.Item{CapturedAt: info.ModTime().Add(-length)}, length)
+	if len(kept) == 0 {
+		return &extract.Result{Path: path, Type: "audio", Duration: length}, excluded, nil, nil
+	}
+	whole := len(excluded) == 0
+	ctx = audit.WithExcludedItems(audit.WithSourceFile(ctx, path), countItems(excluded))
+
+	var calls []string
+	transcript := &transcribe.Transcript{Duration: length}
+	for _, stretch := range kept {
+		label := filepath.Base(path)
+		if !whole {
+			label += fmt.Sprintf(" %s-%s", stretch.From, stretch.To)
+		}
+		if offline {
+			calls = append(calls, fmt.Sprintf("transcription of %s by %s", label, p.transcriber.Name()))
+			transcript.Segments = append(transcript.Segments, transcribe.Segment{
+				Start: stretch.From,
+				End:   stretch.To,
+				Text:  fmt.Sprintf("[transcript of %s]", label),
+			})
+			continue
+		}
+
+		if whole {
+			if transcript, err = p.transcriber.Transcribe(ctx, path); err != nil {
+				return nil, nil, nil, err
+			}
+			break
+		}
+		part, err := p.transcribeStretch(ctx, path, stretch)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if transcript.Language == "" {
+			transcript.Language = part.Language
+		}
+		for _, segment := range part.Segments {
+			segment.Start += stretch.From
+			segment.End += stretch.From
+			transcript.Segments = append(transcript.Segments, segment)
+		}
+	}
+
+	extracted, err := extract.Audio(path, transcript)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return extracted, excluded, calls, nil
+}
+
+// transcribeStretch transcribes part of a WAV recording, cut into a
+// temporary file
+func (p *pipeline) transcribeStretch(ctx context.Context, path string, stretch privacy.Stretch) (*transcribe.Transcript, error) {
+	part, err := os.CreateTemp("", "screenpipe-bridge-*.wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	part.Close()
+	defer os.Remove(part.Name())
+
+	if err := transcribe.CutWAV(path, part.Name(), stretch.From, stretch.To); err != nil {
+		return nil, fmt.Errorf("failed to cut %s: %w", path, err)
+	}
+	return p.transcriber.Transcribe(ctx, part.Name())
+}
+
+// countItems sums the items excluded by each privacy rule
+func countItems(excluded map[string]int) int {
+	total := 0
+	for _, count := range excluded {
+		total += count
+	}
+	return total
+}
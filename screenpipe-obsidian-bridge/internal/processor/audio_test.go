@@ -0,0 +1,173 @@
+package processor
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/transcribe"
+)
+
+// writeRecording writes a silent 8-bit WAV at 100 samples a second that
+// ended at end
+func writeRecording(t *testing.T, path string, length time.Duration, end time.Time) {
+	t.Helper()
+	const rate = 100
+	size := int(length.Seconds() * rate)
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+size))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 1)
+	binary.LittleEndian.PutUint32(header[24:], rate)
+	binary.LittleEndian.PutUint32(header[28:], rate)
+	binary.LittleEndian.PutUint16(header[32:], 1)
+	binary.LittleEndian.PutUint16(header[34:], 8)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(size))
+	if err := os.WriteFile(path, append(header, make([]byte, size)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, end, end); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lengthTranscriber returns one segment a minute into whatever it is sent,
+// and remembers how long each file sent was
+type lengthTranscriber struct {
+	lengths []time.Duration
+}
+
+func (l *lengthTranscriber) Transcribe(ctx context.Context, path string) (*transcribe.Transcript, error) {
+	length, err := transcribe.WAVDuration(path)
+	if err != nil {
+		return nil, err
+	}
+	l.lengths = append(l.lengths, length)
+	return &transcribe.Transcript{
+		Language: "en",
+		Segments: []transcribe.Segment{{Start: time.Minute, End: 2 * time.Minute, Text: "Let's ship on Friday."}},
+	}, nil
+}
+
+func (l *lengthTranscriber) Name() string {
+	return "stub"
+}
+
+func TestReadAudio(t *testing.T) {
+	filter, err := privacy.New(&config.PrivacyConfig{
+		Rules: []config.PrivacyRule{{Name: "standup", Action: "exclude", TimeRanges: []string{"09:30-09:44"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 15, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		end      time.Time
+		length   time.Duration
+		offline  bool
+		sent     []time.Duration
+		offsets  []time.Duration
+		excluded map[string]int
+		calls    int
+	}{
+		{
+			name:     "kept throughout",
+			length:   40 * time.Minute,
+			end:      day(11, 0),
+			sent:     []time.Duration{40 * time.Minute},
+			offsets:  []time.Duration{time.Minute},
+			excluded: map[string]int{},
+		},
+		{
+			name:     "excluded stretch cut out",
+			length:   40 * time.Minute,
+			end:      day(10, 0),
+			sent:     []time.Duration{10 * time.Minute, 15 * time.Minute},
+			offsets:  []time.Duration{time.Minute, 26 * time.Minute},
+			excluded: map[string]int{"standup": 1},
+		},
+		{
+			name:     "excluded throughout",
+			length:   10 * time.Minute,
+			end:      day(9, 44),
+			excluded: map[string]int{"standup": 1},
+		},
+		{
+			name:     "offline",
+			length:   40 * time.Minute,
+			end:      day(10, 0),
+			offline:  true,
+			offsets:  []time.Duration{0, 25 * time.Minute},
+			excluded: map[string]int{"standup": 1},
+			calls:    2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "meeting.wav")
+			writeRecording(t, path, test.length, test.end)
+
+			transcriber := &lengthTranscriber{}
+			p := &pipeline{config: &config.Config{}, privacyFilter: filter, transcriber: transcriber}
+			extracted, excluded, calls, err := p.extract(context.Background(), path, test.offline)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(transcriber.lengths, test.sent) {
+				t.Errorf("sent %v, want %v", transcriber.lengths, test.sent)
+			}
+			var offsets []time.Duration
+			for _, item := range extracted.Items {
+				offsets = append(offsets, item.Offset)
+			}
+			if !reflect.DeepEqual(offsets, test.offsets) {
+				t.Errorf("items at %v, want %v", offsets, test.offsets)
+			}
+			if !reflect.DeepEqual(excluded, test.excluded) {
+				t.Errorf("excluded %v, want %v", excluded, test.excluded)
+			}
+			if len(calls) != test.calls {
+				t.Errorf("calls %q, want %d", calls, test.calls)
+			}
+			for _, call := range calls {
+				if !strings.HasPrefix(call, "transcription of meeting.wav ") {
+					t.Errorf("call %q does not name the recording", call)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentsForExcludedRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meeting.wav")
+	writeRecording(t, path, time.Minute, time.Now())
+	p := &pipeline{config: &config.Config{}}
+	p.config.Obsidian.Attachments = config.AttachmentsConfig{Mode: "copy", Types: []string{"audio"}, MaxFileMB: 10}
+	extracted := &extract.Result{Path: path, Type: "audio"}
+
+	ctx := context.Background()
+	if attachments := p.attachmentsFor(ctx, extracted, map[string]int{}); len(attachments) != 1 {
+		t.Errorf("%d attachments for a kept recording, want 1", len(attachments))
+	}
+	if attachments := p.attachmentsFor(ctx, extracted, map[string]int{"standup": 1}); len(attachments) != 0 {
+		t.Errorf("%d attachments for a partly excluded recording, want none", len(attachments))
+	}
+}
@@ -0,0 +1,204 @@
+package processor
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/metrics"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/state"
+	"screenpipe-obsidian-bridge/internal/tasks"
+)
+
+// Ways a repeated action item is recognized, in the order they are tried
+const (
+	matchExact     = "exact"
+	matchWords     = "words"
+	matchEmbedding = "embedding"
+)
+
+// taskMerge is what mergeRepeatedTasks found: recent action items seen
+// again, and new ones to remember once their note is written
+type taskMerge struct {
+	now   time.Time
+	seen  map[string]state.RecentTask
+	fresh map[string]state.RecentTask
+}
+
+// mergeRepeatedTasks removes the action items that repeat one from a recent
+// note, or one earlier in the same result, and marks the original as seen
+// again. The repeats are listed in the note's metadata instead. replacing
+// is the note being regenerated, whose own action items are not repeats. It
+// returns nil when tasks.dedupe is disabled.
+func (p *Processor) mergeRepeatedTasks(ctx context.Context, current *pipeline, result *llm.ProcessingResult, replacing string) *taskMerge {
+	dedupe := current.config.Tasks.Dedupe
+	if !dedupe.Enabled {
+		return nil
+	}
+	merge := &taskMerge{
+		now:   time.Now(),
+		seen:  make(map[string]state.RecentTask),
+		fresh: make(map[string]state.RecentTask),
+	}
+	if len(result.ActionableTasks) == 0 {
+		return merge
+	}
+
+	recent := p.recentTasks.Since(merge.now.Add(-dedupe.Window))
+	for key, entry := range recent {
+		if replacing != "" && entry.NotePath == replacing {
+			delete(recent, key)
+		}
+	}
+
+	embeddings := embedTasks(ctx, current, result.ActionableTasks)
+
+	var kept []string
+	var details []llm.TaskDetail
+	for i, text := range result.ActionableTasks {
+		var embedding []float32
+		if embeddings != nil {
+			embedding = embeddings[i]
+		}
+
+		if key, match := findRepeat(merge.fresh, text, embedding, dedupe); match != "" {
+			original := merge.fresh[key]
+			original.Seen++
+			merge.fresh[key] = original
+			metrics.TasksMerged.With(match).Inc()
+			logger.DebugContext(ctx, "merged action item repeated in the same note", "task", text, "match", match)
+			continue
+		}
+		if key, match := findRepeat(recent, text, embedding, dedupe); match != "" {
+			original := recent[key]
+			original.LastSeen = merge.now
+			original.Seen++
+			recent[key] = original
+			if _, listed := merge.seen[key]; !listed {
+				result.Metadata.RepeatedTasks = append(result.Metadata.RepeatedTasks, llm.RepeatedTask{
+					Text: original.Text,
+					Note: current.obsidianWriter.RelativePath(original.NotePath),
+				})
+			}
+			merge.seen[key] = original
+			metrics.TasksMerged.With(match).Inc()
+			logger.InfoContext(ctx, "merged repeated action item",
+				"task", text,
+				"original", original.Text,
+				"note", original.NotePath,
+				"match", match,
+				"seen", original.Seen)
+			continue
+		}
+
+		merge.fresh[tasks.Key(text)] = state.RecentTask{
+			Text:      text,
+			FirstSeen: merge.now,
+			LastSeen:  merge.now,
+			Seen:      1,
+			Embedding: embedding,
+		}
+		kept = append(kept, text)
+		details = append(details, result.DetailOf(i))
+	}
+
+	result.ActionableTasks = kept
+	if result.TaskDetails != nil {
+		result.TaskDetails = details
+	}
+	return merge
+}
+
+// rememberTasks records the action items of a note just written, and the
+// new last-seen times of the ones it repeated, forgetting those not seen
+// within the window. The repeated items' task lines in their own notes get
+// the last-seen date too. Encrypted notes' action items are not remembered,
+// so repeats never point to a note that cannot be read.
+func (p *Processor) rememberTasks(ctx context.Context, current *pipeline, merge *taskMerge, notePath string) {
+	if merge == nil {
+		return
+	}
+	for _, entry := range merge.seen {
+		marked, err := current.obsidianWriter.MarkTaskSeen(entry.NotePath, entry.Text, merge.now)
+		switch {
+		case err != nil:
+			logger.WarnContext(ctx, "failed to mark repeated action item", "note", entry.NotePath, "error", err)
+		case !marked:
+			logger.DebugContext(ctx, "repeated action item is no longer open in its note", "task", entry.Text, "note", entry.NotePath)
+		}
+	}
+	entries := merge.seen
+	if !strings.HasSuffix(notePath, obsidian.EncryptedExtension) {
+		for key, entry := range merge.fresh {
+			entry.NotePath = notePath
+			entries[key] = entry
+		}
+	}
+	if err := p.recentTasks.Update(entries, merge.now.Add(-current.config.Tasks.Dedupe.Window)); err != nil {
+		logger.WarnContext(ctx, "failed to record recent tasks", "error", err)
+	}
+}
+
+// embedTasks returns the embeddings of action items when
+// tasks.dedupe.embedding_model is set and the LLM provider can embed, or
+// nil, leaving the comparison to words
+func embedTasks(ctx context.Context, current *pipeline, texts []string) [][]float32 {
+	model := current.config.Tasks.Dedupe.EmbeddingModel
+	if model == "" {
+		return nil
+	}
+	embedder, ok := current.llmClient.(llm.Embedder)
+	if !ok {
+		logger.DebugContext(ctx, "LLM provider cannot embed; comparing action items by words", "provider", current.llmClient.GetProvider())
+		return nil
+	}
+	embeddings, err := embedder.Embed(ctx, model, texts)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to embed action items; comparing them by words", "error", err)
+		return nil
+	}
+	return embeddings
+}
+
+// findRepeat returns the key of the action item in candidates that text
+// repeats, and how it matched, or an empty match. The same normalized text
+// wins outright; otherwise the most similar words, then the most similar
+// embedding, above their thresholds.
+func findRepeat(candidates map[string]state.RecentTask, text string, embedding []float32, dedupe config.TaskDedupeConfig) (string, string) {
+	normalized := tasks.Normalize(text)
+	tokens := tasks.Tokens(text)
+
+	// Sorted so ties resolve the same way every time
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bestWords, bestWordsKey := 0.0, ""
+	bestEmbedding, bestEmbeddingKey := 0.0, ""
+	for _, key := range keys {
+		candidate := candidates[key]
+		if tasks.Normalize(candidate.Text) == normalized {
+			return key, matchExact
+		}
+		if similarity := tasks.Jaccard(tokens, tasks.Tokens(candidate.Text)); similarity >= dedupe.Similarity && similarity > bestWords {
+			bestWords, bestWordsKey = similarity, key
+		}
+		if similarity := tasks.Cosine(embedding, candidate.Embedding); similarity >= dedupe.EmbeddingSimilarity && similarity > bestEmbedding {
+			bestEmbedding, bestEmbeddingKey = similarity, key
+		}
+	}
+
+	switch {
+	case bestWordsKey != "":
+		return bestWordsKey, matchWords
+	case bestEmbeddingKey != "":
+		return bestEmbeddingKey, matchEmbedding
+	}
+	return "", ""
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"testing"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+func TestFindRepeat(t *testing.T) {
+	dedupe := config.TaskDedupeConfig{Similarity: 0.6, EmbeddingSimilarity: 0.9}
+	candidates := map[string]state.RecentTask{
+		"reply": {Text: "Reply to Anna's email about the Q3 budget", Embedding: []float32{1, 0, 0}},
+		"book":  {Text: "Book flights to Berlin", Embedding: []float32{0, 1, 0}},
+		"call":  {Text: "Call the dentist", Embedding: []float32{0, 0, 1}},
+	}
+
+	tests := []struct {
+		name      string
+		text      string
+		embedding []float32
+		wantKey   string
+		wantMatch string
+	}{
+		{
+			name:      "same text ignoring case and punctuation",
+			text:      "book flights to berlin!",
+			wantKey:   "book",
+			wantMatch: matchExact,
+		},
+		{
+			name:      "shared words with plurals and stop words",
+			text:      "Reply to the emails from Anna on Q3 budget",
+			wantKey:   "reply",
+			wantMatch: matchWords,
+		},
+		{
+			name:      "too few shared words",
+			text:      "Book a table in Berlin",
+			wantKey:   "",
+			wantMatch: "",
+		},
+		{
+			name:      "similar embedding",
+			text:      "Make a dental appointment",
+			embedding: []float32{0.05, 0, 1},
+			wantKey:   "call",
+			wantMatch: matchEmbedding,
+		},
+		{
+			name:      "dissimilar embedding",
+			text:      "Make a dental appointment",
+			embedding: []float32{0.7, 0.7, 0},
+			wantKey:   "",
+			wantMatch: "",
+		},
+		{
+			name:      "embedding of another size",
+			text:      "Make a dental appointment",
+			embedding: []float32{0, 1},
+			wantKey:   "",
+			wantMatch: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, match := findRepeat(candidates, test.text, test.embedding, dedupe)
+			if key != test.wantKey || match != test.wantMatch {
+				t.Errorf("findRepeat(%q) = %q, %q, want %q, %q", test.text, key, match, test.wantKey, test.wantMatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/deerflow"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+// deliverIdle is how long the delivery loop sleeps with nothing to deliver,
+// or while Deerflow is disabled
+const deliverIdle = time.Minute
+
+// notifyDeerflow queues a note's payload for the Deerflow webhook when the
+// note passes the tag and type filters. Encrypted notes are never sent.
+func (p *Processor) notifyDeerflow(ctx context.Context, current *pipeline, notePath string, result *llm.ProcessingResult, reprocessed bool) {
+	webhook := current.deerflow
+	if webhook == nil {
+		return
+	}
+	if strings.HasSuffix(notePath, obsidian.EncryptedExtension) {
+		logger.DebugContext(ctx, "not sending encrypted note to Deerflow", "note", notePath)
+		return
+	}
+	tags := obsidian.Tags(result)
+	if !webhook.Matches(tags, result.Metadata.SourceType) {
+		logger.DebugContext(ctx, "note does not match the Deerflow filters", "note", notePath, "source_type", result.Metadata.SourceType)
+		return
+	}
+
+	cfg := current.config
+	payload := deerflow.NotePayload(result, deerflow.Note{
+		Path:        current.obsidianWriter.RelativePath(notePath),
+		URI:         current.obsidianWriter.NoteURI(notePath, cfg.Tasks.VaultName),
+		Tags:        tags,
+		SourceFile:  result.Metadata.SourceFile,
+		SourceType:  result.Metadata.SourceType,
+		ProcessedAt: result.Metadata.ProcessedAt,
+		Reprocessed: reprocessed,
+	}, cfg.Deerflow.WorkflowID)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to encode Deerflow payload", "error", err)
+		return
+	}
+
+	delivery := deerflow.NewDelivery(deerflow.EventNoteProcessed, data, time.Now())
+	if err := p.outbox.Put(delivery); err != nil {
+		// The delivery stays pending in memory; only a crash would lose it
+		logger.WarnContext(ctx, "failed to persist Deerflow outbox", "error", err)
+	}
+	logger.DebugContext(ctx, "queued Deerflow delivery", "delivery", delivery.ID, "note", notePath)
+	select {
+	case p.deliverNow <- struct{}{}:
+	default:
+	}
+}
+
+// deliverLoop sends pending Deerflow deliveries as they fall due
+func (p *Processor) deliverLoop(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Deerflow delivery recovered from panic", "panic", r)
+		}
+	}()
+
+	if pending := p.outbox.Len(); pending > 0 {
+		logger.Info("resuming Deerflow deliveries", "deliveries", pending)
+	}
+
+	for {
+		wait := deliverIdle
+		if p.pipeline().deerflow != nil {
+			p.deliverDue(ctx)
+			if next, ok := p.outbox.Next(); ok && time.Until(next) < wait {
+				wait = time.Until(next)
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-p.deliverNow:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverDue makes an attempt at every delivery that is due
+func (p *Processor) deliverDue(ctx context.Context) {
+	webhook := p.pipeline().deerflow
+	if webhook == nil {
+		return
+	}
+	for _, delivery := range p.outbox.Due(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+		p.deliver(ctx, webhook, delivery)
+	}
+}
+
+// deliver makes one attempt at a delivery. Failures are retried with a
+// growing delay until deerflow.max_attempts; refusals other than rate
+// limits and server errors are not retried.
+func (p *Processor) deliver(ctx context.Context, webhook *deerflow.Webhook, delivery state.Delivery) {
+	delivery.Attempts++
+	start := time.Now()
+	status, err := webhook.Send(ctx, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown; the attempt is made again after the restart
+		return
+	}
+
+	record := deerflow.LogRecord{
+		Timestamp: start,
+		Delivery:  delivery.ID,
+		Event:     delivery.Event,
+		Attempt:   delivery.Attempts,
+		Status:    status,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	switch {
+	case err == nil:
+		record.Outcome = deerflow.OutcomeDelivered
+		logger.InfoContext(ctx, "delivered to Deerflow", "delivery", delivery.ID, "attempt", delivery.Attempts)
+		err = p.outbox.Remove(delivery.ID)
+	case deerflow.Retryable(err) && delivery.Attempts < webhook.MaxAttempts():
+		record.Outcome = deerflow.OutcomeRetrying
+		record.Error = err.Error()
+		delivery.LastError = err.Error()
+		delivery.NextAttempt = time.Now().Add(webhook.RetryDelay(delivery.Attempts))
+		record.NextAttempt = &delivery.NextAttempt
+		logger.WarnContext(ctx, "Deerflow delivery failed, retrying", "delivery", delivery.ID, "attempt", delivery.Attempts, "next_attempt", delivery.NextAttempt.Format(time.RFC3339), "error", err)
+		err = p.outbox.Put(delivery)
+	default:
+		record.Outcome = deerflow.OutcomeFailed
+		record.Error = err.Error()
+		logger.ErrorContext(ctx, "Deerflow delivery failed, giving up", "delivery", delivery.ID, "attempts", delivery.Attempts, "error", err)
+		err = p.outbox.Remove(delivery.ID)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "failed to persist Deerflow outbox", "error", err)
+	}
+
+	metrics.DeerflowDeliveries.With(record.Outcome).Inc()
+	if err := p.deliveryLog.Record(record); err != nil {
+		logger.WarnContext(ctx, "failed to write Deerflow delivery log", "error", err)
+	}
+}
+
+// DeerflowHandler serves Deerflow's inbound requests, signed with the
+// secret of the configuration in effect
+func (p *Processor) DeerflowHandler() http.Handler {
+	return deerflow.Handler(func() *deerflow.Webhook { return p.pipeline().deerflow }, p)
+}
+
+// Reprocess implements deerflow.Actions. Only sources recorded in the
+// ledger can be reprocessed, so Deerflow cannot point the bridge at other
+// files.
+func (p *Processor) Reprocess(ctx context.Context, note, source string) (string, error) {
+	if source == "" {
+		notePath, err := p.resolveNote(note)
+		if err != nil {
+			return "", err
+		}
+		var found bool
+		if source, found = p.SourceForNote(notePath); !found {
+			return "", fmt.Errorf("no source recorded for note %s: %w", note, deerflow.ErrNotFound)
+		}
+	} else if _, found := p.ledger.Lookup(source); !found {
+		return "", fmt.Errorf("source %s was never processed: %w", source, deerflow.ErrNotFound)
+	}
+	if _, err := os.Stat(source); err != nil {
+		return "", fmt.Errorf("source %s is gone: %w", source, deerflow.ErrNotFound)
+	}
+
+	correlationID := logging.NewCorrelationID()
+	p.enqueueItem(logging.WithCorrelationID(ctx, correlationID), state.QueueItem{
+		Path:          source,
+		CorrelationID: correlationID,
+		QueuedAt:      time.Now(),
+		Reprocess:     true,
+	})
+	return source, nil
+}
+
+// CompleteTask implements deerflow.Actions. An action item exported to task
+// managers is found by its key and no longer followed there.
+func (p *Processor) CompleteTask(ctx context.Context, task deerflow.TaskRef) error {
+	notePath, text := "", task.Text
+	export, exported := p.exports.Lookup(task.Key)
+	switch {
+	case task.Key != "" && exported:
+		if export.Done {
+			return nil
+		}
+		notePath, text = export.NotePath, export.Text
+	case task.Note != "" && text != "":
+		var err error
+		if notePath, err = p.resolveNote(task.Note); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown task key %s: %w", task.Key, deerflow.ErrNotFound)
+	}
+
+	ticked, err := p.pipeline().obsidianWriter.CompleteTask(notePath, text)
+	if err != nil {
+		return fmt.Errorf("failed to tick task: %w", err)
+	}
+	if !ticked {
+		return fmt.Errorf("no open task %q in %s: %w", text, p.pipeline().obsidianWriter.RelativePath(notePath), deerflow.ErrNotFound)
+	}
+	logger.InfoContext(ctx, "ticked task completed in Deerflow", "note", notePath)
+	metrics.TasksCompleted.With("deerflow").Inc()
+
+	if exported {
+		export.Done = true
+		if err := p.exports.Record(task.Key, export); err != nil {
+			logger.WarnContext(ctx, "failed to record exported task", "error", err)
+		}
+	}
+	return nil
+}
+
+// resolveNote turns a vault-relative note path, with or without extension,
+// into the note's path, refusing paths outside the vault
+func (p *Processor) resolveNote(note string) (string, error) {
+	vault := p.Config().Obsidian.VaultPath
+	notePath := filepath.Join(vault, filepath.FromSlash(note))
+	if rel, err := filepath.Rel(vault, notePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("note %s is outside the vault: %w", note, deerflow.ErrNotFound)
+	}
+	if !strings.HasSuffix(notePath, ".md") && !strings.HasSuffix(notePath, obsidian.EncryptedExtension) {
+		notePath += ".md"
+	}
+	if _, err := os.Stat(notePath); err != nil {
+		return "", fmt.Errorf("note %s: %w", note, deerflow.ErrNotFound)
+	}
+	return notePath, nil
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/vision"
+)
+
+// readImage recognizes the text in a screenshot and, when a vision provider
+// is configured, asks the model to describe what OCR cannot see. The privacy
+// rules are decided on the time the screenshot was taken before either, so
+// an excluded screenshot is neither read nor uploaded. Offline, the vision
+// request is listed instead of made and a placeholder stands in for the
+// description.
+func (p *pipeline) readImage(ctx context.Context, path string, offline bool) (*extract.Result, map[string]int, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if _, excluded := p.privacyFilter.Apply([]extract.Item{{CapturedAt: info.ModTime()}}); len(excluded) > 0 {
+		return &extract.Result{Path: path, Type: "image"}, excluded, nil, nil
+	}
+
+	text := ""
+	if p.ocr != nil {
+		ocrCtx, cancel := context.WithTimeout(ctx, p.config.OCR.Timeout)
+		text, err = p.ocr.Recognize(ocrCtx, path)
+		cancel()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	description := ""
+	var calls []string
+	if p.vision != nil {
+		img, err := vision.Prepare(path, p.config.Vision.MaxDimension, p.config.Vision.MaxUploadKB<<10)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if offline {
+			calls = append(calls, fmt.Sprintf("vision description of %s by %s %s (%dx%d, %d bytes)",
+				filepath.Base(path), p.vision.Name(), p.config.Vision.Model, img.Width, img.Height, len(img.Data)))
+			description = fmt.Sprintf("[description of %s]", filepath.Base(path))
+		} else {
+			described, err := p.vision.Describe(audit.WithSourceFile(ctx, path), img)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			description = described.Text
+		}
+	}
+
+	extracted, err := extract.Image(path, text, description)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return extracted, map[string]int{}, calls, nil
+}
+
+// imageAvailable returns why screenshots cannot be read, or nil if OCR or a
+// vision model can read them
+func (p *pipeline) imageAvailable() error {
+	if p.ocr == nil && p.vision == nil {
+		return fmt.Errorf("%w, and vision.provider is not set", p.ocrMissing)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/vision"
+)
+
+// countingDescriber describes every screenshot the same way and counts the
+// screenshots sent
+type countingDescriber struct {
+	sent int
+}
+
+func (c *countingDescriber) Describe(ctx context.Context, img *vision.Image) (*vision.Description, error) {
+	c.sent++
+	return &vision.Description{Text: "A Gmail inbox."}, nil
+}
+
+func (c *countingDescriber) Name() string {
+	return "stub"
+}
+
+func TestReadImage(t *testing.T) {
+	filter, err := privacy.New(&config.PrivacyConfig{
+		Rules: []config.PrivacyRule{{Name: "evenings", Action: "exclude", TimeRanges: []string{"18:00-23:59"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(hour int) time.Time {
+		return time.Date(2025, 1, 15, hour, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		taken    time.Time
+		offline  bool
+		sent     int
+		items    []string
+		excluded map[string]int
+		calls    int
+	}{
+		{
+			name:     "kept",
+			taken:    day(10),
+			sent:     1,
+			items:    []string{"A Gmail inbox."},
+			excluded: map[string]int{},
+		},
+		{
+			name:     "excluded before upload",
+			taken:    day(20),
+			excluded: map[string]int{"evenings": 1},
+		},
+		{
+			name:     "offline",
+			taken:    day(10),
+			offline:  true,
+			items:    []string{"[description of capture.png]"},
+			excluded: map[string]int{},
+			calls:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.png")
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := png.Encode(file, image.NewGray(image.Rect(0, 0, 64, 48))); err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+			if err := os.Chtimes(path, test.taken, test.taken); err != nil {
+				t.Fatal(err)
+			}
+
+			describer := &countingDescriber{}
+			p := &pipeline{config: &config.Config{}, privacyFilter: filter, vision: describer}
+			p.config.Vision = config.VisionConfig{Model: "gpt-4o", MaxDimension: 1568, MaxUploadKB: 512}
+			extracted, excluded, calls, err := p.extract(context.Background(), path, test.offline)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if describer.sent != test.sent {
+				t.Errorf("sent %d screenshots, want %d", describer.sent, test.sent)
+			}
+			var items []string
+			for _, item := range extracted.Items {
+				if item.Label != extract.LabelDescription {
+					t.Errorf("unexpected item %q", item.Label)
+				}
+				items = append(items, item.Text)
+			}
+			if !reflect.DeepEqual(items, test.items) {
+				t.Errorf("items %q, want %q", items, test.items)
+			}
+			if !reflect.DeepEqual(excluded, test.excluded) {
+				t.Errorf("excluded %v, want %v", excluded, test.excluded)
+			}
+			if len(calls) != test.calls {
+				t.Errorf("calls %q, want %d", calls, test.calls)
+			}
+		})
+	}
+}
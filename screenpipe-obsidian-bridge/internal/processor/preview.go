@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+// Preview is the content a file would send to the LLM
+type Preview struct {
+	Path string
+	Type string
+	// Content is the extracted text after privacy rules were applied, with
+	// placeholders for transcripts and descriptions not requested
+	Content string
+	// Calls are the transcription and vision requests that would be made
+	Calls []string
+	// Prompts are the prompts that would be sent, with API keys redacted
+	Prompts []llm.Prompt
+	// Excluded counts items dropped per privacy rule
+	Excluded map[string]int
+	// Skipped is the reason nothing would be sent, if any
+	Skipped string
+}
+
+// Previewer shows what processing files would send, for dry runs. It reads
+// files and applies the privacy rules and prompt templates like the
+// processor, but opens no state, audit log, vault or file watcher, needs no
+// encryption key and writes nothing.
+type Previewer struct {
+	current *pipeline
+}
+
+// NewPreviewer creates a previewer for cfg
+func NewPreviewer(cfg *config.Config) (*Previewer, error) {
+	keys, err := credentials.New(&cfg.LLM, &cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up LLM credentials: %w", err)
+	}
+	current, err := newReader(cfg, keys, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Previewer{current: current}, nil
+}
+
+// Config returns the configuration previewed with
+func (p *Previewer) Config() *config.Config {
+	return p.current.config
+}
+
+// Preview extracts a file and applies the privacy rules without calling the
+// LLM, transcription or vision services
+func (p *Previewer) Preview(ctx context.Context, filePath string) (*Preview, error) {
+	current := p.current
+	extracted, excluded, calls, err := current.extract(ctx, filePath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{
+		Path:     filePath,
+		Type:     extracted.Type,
+		Calls:    calls,
+		Excluded: excluded,
+	}
+	switch {
+	case len(extracted.Items) == 0 && len(excluded) > 0:
+		preview.Skipped = metrics.SkipPrivacy
+	default:
+		preview.Content = extracted.Text()
+		if preview.Content == "" {
+			preview.Skipped = metrics.SkipEmpty
+			break
+		}
+		for _, prompt := range current.templates.RenderAll(preview.Content) {
+			prompt.Text = credentials.Redact(prompt.Text)
+			preview.Prompts = append(preview.Prompts, prompt)
+		}
+	}
+	return preview, nil
+}
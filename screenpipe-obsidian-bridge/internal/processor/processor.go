@@ -3,28 +3,166 @@ package processor
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"screenpipe-obsidian-bridge/internal/audit"
 	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/deerflow"
+	"screenpipe-obsidian-bridge/internal/extract"
 	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
 	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/ocr"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/secure"
+	"screenpipe-obsidian-bridge/internal/state"
+	"screenpipe-obsidian-bridge/internal/tasks"
+	"screenpipe-obsidian-bridge/internal/transcribe"
+	"screenpipe-obsidian-bridge/internal/video"
+	"screenpipe-obsidian-bridge/internal/vision"
 	"screenpipe-obsidian-bridge/internal/watcher"
 )
 
+var logger = logging.For(logging.ComponentProcessor)
+
 // Processor orchestrates the main workflow
 type Processor struct {
+	// current is swapped as a whole when the configuration is reloaded;
+	// each file uses the pipeline that was current when it started
+	current atomic.Pointer[pipeline]
+	watcher *watcher.Watcher
+	ledger  *state.Ledger
+	// exports records the action items exported to task managers
+	exports *state.Exports
+	// recentTasks remembers recent action items to merge repeats into
+	recentTasks *state.RecentTasks
+	// outbox holds the Deerflow deliveries not made yet, attempted by the
+	// delivery loop when deliverNow wakes it or one falls due
+	outbox      *state.Outbox
+	deliverNow  chan struct{}
+	deliveryLog *deerflow.DeliveryLog
+	auditLog    *audit.Logger
+	codec       *secure.Codec
+
+	// queue persists the files waiting to be processed; queued wakes the
+	// file processor and reconcileNow asks for a reconciliation scan
+	queue        *state.Queue
+	queued       chan struct{}
+	reconcileNow chan struct{}
+
+	// Processing state
+	processingMutex sync.Mutex
+	isProcessing    map[string]bool
+	excludedByRule  map[string]int
+	processedFiles  int
+	failedFiles     int
+	startedAt       time.Time
+	// seen maps files the watcher reported to their modification time, so
+	// reconciliation only queues files it missed
+	seen map[string]time.Time
+	// behind is set while the queue is over its alert thresholds
+	behind bool
+}
+
+// pipeline holds the parts of the processor that a reload replaces
+type pipeline struct {
 	config         *config.Config
-	watcher        *watcher.Watcher
 	llmClient      llm.Client
+	templates      llm.PromptTemplates
 	obsidianWriter *obsidian.Writer
-	
-	// Processing state
-	processQueue   chan string
-	processingMutex sync.Mutex
-	isProcessing   map[string]bool
+	privacyFilter  *privacy.Filter
+	// transcriber is nil when transcription is disabled
+	transcriber transcribe.Transcriber
+	video       *video.Extractor
+	// ocr is nil when tesseract is missing, with ocrMissing saying why;
+	// vision is nil when no vision provider is configured
+	ocr        *ocr.Tesseract
+	ocrMissing error
+	vision     vision.Describer
+	// sinks are the task managers action items are exported to
+	sinks []tasks.Sink
+	// deerflow is nil when Deerflow is disabled
+	deerflow *deerflow.Webhook
+}
+
+// newPipeline builds the reloadable parts of the processor from cfg
+func newPipeline(cfg *config.Config, auditLog *audit.Logger, codec *secure.Codec) (*pipeline, error) {
+	// Create the API key provider queried by the LLM client on every request
+	keys, err := credentials.New(&cfg.LLM, &cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up LLM credentials: %w", err)
+	}
+
+	current, err := newReader(cfg, keys, auditLog)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create LLM client based on provider
+	if current.llmClient, err = llm.NewClient(&cfg.LLM, keys, auditLog); err != nil {
+		return nil, err
+	}
+
+	// Task managers authenticate with their own credentials
+	if current.sinks, err = tasks.New(&cfg.Tasks, &cfg.Security); err != nil {
+		return nil, fmt.Errorf("failed to set up task sinks: %w", err)
+	}
+
+	// The Deerflow webhook signs with its own secret
+	if current.deerflow, err = deerflow.New(&cfg.Deerflow, &cfg.Security); err != nil {
+		return nil, fmt.Errorf("failed to set up Deerflow: %w", err)
+	}
+
+	current.obsidianWriter = obsidian.New(&cfg.Obsidian, &cfg.Security.Encryption, codec)
+	return current, nil
+}
+
+// newReader builds the parts of a pipeline that read files and turn them
+// into prompts: extraction, transcription, vision, privacy rules and prompt
+// templates. None of them writes anything.
+func newReader(cfg *config.Config, keys credentials.Provider, auditLog *audit.Logger) (*pipeline, error) {
+	templates, err := llm.LoadPromptTemplates(&cfg.LLM)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the transcriber for audio files, which shares the LLM keys
+	transcriber, err := transcribe.New(&cfg.Transcription, &cfg.LLM, keys, auditLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up transcription: %w", err)
+	}
+
+	// Video needs ffmpeg and tesseract; without them recordings are skipped
+	videoExtractor := video.New(&cfg.Video, &cfg.OCR)
+
+	// Screenshots are read with tesseract, a vision model or both
+	tesseract, ocrMissing := ocr.NewTesseract(&cfg.OCR)
+	describer, err := vision.New(&cfg.Vision, &cfg.LLM, keys, &cfg.Security, auditLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up vision: %w", err)
+	}
+
+	// Compile privacy rules
+	privacyFilter, err := privacy.New(&cfg.Privacy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile privacy rules: %w", err)
+	}
+
+	return &pipeline{
+		config:        cfg,
+		templates:     templates,
+		privacyFilter: privacyFilter,
+		transcriber:   transcriber,
+		video:         videoExtractor,
+		ocr:           tesseract,
+		ocrMissing:    ocrMissing,
+		vision:        describer,
+	}, nil
 }
 
 // New creates a new processor
@@ -35,41 +173,121 @@ func New(cfg *config.Config) (*Processor, error) {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
-	// Create LLM client based on provider
-	var llmClient llm.Client
-	switch cfg.LLM.Provider {
-	case "openai":
-		llmClient = llm.NewOpenAIClient(&cfg.LLM)
-	default:
-		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.LLM.Provider)
+	// Open the request audit log (nil when request logging is disabled)
+	auditLog, err := audit.New(&cfg.Security)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up at-rest encryption for persisted state and selected notes
+	codec, err := secure.New(&cfg.Security.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up encryption: %w", err)
+	}
+
+	// Open the processed-file ledger
+	ledger, err := state.OpenLedger(cfg.Processing.StateDir, codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+
+	// Open the pending queue, with any files left from the last run
+	queue, err := state.OpenQueue(cfg.Processing.StateDir, codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue: %w", err)
+	}
+
+	// Open the record of exported action items
+	exports, err := state.OpenExports(cfg.Processing.StateDir, codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open exports: %w", err)
+	}
+
+	// Open the index of recent action items
+	recentTasks, err := state.OpenRecentTasks(cfg.Processing.StateDir, codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recent tasks: %w", err)
 	}
 
-	// Create Obsidian writer
-	obsidianWriter := obsidian.New(&cfg.Obsidian)
+	// Open the Deerflow deliveries left from the last run
+	outbox, err := state.OpenOutbox(cfg.Processing.StateDir, codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Deerflow outbox: %w", err)
+	}
+
+	// Create the LLM client, Obsidian writer and privacy filter
+	current, err := newPipeline(cfg, auditLog, codec)
+	if err != nil {
+		return nil, err
+	}
 
 	processor := &Processor{
-		config:         cfg,
 		watcher:        fileWatcher,
-		llmClient:      llmClient,
-		obsidianWriter: obsidianWriter,
-		processQueue:   make(chan string, cfg.Processing.BatchSize*2),
+		ledger:         ledger,
+		exports:        exports,
+		recentTasks:    recentTasks,
+		outbox:         outbox,
+		deliverNow:     make(chan struct{}, 1),
+		deliveryLog:    deerflow.NewDeliveryLog(cfg.Deerflow.DeliveryLog),
+		auditLog:       auditLog,
+		codec:          codec,
+		queue:          queue,
+		queued:         make(chan struct{}, 1),
+		reconcileNow:   make(chan struct{}, 1),
 		isProcessing:   make(map[string]bool),
+		excludedByRule: make(map[string]int),
+		startedAt:      time.Now(),
+		seen:           make(map[string]time.Time),
 	}
+	processor.current.Store(current)
 
 	return processor, nil
 }
 
+// pipeline returns the pipeline in effect
+func (p *Processor) pipeline() *pipeline {
+	return p.current.Load()
+}
+
+// Reload applies a new configuration to the running processor. Everything is
+// built before anything is swapped, so an error leaves the running pipeline
+// untouched. Settings that need a restart keep their running values.
+func (p *Processor) Reload(cfg *config.Config) error {
+	running := p.pipeline()
+	cfg.KeepRestartSettings(running.config)
+
+	next, err := newPipeline(cfg, p.auditLog, p.codec)
+	if err != nil {
+		return err
+	}
+	if err := next.obsidianWriter.CreateVaultStructure(); err != nil {
+		return fmt.Errorf("failed to create vault structure: %w", err)
+	}
+	if err := p.watcher.Reconfigure(&cfg.ScreenPipe); err != nil {
+		return err
+	}
+
+	p.current.Store(next)
+	logger.Info("applied new configuration",
+		"screenpipe_output", cfg.ScreenPipe.OutputPath,
+		"obsidian_vault", cfg.Obsidian.VaultPath,
+		"llm_provider", cfg.LLM.Provider,
+		"llm_model", cfg.LLM.Model)
+	return nil
+}
+
 // Start begins the processing workflow
 func (p *Processor) Start(ctx context.Context) error {
-	log.Println("🚀 Starting ScreenPipe Obsidian Bridge...")
+	logger.Info("starting ScreenPipe Obsidian Bridge")
+	current := p.pipeline()
 
 	// Validate Obsidian vault
-	if err := p.obsidianWriter.ValidateVaultPath(); err != nil {
-		log.Printf("Warning: %v", err)
+	if err := current.obsidianWriter.ValidateVaultPath(); err != nil {
+		logger.Warn("vault validation failed", "error", err)
 	}
 
 	// Create vault structure
-	if err := p.obsidianWriter.CreateVaultStructure(); err != nil {
+	if err := current.obsidianWriter.CreateVaultStructure(); err != nil {
 		return fmt.Errorf("failed to create vault structure: %w", err)
 	}
 
@@ -81,20 +299,32 @@ func (p *Processor) Start(ctx context.Context) error {
 	// Start processing goroutines
 	go p.handleFileEvents(ctx)
 	go p.processFiles(ctx)
+	go p.reconcileLoop(ctx)
+	go p.syncTasksLoop(ctx)
+	go p.deliverLoop(ctx)
 
-	log.Printf("✅ Successfully started monitoring:")
-	log.Printf("   📂 ScreenPipe Output: %s", p.config.ScreenPipe.OutputPath)
-	log.Printf("   📝 Obsidian Vault: %s", p.config.Obsidian.VaultPath)
-	log.Printf("   🤖 LLM Provider: %s (%s)", p.config.LLM.Provider, p.config.LLM.Model)
+	logger.Info("started monitoring",
+		"screenpipe_output", current.config.ScreenPipe.OutputPath,
+		"obsidian_vault", current.config.Obsidian.VaultPath,
+		"llm_provider", current.config.LLM.Provider,
+		"llm_model", current.config.LLM.Model)
 
 	return nil
 }
 
 // Stop stops the processor
 func (p *Processor) Stop() error {
-	log.Println("🛑 Stopping ScreenPipe Obsidian Bridge...")
-	
-	close(p.processQueue)
+	logger.Info("stopping ScreenPipe Obsidian Bridge")
+
+	if err := p.auditLog.Close(); err != nil {
+		logger.Warn("failed to close audit log", "error", err)
+	}
+	if err := p.deliveryLog.Close(); err != nil {
+		logger.Warn("failed to close Deerflow delivery log", "error", err)
+	}
+	if err := p.queue.Close(); err != nil {
+		logger.Warn("failed to persist queue", "error", err)
+	}
 	return p.watcher.Stop()
 }
 
@@ -102,109 +332,134 @@ func (p *Processor) Stop() error {
 func (p *Processor) handleFileEvents(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("File event handler recovered from panic: %v", r)
+			logger.Error("file event handler recovered from panic", "panic", r)
 		}
 	}()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("File event handler context cancelled")
+			logger.Debug("file event handler context cancelled")
 			return
 
 		case event, ok := <-p.watcher.Events():
 			if !ok {
-				log.Println("File events channel closed")
+				logger.Debug("file events channel closed")
 				return
 			}
 
 			// Check if we should process this file
-			if p.shouldProcessFile(event.Path) {
-				select {
-				case p.processQueue <- event.Path:
-					log.Printf("📋 Queued file for processing: %s", event.Path)
-				case <-ctx.Done():
-					return
-				default:
-					log.Printf("⚠️ Process queue full, skipping file: %s", event.Path)
-				}
+			eventCtx := logging.WithCorrelationID(ctx, event.CorrelationID)
+			metrics.FilesDetected.With(extract.TypeOf(event.Path)).Inc()
+			p.markSeen(event.Path)
+			if p.skipReason(eventCtx, event.Path) == "" {
+				p.enqueue(eventCtx, event.Path, event.CorrelationID)
 			}
 
 		case err, ok := <-p.watcher.Errors():
 			if !ok {
-				log.Println("File watcher errors channel closed")
+				logger.Debug("file watcher errors channel closed")
 				return
 			}
-			log.Printf("❌ File watcher error: %v", err)
+			metrics.WatcherErrors.With().Inc()
+			logger.Error("file watcher error", "error", err)
+			if watcher.IsOverflow(err) {
+				// Events were lost; look for the files they were about
+				p.requestReconcile()
+			}
 		}
 	}
 }
 
-// processFiles handles the file processing queue
-func (p *Processor) processFiles(ctx context.Context) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("File processor recovered from panic: %v", r)
-		}
-	}()
-
-	// Batch processing with delay
-	batch := make([]string, 0, p.config.Processing.BatchSize)
-	ticker := time.NewTicker(time.Duration(p.config.Processing.BatchDelay) * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("File processor context cancelled")
-			return
-
-		case filePath, ok := <-p.processQueue:
-			if !ok {
-				// Process any remaining files in batch
-				if len(batch) > 0 {
-					p.processBatch(ctx, batch)
-				}
-				log.Println("Process queue closed")
-				return
-			}
-
-			batch = append(batch, filePath)
+// Options adjust how a single file is processed
+type Options struct {
+	// Reprocess ignores the ledger cache and rewrites the source's existing
+	// note in place with the current prompts and template
+	Reprocess bool
+}
 
-			// Process batch if it's full
-			if len(batch) >= p.config.Processing.BatchSize {
-				p.processBatch(ctx, batch)
-				batch = batch[:0] // Reset batch
-			}
+// Outcome describes what happened to one source file
+type Outcome struct {
+	Path string
+	// NotePath is the note written for the file, empty when skipped
+	NotePath string
+	// Skipped is the reason the file produced no note, e.g. "unchanged"
+	Skipped string
+}
 
-		case <-ticker.C:
-			// Process batch on timer if not empty
-			if len(batch) > 0 {
-				p.processBatch(ctx, batch)
-				batch = batch[:0] // Reset batch
-			}
-		}
+// ProcessFile runs one file through the pipeline outside the watch loop, for
+// the once, backfill and reprocess commands. Without the watcher to wait for
+// files to settle, files changed within the quiet period are skipped for a
+// later run.
+func (p *Processor) ProcessFile(ctx context.Context, filePath string, opts Options) (Outcome, error) {
+	ctx = logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+	fileType := extract.TypeOf(filePath)
+	metrics.FilesDetected.With(fileType).Inc()
+
+	if reason := p.skipReason(ctx, filePath); reason != "" {
+		return Outcome{Path: filePath, Skipped: reason}, nil
+	}
+	if info, err := os.Stat(filePath); err == nil && time.Since(info.ModTime()) < p.Config().ScreenPipe.QuietPeriod {
+		metrics.FilesSkipped.With(fileType, metrics.SkipTooNew).Inc()
+		logger.InfoContext(ctx, "skipping file that is still changing", "path", filePath)
+		return Outcome{Path: filePath, Skipped: metrics.SkipTooNew}, nil
 	}
+	return p.processFile(ctx, filePath, opts)
 }
 
-// processBatch processes a batch of files
-func (p *Processor) processBatch(ctx context.Context, filePaths []string) {
-	log.Printf("🔄 Processing batch of %d files", len(filePaths))
+// extract reads a file, transcribing audio and describing screenshots, and
+// drops the items excluded by privacy rules. Offline, no transcription or
+// vision requests are made; the ones that would be are returned instead.
+func (p *pipeline) extract(ctx context.Context, filePath string, offline bool) (*extract.Result, map[string]int, []string, error) {
+	var extracted *extract.Result
+	var excluded map[string]int
+	var calls []string
+	var err error
+	switch extract.TypeOf(filePath) {
+	case "audio":
+		if extracted, excluded, calls, err = p.readAudio(ctx, filePath, offline); err != nil {
+			return nil, nil, nil, err
+		}
+	case "video":
+		recording, err := p.video.Extract(ctx, filePath, p.attaches("video"))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if extracted, err = extract.Video(filePath, recording); err != nil {
+			return nil, nil, nil, err
+		}
+	case "image":
+		if extracted, excluded, calls, err = p.readImage(ctx, filePath, offline); err != nil {
+			return nil, nil, nil, err
+		}
+	default:
+		if extracted, err = extract.File(filePath); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 
-	for _, filePath := range filePaths {
-		if err := p.processFile(ctx, filePath); err != nil {
-			log.Printf("❌ Failed to process file %s: %v", filePath, err)
+	kept, dropped := p.privacyFilter.Apply(extracted.Items)
+	extracted.Items = kept
+	if excluded == nil {
+		excluded = dropped
+	} else {
+		for rule, count := range dropped {
+			excluded[rule] += count
 		}
 	}
+	return extracted, excluded, calls, nil
 }
 
 // processFile processes a single file
-func (p *Processor) processFile(ctx context.Context, filePath string) error {
+func (p *Processor) processFile(ctx context.Context, filePath string, opts Options) (Outcome, error) {
+	outcome := Outcome{Path: filePath}
+
 	// Check if already processing this file
 	p.processingMutex.Lock()
 	if p.isProcessing[filePath] {
 		p.processingMutex.Unlock()
-		return nil // Skip if already processing
+		outcome.Skipped = "in progress"
+		return outcome, nil // Skip if already processing
 	}
 	p.isProcessing[filePath] = true
 	p.processingMutex.Unlock()
@@ -215,63 +470,192 @@ func (p *Processor) processFile(ctx context.Context, filePath string) error {
 		p.processingMutex.Unlock()
 	}()
 
-	log.Printf("🔍 Processing file: %s", filePath)
+	logger.InfoContext(ctx, "processing file", "path", filePath)
+	fileType := extract.TypeOf(filePath)
+	current := p.pipeline()
 
-	// Read file content
-	content, err := os.ReadFile(filePath)
+	// Extract content, dropping items excluded by privacy rules before
+	// anything reaches the LLM
+	extractStart := time.Now()
+	extracted, excluded, _, err := current.extract(ctx, filePath, false)
+	metrics.ExtractionDuration.With(fileType).Observe(time.Since(extractStart).Seconds())
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		stage := metrics.StageExtract
+		if fileType == "audio" {
+			stage = metrics.StageTranscribe
+		}
+		metrics.FilesFailed.With(fileType, stage).Inc()
+		p.recordFailure()
+		return outcome, err
 	}
 
+	if len(excluded) > 0 {
+		p.recordExcluded(excluded)
+	}
+	if len(extracted.Items) == 0 && len(excluded) > 0 {
+		metrics.FilesSkipped.With(fileType, metrics.SkipPrivacy).Inc()
+		logger.InfoContext(ctx, "skipping file excluded by privacy rules", "path", filePath)
+		outcome.Skipped = metrics.SkipPrivacy
+		return outcome, nil
+	}
+
+	content := extracted.Text()
+
 	// Skip empty files
 	if len(content) == 0 {
-		log.Printf("⏭️ Skipping empty file: %s", filePath)
-		return nil
+		metrics.FilesSkipped.With(fileType, metrics.SkipEmpty).Inc()
+		logger.InfoContext(ctx, "skipping empty file", "path", filePath)
+		outcome.Skipped = metrics.SkipEmpty
+		return outcome, nil
+	}
+
+	// Skip files whose content was already turned into a note
+	contentHash := state.HashContent([]byte(content))
+	if !opts.Reprocess && p.ledger.Unchanged(filePath, contentHash) {
+		metrics.FilesSkipped.With(fileType, metrics.SkipUnchanged).Inc()
+		logger.InfoContext(ctx, "skipping unchanged file", "path", filePath)
+		outcome.Skipped = metrics.SkipUnchanged
+		return outcome, nil
 	}
 
 	// Process with LLM
-	result, err := p.llmClient.ProcessContent(ctx, string(content), filePath)
+	result, err := current.llmClient.ProcessContent(audit.WithExcludedItems(ctx, countItems(excluded)), content, filePath)
+	if err != nil {
+		metrics.FilesFailed.With(fileType, metrics.StageLLM).Inc()
+		p.recordFailure()
+		return outcome, fmt.Errorf("failed to process content with LLM: %w", err)
+	}
+	result.Metadata.SourceType = extracted.Type
+	if extracted.Duration > 0 {
+		result.Metadata.Duration = extracted.Duration.Round(time.Second).String()
+	}
+	result.Metadata.Language = extracted.Language
+	result.Metadata.Resolution = extracted.Resolution
+	result.Metadata.Attachments = current.attachmentsFor(ctx, extracted, excluded)
+	detectDueDates(result, time.Now())
+
+	// Write to Obsidian, replacing the previous note when reprocessing
+	var notePath string
+	previous, found := p.ledger.Lookup(filePath)
+	if opts.Reprocess && found && previous.NotePath != "" {
+		notePath = previous.NotePath
+	}
+	merge := p.mergeRepeatedTasks(ctx, current, result, notePath)
+	if notePath != "" {
+		err = current.obsidianWriter.ReplaceNote(ctx, notePath, result)
+	} else {
+		notePath, err = current.obsidianWriter.WriteNote(ctx, result)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to process content with LLM: %w", err)
+		metrics.NoteWriteErrors.With().Inc()
+		metrics.FilesFailed.With(fileType, metrics.StageWrite).Inc()
+		p.recordFailure()
+		return outcome, fmt.Errorf("failed to write Obsidian note: %w", err)
+	}
+	outcome.NotePath = notePath
+	p.rememberTasks(ctx, current, merge, notePath)
+
+	// Record the note in the ledger
+	if err := p.ledger.Record(filePath, state.LedgerEntry{
+		ContentHash: contentHash,
+		NotePath:    notePath,
+		ProcessedAt: time.Now(),
+	}); err != nil {
+		logger.WarnContext(ctx, "failed to update ledger", "error", err)
 	}
 
-	// Write to Obsidian
-	if err := p.obsidianWriter.WriteNote(result); err != nil {
-		return fmt.Errorf("failed to write Obsidian note: %w", err)
+	// Export the action items to task managers and send the note to Deerflow
+	p.exportTasks(ctx, current, notePath, result)
+	p.notifyDeerflow(ctx, current, notePath, result, opts.Reprocess && found)
+
+	metrics.FilesProcessed.With(fileType).Inc()
+	p.processingMutex.Lock()
+	p.processedFiles++
+	p.processingMutex.Unlock()
+
+	logger.InfoContext(ctx, "processed file",
+		"path", filePath,
+		"note", notePath,
+		"total_tokens", result.Metadata.TokenUsage.TotalTokens,
+		"prompt_tokens", result.Metadata.TokenUsage.PromptTokens,
+		"completion_tokens", result.Metadata.TokenUsage.CompletionTokens)
+
+	return outcome, nil
+}
+
+// recordExcluded adds privacy exclusions to the stats; excluded items are
+// counted but never logged or processed
+func (p *Processor) recordExcluded(excluded map[string]int) {
+	p.processingMutex.Lock()
+	defer p.processingMutex.Unlock()
+
+	for rule, count := range excluded {
+		p.excludedByRule[rule] += count
 	}
+}
 
-	log.Printf("✅ Successfully processed: %s", filePath)
-	log.Printf("   📊 Token usage: %d total (%d prompt + %d completion)",
-		result.Metadata.TokenUsage.TotalTokens,
-		result.Metadata.TokenUsage.PromptTokens,
-		result.Metadata.TokenUsage.CompletionTokens)
+// recordFailure counts a file that failed to process
+func (p *Processor) recordFailure() {
+	p.processingMutex.Lock()
+	defer p.processingMutex.Unlock()
 
-	return nil
+	p.failedFiles++
+}
+
+// SourceForNote returns the source file recorded in the ledger for a note
+func (p *Processor) SourceForNote(notePath string) (string, bool) {
+	sourceFile, _, found := p.ledger.FindNote(notePath)
+	return sourceFile, found
 }
 
-// shouldProcessFile determines if a file should be processed
-func (p *Processor) shouldProcessFile(filePath string) bool {
+// skipReason returns why a file should not be processed, or "" if it should.
+// Files reach the daemon only after the watcher saw them settle.
+func (p *Processor) skipReason(ctx context.Context, filePath string) string {
 	// Check file size - skip very large files
 	info, err := os.Stat(filePath)
 	if err != nil {
-		log.Printf("❌ Failed to stat file %s: %v", filePath, err)
-		return false
+		logger.ErrorContext(ctx, "failed to stat file", "path", filePath, "error", err)
+		return "unreadable"
 	}
 
-	// Skip files larger than 1MB for now
+	// Skip files larger than 1MB for now; recordings have their own limits
 	maxSize := int64(1024 * 1024) // 1MB
+	switch extract.TypeOf(filePath) {
+	case "audio":
+		transcription := p.Config().Transcription
+		if transcription.Provider == "" {
+			metrics.FilesSkipped.With("audio", metrics.SkipNoTranscriber).Inc()
+			logger.InfoContext(ctx, "skipping audio file, transcription is disabled", "path", filePath)
+			return metrics.SkipNoTranscriber
+		}
+		maxSize = int64(transcription.MaxFileMB) << 20
+	case "video":
+		if err := p.pipeline().video.Available(); err != nil {
+			metrics.FilesSkipped.With("video", metrics.SkipMissingTool).Inc()
+			logger.WarnContext(ctx, "skipping video file", "path", filePath, "error", err)
+			return metrics.SkipMissingTool
+		}
+		maxSize = int64(p.Config().Video.MaxFileMB) << 20
+	case "image":
+		if err := p.pipeline().imageAvailable(); err != nil {
+			metrics.FilesSkipped.With("image", metrics.SkipMissingTool).Inc()
+			logger.WarnContext(ctx, "skipping image file", "path", filePath, "error", err)
+			return metrics.SkipMissingTool
+		}
+		maxSize = int64(p.Config().Vision.MaxFileMB) << 20
+	}
 	if info.Size() > maxSize {
-		log.Printf("⏭️ Skipping large file (%d bytes): %s", info.Size(), filePath)
-		return false
+		metrics.FilesSkipped.With(extract.TypeOf(filePath), metrics.SkipTooLarge).Inc()
+		logger.InfoContext(ctx, "skipping large file", "path", filePath, "bytes", info.Size())
+		return metrics.SkipTooLarge
 	}
 
-	// Skip files that are too new (might still be writing)
-	if time.Since(info.ModTime()) < 5*time.Second {
-		log.Printf("⏭️ Skipping recently modified file: %s", filePath)
-		return false
-	}
+	return ""
+}
 
-	return true
+// Config returns the configuration in effect
+func (p *Processor) Config() *config.Config {
+	return p.pipeline().config
 }
 
 // GetStatus returns the current processor status
@@ -279,26 +663,61 @@ func (p *Processor) GetStatus() ProcessorStatus {
 	p.processingMutex.Lock()
 	defer p.processingMutex.Unlock()
 
+	excludedByRule := make(map[string]int, len(p.excludedByRule))
+	excludedItems := 0
+	for rule, count := range p.excludedByRule {
+		excludedByRule[rule] = count
+		excludedItems += count
+	}
+
 	return ProcessorStatus{
-		WatchedPaths:     p.watcher.GetWatchedPaths(),
-		QueueLength:      len(p.processQueue),
-		ProcessingFiles:  len(p.isProcessing),
-		LLMProvider:      p.llmClient.GetProvider(),
+		WatchedPaths:    p.watcher.GetWatchedPaths(),
+		QueueLength:     p.queue.Len(),
+		QueueOldestAge:  p.queue.OldestAge().Seconds(),
+		FallingBehind:   p.behind,
+		PendingFiles:    p.watcher.Pending(),
+		ProcessingFiles: len(p.isProcessing),
+		LLMProvider:     p.pipeline().llmClient.GetProvider(),
+		ExcludedItems:   excludedItems,
+		ExcludedByRule:  excludedByRule,
+		ProcessedFiles:  p.processedFiles,
+		FailedFiles:     p.failedFiles,
+		LedgerEntries:   p.ledger.Len(),
+		ExportedTasks:   p.exports.Len(),
+		RecentTasks:     p.recentTasks.Len(),
+		DeerflowPending: p.outbox.Len(),
+		StartedAt:       p.startedAt,
 	}
 }
 
 // ProcessorStatus contains status information
 type ProcessorStatus struct {
-	WatchedPaths    []string `json:"watched_paths"`
-	QueueLength     int      `json:"queue_length"`
-	ProcessingFiles int      `json:"processing_files"`
-	LLMProvider     string   `json:"llm_provider"`
+	WatchedPaths []string `json:"watched_paths"`
+	QueueLength  int      `json:"queue_length"`
+	// QueueOldestAge is how long the oldest queued file has waited, in seconds
+	QueueOldestAge float64 `json:"queue_oldest_seconds"`
+	// FallingBehind is set while the queue is over its alert thresholds
+	FallingBehind bool `json:"falling_behind"`
+	// PendingFiles are waiting for their file to stop changing
+	PendingFiles    int            `json:"pending_files"`
+	ProcessingFiles int            `json:"processing_files"`
+	LLMProvider     string         `json:"llm_provider"`
+	ExcludedItems   int            `json:"excluded_items"`
+	ExcludedByRule  map[string]int `json:"excluded_by_rule"`
+	ProcessedFiles  int            `json:"processed_files"`
+	FailedFiles     int            `json:"failed_files"`
+	LedgerEntries   int            `json:"ledger_entries"`
+	// ExportedTasks counts the action items exported to task managers
+	ExportedTasks int `json:"exported_tasks"`
+	// RecentTasks counts the action items remembered to merge repeats into
+	RecentTasks int `json:"recent_tasks"`
+	// DeerflowPending counts the Deerflow deliveries not made yet
+	DeerflowPending int       `json:"deerflow_pending"`
+	StartedAt       time.Time `json:"started_at"`
 }
 
 // TODO: Add more sophisticated features:
 // - Retry logic for failed processing
 // - File content deduplication
 // - Processing history/cache
-// - Metrics and monitoring
-// - Configuration hot-reloading
-// - Support for different ScreenPipe output formats 
\ No newline at end of file
+// - Support for different ScreenPipe output formats
@@ -0,0 +1,320 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/extract"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
+	"screenpipe-obsidian-bridge/internal/state"
+	"screenpipe-obsidian-bridge/internal/watcher"
+)
+
+// reconcileMargin widens each reconciliation scan back past the previous
+// one, for files whose modification time lags their arrival
+const reconcileMargin = time.Minute
+
+// enqueue adds a file to the pending queue and wakes the file processor
+func (p *Processor) enqueue(ctx context.Context, filePath, correlationID string) bool {
+	return p.enqueueItem(ctx, state.QueueItem{
+		Path:          filePath,
+		CorrelationID: correlationID,
+		QueuedAt:      time.Now(),
+	})
+}
+
+// enqueueItem adds an item to the pending queue and wakes the file processor
+func (p *Processor) enqueueItem(ctx context.Context, item state.QueueItem) bool {
+	filePath := item.Path
+	added, err := p.queue.Push(item)
+	if err != nil {
+		// The file stays queued in memory; only a crash would lose it
+		logger.WarnContext(ctx, "failed to persist queue", "error", err)
+	}
+	if !added {
+		logger.DebugContext(ctx, "file already queued", "path", filePath)
+		return false
+	}
+
+	logger.InfoContext(ctx, "queued file for processing", "path", filePath)
+	p.checkBacklog()
+	select {
+	case p.queued <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// processFiles works through the pending queue, a batch at a time once
+// enough files are queued or when the batch delay passes
+func (p *Processor) processFiles(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("file processor recovered from panic", "panic", r)
+		}
+	}()
+
+	// Batch processing with delay; batch settings need a restart
+	processing := p.pipeline().config.Processing
+	ticker := time.NewTicker(time.Duration(processing.BatchDelay) * time.Second)
+	defer ticker.Stop()
+
+	if queued := p.queue.Len(); queued > 0 {
+		logger.Info("resuming queued files", "files", queued)
+	}
+	p.checkBacklog()
+
+	for {
+		timedOut := false
+		select {
+		case <-ctx.Done():
+			logger.Debug("file processor context cancelled")
+			return
+		case <-p.queued:
+		case <-ticker.C:
+			timedOut = true
+		}
+
+		// Drain full batches; a partial batch, and files waiting to be
+		// retried, wait for the batch delay
+		for p.queue.Ready(time.Now()) >= processing.BatchSize || (timedOut && p.queue.Ready(time.Now()) > 0) {
+			p.processBatch(ctx, p.queue.Next(processing.BatchSize, time.Now()))
+			if ctx.Err() != nil {
+				return
+			}
+			timedOut = false
+		}
+		p.checkBacklog()
+	}
+}
+
+// processBatch processes a batch of queued files, removing each from the
+// queue once it was handled. Files that failed stay queued for a retry
+// until processing.max_attempts, and files not reached before shutdown stay
+// queued too.
+func (p *Processor) processBatch(ctx context.Context, items []state.QueueItem) {
+	logger.Info("processing batch", "files", len(items))
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		fileCtx := logging.WithCorrelationID(ctx, item.CorrelationID)
+		_, err := p.processFile(fileCtx, item.Path, Options{Reprocess: item.Reprocess})
+		if ctx.Err() != nil {
+			// Interrupted by shutdown; process it again after the restart
+			return
+		}
+		if err != nil && p.retry(fileCtx, item, err) {
+			continue
+		}
+		if err := p.queue.Done(item.Path); err != nil {
+			logger.WarnContext(fileCtx, "failed to persist queue", "error", err)
+		}
+	}
+}
+
+// retry keeps a file that failed to process queued until its next attempt
+// is due. It reports false when the file is gone or has used up
+// processing.max_attempts, and should be removed from the queue.
+func (p *Processor) retry(ctx context.Context, item state.QueueItem, err error) bool {
+	if _, statErr := os.Stat(item.Path); os.IsNotExist(statErr) {
+		logger.WarnContext(ctx, "failed to process file, which is gone", "path", item.Path, "error", err)
+		return false
+	}
+
+	processing := p.pipeline().config.Processing
+	item.Attempts++
+	item.LastError = err.Error()
+	if item.Attempts >= processing.MaxAttempts {
+		logger.ErrorContext(ctx, "failed to process file, giving up", "path", item.Path, "attempts", item.Attempts, "error", err)
+		return false
+	}
+
+	delay := processing.RetryDelay
+	for i := 1; i < item.Attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	item.NextAttempt = time.Now().Add(min(delay, time.Hour))
+	logger.WarnContext(ctx, "failed to process file, retrying",
+		"path", item.Path,
+		"attempt", item.Attempts,
+		"next_attempt", item.NextAttempt.Format(time.RFC3339),
+		"error", err)
+	if err := p.queue.Retry(item); err != nil {
+		logger.WarnContext(ctx, "failed to persist queue", "error", err)
+	}
+	return true
+}
+
+// checkBacklog updates the queue metrics and warns once when the queue goes
+// over its alert depth or age, and again when it has caught up
+func (p *Processor) checkBacklog() {
+	processing := p.pipeline().config.Processing
+	depth := p.queue.Len()
+	age := p.queue.OldestAge()
+	metrics.QueueDepth.With().Set(float64(depth))
+	metrics.QueueOldestAge.With().Set(age.Seconds())
+
+	behind := depth >= processing.AlertQueueDepth || age >= processing.AlertQueueAge
+
+	p.processingMutex.Lock()
+	changed := behind != p.behind
+	p.behind = behind
+	p.processingMutex.Unlock()
+
+	if behind {
+		metrics.FallingBehind.With().Set(1)
+	} else {
+		metrics.FallingBehind.With().Set(0)
+	}
+	if !changed {
+		return
+	}
+	if behind {
+		metrics.FallingBehindTotal.With().Inc()
+		logger.Warn("falling behind, files are arriving faster than they are processed",
+			"queued", depth,
+			"oldest", age.Round(time.Second),
+			"alert_queue_depth", processing.AlertQueueDepth,
+			"alert_queue_age", processing.AlertQueueAge)
+	} else {
+		logger.Info("caught up with the queue", "queued", depth)
+	}
+}
+
+// markSeen records that the watcher reported a file
+func (p *Processor) markSeen(filePath string) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+
+	p.processingMutex.Lock()
+	defer p.processingMutex.Unlock()
+
+	p.seen[filePath] = info.ModTime()
+}
+
+// requestReconcile asks for a reconciliation scan as soon as possible
+func (p *Processor) requestReconcile() {
+	select {
+	case p.reconcileNow <- struct{}{}:
+	default:
+	}
+}
+
+// reconcileLoop scans for missed files at startup, every reconcile interval
+// and whenever the watcher reports lost events
+func (p *Processor) reconcileLoop(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("reconciliation recovered from panic", "panic", r)
+		}
+	}()
+
+	// The reconcile interval needs a restart to change
+	ticker := time.NewTicker(p.pipeline().config.Processing.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		p.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.reconcileNow:
+		}
+	}
+}
+
+// reconcile queues files that were modified since the last scan but never
+// reached the processor, e.g. because events were lost or the daemon was
+// not running. Files still within the quiet period are left to the watcher
+// and the next scan.
+func (p *Processor) reconcile(ctx context.Context) {
+	cfg := p.pipeline().config
+	until := time.Now().Add(-cfg.ScreenPipe.QuietPeriod)
+
+	last := p.queue.ReconciledAt()
+	if last.IsZero() {
+		// Files from before the first run are for the backfill command
+		if err := p.queue.SetReconciledAt(until); err != nil {
+			logger.Warn("failed to persist queue", "error", err)
+		}
+		return
+	}
+
+	paths, err := watcher.Existing(&cfg.ScreenPipe, last.Add(-reconcileMargin), until)
+	if err != nil {
+		logger.Warn("reconciliation scan failed", "error", err)
+		return
+	}
+
+	found := 0
+	for _, filePath := range paths {
+		if ctx.Err() != nil {
+			return
+		}
+		if p.accounted(filePath) {
+			continue
+		}
+
+		correlationID := logging.NewCorrelationID()
+		fileCtx := logging.WithCorrelationID(ctx, correlationID)
+		metrics.FilesDetected.With(extract.TypeOf(filePath)).Inc()
+		p.markSeen(filePath)
+		if p.skipReason(fileCtx, filePath) != "" {
+			continue
+		}
+		logger.InfoContext(fileCtx, "found file missed by the watcher", "path", filePath)
+		if p.enqueue(fileCtx, filePath, correlationID) {
+			metrics.FilesReconciled.With(extract.TypeOf(filePath)).Inc()
+			found++
+		}
+	}
+
+	if err := p.queue.SetReconciledAt(until); err != nil {
+		logger.Warn("failed to persist queue", "error", err)
+	}
+	p.forgetSeen(until.Add(-reconcileMargin))
+	logger.Debug("reconciled output directory", "scanned", len(paths), "queued", found, "since", last)
+}
+
+// accounted reports whether a file's current version is already known: queued,
+// being processed, reported by the watcher or recorded in the ledger
+func (p *Processor) accounted(filePath string) bool {
+	if p.queue.Contains(filePath) {
+		return true
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return true
+	}
+
+	p.processingMutex.Lock()
+	seen, wasSeen := p.seen[filePath]
+	processing := p.isProcessing[filePath]
+	p.processingMutex.Unlock()
+	if processing || (wasSeen && !seen.Before(info.ModTime())) {
+		return true
+	}
+
+	entry, found := p.ledger.Lookup(filePath)
+	return found && !entry.ProcessedAt.Before(info.ModTime())
+}
+
+// forgetSeen drops files seen before cutoff, which later scans no longer cover
+func (p *Processor) forgetSeen(cutoff time.Time) {
+	p.processingMutex.Lock()
+	defer p.processingMutex.Unlock()
+
+	for filePath, modTime := range p.seen {
+		if modTime.Before(cutoff) {
+			delete(p.seen, filePath)
+		}
+	}
+}
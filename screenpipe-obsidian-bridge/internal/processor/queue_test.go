@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/privacy"
+	"screenpipe-obsidian-bridge/internal/state"
+)
+
+// failingClient fails every request as a rate-limited provider does
+type failingClient struct {
+	calls int
+}
+
+func (f *failingClient) ProcessContent(ctx context.Context, content string, sourceFile string) (*llm.ProcessingResult, error) {
+	f.calls++
+	return nil, errors.New("status 429: rate limit exceeded")
+}
+
+func (f *failingClient) GetProvider() string {
+	return "stub"
+}
+
+func (f *failingClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestProcessBatchRetriesFailures(t *testing.T) {
+	stateDir := t.TempDir()
+	queue, err := state.OpenQueue(stateDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := state.OpenLedger(stateDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := privacy.New(&config.PrivacyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &failingClient{}
+	p := &Processor{
+		queue:        queue,
+		ledger:       ledger,
+		isProcessing: make(map[string]bool),
+		seen:         make(map[string]time.Time),
+	}
+	p.current.Store(&pipeline{
+		config:        &config.Config{Processing: config.ProcessingConfig{MaxAttempts: 3, RetryDelay: time.Minute}},
+		llmClient:     client,
+		privacyFilter: filter,
+	})
+
+	filePath := filepath.Join(t.TempDir(), "capture.txt")
+	if err := os.WriteFile(filePath, []byte("Drafted the Q3 report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	p.enqueue(ctx, filePath, "c1")
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		start := time.Now()
+		p.processBatch(ctx, []state.QueueItem{queue.Next(1, time.Now().Add(time.Hour))[0]})
+
+		// The failed file stays queued, and accounted for, until its retry
+		if !queue.Contains(filePath) || !p.accounted(filePath) {
+			t.Fatalf("attempt %d: failed file left the queue", attempt+1)
+		}
+		item := queue.Next(1, time.Now().Add(time.Hour))[0]
+		if item.Attempts != attempt+1 || item.LastError == "" {
+			t.Errorf("attempt %d: item = %+v", attempt+1, item)
+		}
+		if wait := item.NextAttempt.Sub(start); wait < delay || wait > delay+time.Second {
+			t.Errorf("attempt %d: retried after %s, want %s", attempt+1, wait, delay)
+		}
+		if queue.Ready(time.Now()) != 0 || len(queue.Next(5, time.Now())) != 0 {
+			t.Errorf("attempt %d: file is due before its retry", attempt+1)
+		}
+		if queue.Ready(item.NextAttempt) != 1 {
+			t.Errorf("attempt %d: file is not due at its retry", attempt+1)
+		}
+	}
+
+	// The last attempt gives up
+	p.processBatch(ctx, queue.Next(1, time.Now().Add(time.Hour)))
+	if queue.Contains(filePath) || client.calls != 3 {
+		t.Errorf("after %d attempts the file is still queued", client.calls)
+	}
+
+	// A file that is gone is not retried
+	missing := filepath.Join(t.TempDir(), "gone.txt")
+	p.enqueue(ctx, missing, "c2")
+	p.processBatch(ctx, queue.Next(1, time.Now()))
+	if queue.Contains(missing) {
+		t.Error("missing file is still queued")
+	}
+
+	// Retries survive a restart
+	p.enqueue(ctx, filePath, "c3")
+	p.processBatch(ctx, queue.Next(1, time.Now()))
+	reopened, err := state.OpenQueue(stateDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := reopened.Next(1, time.Now().Add(time.Hour)); len(items) != 1 || items[0].Attempts != 1 || reopened.Ready(time.Now()) != 0 {
+		t.Errorf("reopened queue holds %+v, want the file waiting for its retry", items)
+	}
+}
@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/metrics"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/state"
+	"screenpipe-obsidian-bridge/internal/tasks"
+)
+
+// exportTasks exports a note's action items to the configured task managers.
+// Items exported before, from this or any other note, are not exported
+// again. Encrypted notes are never exported.
+func (p *Processor) exportTasks(ctx context.Context, current *pipeline, notePath string, result *llm.ProcessingResult) {
+	if len(current.sinks) == 0 || len(result.ActionableTasks) == 0 || strings.HasSuffix(notePath, obsidian.EncryptedExtension) {
+		return
+	}
+
+	now := time.Now()
+	for i, text := range result.ActionableTasks {
+		key := tasks.Key(text)
+		if _, found := p.exports.Lookup(key); found {
+			for _, sink := range current.sinks {
+				metrics.TasksExported.With(sink.Name(), "duplicate").Inc()
+			}
+			logger.DebugContext(ctx, "action item already exported", "task", text)
+			continue
+		}
+
+		export := state.Export{
+			Text:       text,
+			NotePath:   notePath,
+			ExportedAt: now,
+			Due:        result.DetailOf(i).Due,
+			Refs:       make(map[string]string),
+		}
+		for _, sink := range current.sinks {
+			p.createTask(ctx, current, sink, key, &export)
+		}
+		if err := p.exports.Record(key, export); err != nil {
+			logger.WarnContext(ctx, "failed to record exported task", "error", err)
+		}
+	}
+}
+
+// detectDueDates fills in the due dates the LLM did not give from dates
+// the action items mention, such as "by Friday"
+func detectDueDates(result *llm.ProcessingResult, now time.Time) {
+	for i, text := range result.ActionableTasks {
+		if result.DetailOf(i).Due != "" {
+			continue
+		}
+		due := tasks.DetectDue(text, now)
+		if due.IsZero() {
+			continue
+		}
+		if len(result.TaskDetails) < len(result.ActionableTasks) {
+			details := make([]llm.TaskDetail, len(result.ActionableTasks))
+			copy(details, result.TaskDetails)
+			result.TaskDetails = details
+		}
+		result.TaskDetails[i].Due = due.Format("2006-01-02")
+	}
+}
+
+// createTask exports one action item to a sink and records its ID there.
+// Failures are logged and retried by the next sync.
+func (p *Processor) createTask(ctx context.Context, current *pipeline, sink tasks.Sink, key string, export *state.Export) {
+	task := tasks.Task{
+		UID:  key,
+		Text: export.Text,
+		Note: current.obsidianWriter.RelativePath(export.NotePath),
+		Link: current.obsidianWriter.NoteURI(export.NotePath, current.config.Tasks.VaultName),
+	}
+	if export.Due != "" {
+		task.Due, _ = time.ParseInLocation("2006-01-02", export.Due, time.Local)
+	}
+
+	id, err := sink.Create(ctx, task)
+	if err != nil {
+		metrics.TasksExported.With(sink.Name(), "error").Inc()
+		logger.WarnContext(ctx, "failed to export task", "sink", sink.Name(), "error", err)
+		return
+	}
+	metrics.TasksExported.With(sink.Name(), "created").Inc()
+	logger.InfoContext(ctx, "exported task", "sink", sink.Name(), "id", id, "due", export.Due)
+	export.Refs[sink.Name()] = id
+}
+
+// syncTasksLoop checks exported tasks for completion every sync interval
+func (p *Processor) syncTasksLoop(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("task sync recovered from panic", "panic", r)
+		}
+	}()
+
+	// The sync interval needs a restart to change
+	ticker := time.NewTicker(p.pipeline().config.Tasks.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.syncTasks(ctx)
+		}
+	}
+}
+
+// syncTasks retries failed exports and ticks the checkbox of tasks that a
+// task manager reports completed. A task deleted or dismissed in every sink
+// is no longer followed.
+func (p *Processor) syncTasks(ctx context.Context) {
+	current := p.pipeline()
+	if len(current.sinks) == 0 {
+		return
+	}
+
+	for key, export := range p.exports.Open() {
+		changed := false
+		done := ""
+		closed := 0
+		for _, sink := range current.sinks {
+			name := sink.Name()
+			if export.Closed[name] {
+				closed++
+				continue
+			}
+			id, exported := export.Refs[name]
+			if !exported {
+				p.createTask(ctx, current, sink, key, &export)
+				changed = changed || export.Refs[name] != ""
+				continue
+			}
+
+			status, err := sink.Status(ctx, id)
+			if err != nil {
+				logger.WarnContext(ctx, "failed to check task", "sink", name, "id", id, "error", err)
+				continue
+			}
+			switch status {
+			case tasks.StatusDone:
+				done = name
+			case tasks.StatusGone:
+				logger.InfoContext(ctx, "exported task was removed", "sink", name, "id", id)
+				export.Closed[name] = true
+				closed++
+				changed = true
+			}
+			if done != "" {
+				break
+			}
+		}
+
+		switch {
+		case done != "":
+			ticked, err := current.obsidianWriter.CompleteTask(export.NotePath, export.Text)
+			if err != nil {
+				logger.WarnContext(ctx, "failed to tick completed task", "note", export.NotePath, "error", err)
+			} else if !ticked {
+				logger.InfoContext(ctx, "completed task not found in its note", "note", export.NotePath, "task", export.Text)
+			} else {
+				logger.InfoContext(ctx, "ticked completed task", "sink", done, "note", export.NotePath)
+			}
+			metrics.TasksCompleted.With(done).Inc()
+			export.Done = true
+			changed = true
+		case closed == len(current.sinks):
+			export.Done = true
+			changed = true
+		}
+
+		if changed {
+			if err := p.exports.Record(key, export); err != nil {
+				logger.WarnContext(ctx, "failed to record exported task", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/obsidian"
+	"screenpipe-obsidian-bridge/internal/state"
+	"screenpipe-obsidian-bridge/internal/tasks"
+)
+
+func TestSyncTasksWithCalDAV(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string]string)
+	down := true
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			puts++
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			object, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, object)
+		}
+	}))
+	defer server.Close()
+
+	vault := t.TempDir()
+	cfg := &config.Config{
+		Obsidian: config.ObsidianConfig{VaultPath: vault, Tasks: config.NoteTasksConfig{Tag: "#task"}},
+		Tasks:    config.TasksConfig{Sinks: []config.TaskSinkConfig{{Type: "caldav", Endpoint: server.URL + "/tasks"}}},
+	}
+	sinks, err := tasks.New(&cfg.Tasks, &cfg.Security)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports, err := state.OpenExports(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{exports: exports}
+	p.current.Store(&pipeline{
+		config:         cfg,
+		obsidianWriter: obsidian.New(&cfg.Obsidian, &config.EncryptionConfig{}, nil),
+		sinks:          sinks,
+	})
+
+	notePath := filepath.Join(vault, "2025-01-15 standup.md")
+	if err := os.WriteFile(notePath, []byte("## Action Items\n- [ ] Send Anna the Q3 report #task ^task-1a2b3c4d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := &llm.ProcessingResult{ActionableTasks: []string{"Send Anna the Q3 report"}}
+	ctx := context.Background()
+	key := tasks.Key("Send Anna the Q3 report")
+
+	// An export the server refuses is recorded without a reference
+	p.exportTasks(ctx, p.pipeline(), notePath, result)
+	export, found := exports.Lookup(key)
+	if !found || len(export.Refs) != 0 {
+		t.Fatalf("export after a failure = %+v, %v, want one without refs", export, found)
+	}
+
+	// The next sync retries it
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	p.syncTasks(ctx)
+	export, _ = exports.Lookup(key)
+	if export.Refs["caldav"] != key+".ics" {
+		t.Fatalf("refs after sync = %v, want the CalDAV to-do", export.Refs)
+	}
+
+	// An action item seen again is not exported again
+	p.exportTasks(ctx, p.pipeline(), notePath, result)
+	p.syncTasks(ctx)
+	if puts != 1 {
+		t.Errorf("%d to-dos created, want 1", puts)
+	}
+	if data, _ := os.ReadFile(notePath); !strings.Contains(string(data), "- [ ] Send Anna") {
+		t.Errorf("open to-do ticked the note:\n%s", data)
+	}
+
+	// Completing the to-do ticks the checkbox
+	mu.Lock()
+	path := "/tasks/" + key + ".ics"
+	objects[path] = strings.Replace(objects[path], "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+	mu.Unlock()
+	p.syncTasks(ctx)
+	data, err := os.ReadFile(notePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- [x] Send Anna the Q3 report #task ^task-1a2b3c4d") {
+		t.Errorf("completed to-do did not tick the note:\n%s", data)
+	}
+	if export, _ = exports.Lookup(key); !export.Done || len(exports.Open()) != 0 {
+		t.Errorf("export after completion = %+v, want done", export)
+	}
+}
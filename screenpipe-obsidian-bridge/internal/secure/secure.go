@@ -0,0 +1,204 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// magic prefixes every encrypted file so plaintext written before
+// encryption was enabled can still be read
+var magic = []byte("SPBENC1\n")
+
+// KeySize is the AES-256 key length in bytes
+const KeySize = 32
+
+// Codec encrypts and decrypts data at rest with AES-256-GCM. A nil or
+// disabled Codec passes data through unchanged.
+type Codec struct {
+	aead cipher.AEAD
+}
+
+// New creates a codec from the encryption configuration
+func New(cfg *config.EncryptionConfig) (*Codec, error) {
+	if !cfg.Enabled {
+		return &Codec{}, nil
+	}
+
+	key, err := LoadKey(cfg.KeyEnv, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithKey(key)
+}
+
+// NewWithKey creates a codec from a raw 32-byte key
+func NewWithKey(key []byte) (*Codec, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Codec{aead: aead}, nil
+}
+
+// LoadKey reads a base64 key from an environment variable, falling back to a
+// key file (for example one exported from the OS keyring)
+func LoadKey(keyEnv, keyFile string) ([]byte, error) {
+	if keyEnv != "" {
+		if value := os.Getenv(keyEnv); value != "" {
+			return decodeKey(value, "environment variable "+keyEnv)
+		}
+	}
+
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file %s: %w", keyFile, err)
+		}
+		// Accept either a raw 32-byte key or its base64 encoding
+		if len(data) == KeySize {
+			return data, nil
+		}
+		return decodeKey(string(data), "key file "+keyFile)
+	}
+
+	return nil, fmt.Errorf("encryption is enabled but no key was found (set %s or security.encryption.key_file)", keyEnvName(keyEnv))
+}
+
+// GenerateKey returns a new random key encoded as base64
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Enabled reports whether the codec encrypts data
+func (c *Codec) Enabled() bool {
+	return c != nil && c.aead != nil
+}
+
+// Seal encrypts plaintext; disabled codecs return it unchanged
+func (c *Codec) Seal(plaintext []byte) ([]byte, error) {
+	if !c.Enabled() {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(magic)+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Open decrypts data produced by Seal. Data without the encryption header is
+// treated as plaintext so existing files keep working after enabling encryption.
+func (c *Codec) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if !c.Enabled() {
+		return nil, fmt.Errorf("data is encrypted but no encryption key is configured")
+	}
+
+	body := data[len(magic):]
+	nonceSize := c.aead.NonceSize()
+	if len(body) < nonceSize {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+
+	plaintext, err := c.aead.Open(nil, body[:nonceSize], body[nonceSize:], magic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (wrong key?): %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted reports whether data carries the encryption header
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// ReadFile reads and decrypts a file
+func (c *Codec) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(data)
+}
+
+// WriteFile encrypts data and writes it atomically via a temporary file
+func (c *Codec) WriteFile(path string, data []byte, perm os.FileMode) error {
+	sealed, err := c.Seal(data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// decodeKey decodes a base64 key and checks its length
+func decodeKey(value, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key in %s: %w", source, err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key in %s must decode to %d bytes, got %d", source, KeySize, len(key))
+	}
+	return key, nil
+}
+
+// keyEnvName returns the configured key variable for error messages
+func keyEnvName(keyEnv string) string {
+	if keyEnv == "" {
+		return "security.encryption.key_env"
+	}
+	return keyEnv
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// ExportsFile is the exported-task record's file name inside the state directory
+const ExportsFile = "exports.json"
+
+// Export records one action item exported to task managers
+type Export struct {
+	// Text is the action item as the LLM wrote it
+	Text     string `json:"text"`
+	NotePath string `json:"note_path"`
+	// Due is the detected due date, as YYYY-MM-DD
+	Due        string    `json:"due,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Refs maps sink names to the task's ID there. Sinks without one have
+	// not accepted the task yet and are retried.
+	Refs map[string]string `json:"refs"`
+	// Closed lists the sinks where the task was deleted or closed without
+	// being done; they are no longer asked about it
+	Closed map[string]bool `json:"closed,omitempty"`
+	// Done is set once a sink reported the task completed and its
+	// checkbox was ticked
+	Done bool `json:"done,omitempty"`
+}
+
+// Exports is the persisted record of exported action items, keyed by their
+// normalized text so an item seen again is not exported twice
+type Exports struct {
+	path    string
+	codec   *secure.Codec
+	mutex   sync.Mutex
+	entries map[string]Export
+}
+
+// OpenExports loads the exported-task record from the state directory
+func OpenExports(stateDir string, codec *secure.Codec) (*Exports, error) {
+	e := &Exports{
+		path:    filepath.Join(stateDir, ExportsFile),
+		codec:   codec,
+		entries: make(map[string]Export),
+	}
+
+	data, err := codec.ReadFile(e.path)
+	if os.IsNotExist(err) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exports %s: %w", e.path, err)
+	}
+
+	if err := json.Unmarshal(data, &e.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse exports %s: %w", e.path, err)
+	}
+
+	return e, nil
+}
+
+// Lookup returns the export recorded under key
+func (e *Exports) Lookup(key string) (Export, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	entry, ok := e.entries[key]
+	return entry, ok
+}
+
+// Record stores an export and persists the record
+func (e *Exports) Record(key string, entry Export) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.entries[key] = entry
+	return e.save()
+}
+
+// Open returns the exports that are not done yet, by key
+func (e *Exports) Open() map[string]Export {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	open := make(map[string]Export)
+	for key, entry := range e.entries {
+		if !entry.Done {
+			refs := make(map[string]string, len(entry.Refs))
+			for sink, ref := range entry.Refs {
+				refs[sink] = ref
+			}
+			entry.Refs = refs
+			closed := make(map[string]bool, len(entry.Closed))
+			for sink := range entry.Closed {
+				closed[sink] = true
+			}
+			entry.Closed = closed
+			open[key] = entry
+		}
+	}
+	return open
+}
+
+// Len returns the number of recorded exports
+func (e *Exports) Len() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.entries)
+}
+
+// save writes the record; callers must hold the mutex
+func (e *Exports) save() error {
+	data, err := json.MarshalIndent(e.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode exports: %w", err)
+	}
+
+	if err := e.codec.WriteFile(e.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write exports %s: %w", e.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// LedgerFile is the ledger's file name inside the state directory
+const LedgerFile = "ledger.json"
+
+// Files are the state files written through the codec, and so the ones
+// rekey re-encrypts. Logs kept in the state directory are appended to as
+// plaintext and are not among them.
+var Files = []string{LedgerFile, QueueFile, ExportsFile, RecentTasksFile, OutboxFile}
+
+// LedgerEntry records the outcome of processing one source file
+type LedgerEntry struct {
+	ContentHash string    `json:"content_hash"`
+	NotePath    string    `json:"note_path"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// Ledger is the persisted record of processed source files. It doubles as a
+// cache so unchanged files are not sent to the LLM again after a restart.
+type Ledger struct {
+	path    string
+	codec   *secure.Codec
+	mutex   sync.Mutex
+	entries map[string]LedgerEntry
+}
+
+// OpenLedger loads the ledger from the state directory, creating it if needed
+func OpenLedger(stateDir string, codec *secure.Codec) (*Ledger, error) {
+	l := &Ledger{
+		path:    filepath.Join(stateDir, LedgerFile),
+		codec:   codec,
+		entries: make(map[string]LedgerEntry),
+	}
+
+	data, err := codec.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger %s: %w", l.path, err)
+	}
+
+	if err := json.Unmarshal(data, &l.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse ledger %s: %w", l.path, err)
+	}
+
+	return l, nil
+}
+
+// Lookup returns the entry for a source file
+func (l *Ledger) Lookup(sourceFile string) (LedgerEntry, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry, ok := l.entries[sourceFile]
+	return entry, ok
+}
+
+// FindNote returns the source file whose note is notePath
+func (l *Ledger) FindNote(notePath string) (string, LedgerEntry, bool) {
+	target := cleanPath(notePath)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for sourceFile, entry := range l.entries {
+		if cleanPath(entry.NotePath) == target {
+			return sourceFile, entry, true
+		}
+	}
+	return "", LedgerEntry{}, false
+}
+
+// cleanPath makes a path absolute and clean so paths given in different
+// forms compare equal
+func cleanPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Unchanged reports whether a source file was already processed with the
+// same content
+func (l *Ledger) Unchanged(sourceFile, contentHash string) bool {
+	entry, ok := l.Lookup(sourceFile)
+	return ok && entry.ContentHash == contentHash
+}
+
+// Record stores the outcome of processing a source file and persists the ledger
+func (l *Ledger) Record(sourceFile string, entry LedgerEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries[sourceFile] = entry
+	return l.save()
+}
+
+// Len returns the number of recorded source files
+func (l *Ledger) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.entries)
+}
+
+// save writes the ledger; callers must hold the mutex
+func (l *Ledger) save() error {
+	data, err := json.MarshalIndent(l.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger: %w", err)
+	}
+
+	if err := l.codec.WriteFile(l.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write ledger %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// HashContent returns the hex SHA-256 of content, used as the ledger's cache key
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
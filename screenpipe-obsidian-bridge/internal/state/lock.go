@@ -0,0 +1,47 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile is locked in the state directory by the process using it
+const LockFile = "bridge.lock"
+
+// ErrLocked is returned by Lock when another process holds the lock
+var ErrLocked = errors.New("state directory is in use by another process")
+
+// Lock is an exclusive lock on a state directory, held by the running
+// daemon and by commands that rewrite the state behind its back. The
+// operating system releases it when the process exits, even on a crash.
+type Lock struct {
+	file *os.File
+}
+
+// LockDir takes the lock on stateDir, failing with ErrLocked when another
+// process holds it
+func LockDir(stateDir string) (*Lock, error) {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %s: %w", stateDir, err)
+	}
+	path := filepath.Join(stateDir, LockFile)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%s: %w", stateDir, ErrLocked)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	return l.file.Close()
+}
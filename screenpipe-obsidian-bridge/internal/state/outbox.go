@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// OutboxFile is the pending-delivery record's file name inside the state directory
+const OutboxFile = "deerflow_outbox.json"
+
+// Delivery is a webhook payload waiting to be delivered
+type Delivery struct {
+	// ID identifies the delivery to the receiver, which sees it again on
+	// every retry
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	// CreatedAt is when the payload was queued and NextAttempt when it is
+	// due to be sent
+	CreatedAt   time.Time `json:"created_at"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Outbox is the persisted record of webhook payloads not delivered yet, so
+// they survive a restart
+type Outbox struct {
+	path    string
+	codec   *secure.Codec
+	mutex   sync.Mutex
+	entries map[string]Delivery
+}
+
+// OpenOutbox loads the pending deliveries from the state directory
+func OpenOutbox(stateDir string, codec *secure.Codec) (*Outbox, error) {
+	o := &Outbox{
+		path:    filepath.Join(stateDir, OutboxFile),
+		codec:   codec,
+		entries: make(map[string]Delivery),
+	}
+
+	data, err := codec.ReadFile(o.path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox %s: %w", o.path, err)
+	}
+
+	if err := json.Unmarshal(data, &o.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse outbox %s: %w", o.path, err)
+	}
+
+	return o, nil
+}
+
+// Put stores a delivery, replacing any with the same ID, and persists the
+// record
+func (o *Outbox) Put(delivery Delivery) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.entries[delivery.ID] = delivery
+	return o.save()
+}
+
+// Remove drops a delivered or abandoned delivery
+func (o *Outbox) Remove(id string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if _, ok := o.entries[id]; !ok {
+		return nil
+	}
+	delete(o.entries, id)
+	return o.save()
+}
+
+// Due returns the deliveries whose next attempt is at or before now, oldest
+// first
+func (o *Outbox) Due(now time.Time) []Delivery {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	due := []Delivery{}
+	for _, entry := range o.entries {
+		if !entry.NextAttempt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	return due
+}
+
+// Next returns when the earliest pending delivery is due, and false when
+// none is pending
+func (o *Outbox) Next() (time.Time, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var next time.Time
+	for _, entry := range o.entries {
+		if next.IsZero() || entry.NextAttempt.Before(next) {
+			next = entry.NextAttempt
+		}
+	}
+	return next, len(o.entries) > 0
+}
+
+// Len returns the number of pending deliveries
+func (o *Outbox) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.entries)
+}
+
+// save writes the record; callers must hold the mutex
+func (o *Outbox) save() error {
+	data, err := json.MarshalIndent(o.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+
+	if err := o.codec.WriteFile(o.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write outbox %s: %w", o.path, err)
+	}
+	return nil
+}
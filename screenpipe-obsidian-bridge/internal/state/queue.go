@@ -0,0 +1,388 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// QueueFile is the pending queue's file name inside the state directory
+const QueueFile = "queue.json"
+
+// QueueJournalFile records, one line each, the changes to the queue since
+// QueueFile was last written
+const QueueJournalFile = "queue.journal"
+
+// compactMin is the fewest journal records that are folded into QueueFile;
+// past it, the journal is compacted once it holds as many records as the
+// queue has items, so each change costs constant I/O on average
+const compactMin = 256
+
+// QueueItem is a source file waiting to be processed
+type QueueItem struct {
+	Path          string    `json:"path"`
+	CorrelationID string    `json:"correlation_id"`
+	QueuedAt      time.Time `json:"queued_at"`
+	// Reprocess rewrites the file's note even if its content is unchanged
+	Reprocess bool `json:"reprocess,omitempty"`
+	// Attempts counts the failed attempts to process the file, and
+	// NextAttempt is when it is due to be tried again
+	Attempts    int       `json:"attempts,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Queue is the persisted list of files waiting to be processed. It has no
+// size limit, so bursts are absorbed instead of dropped, and files still
+// queued when the daemon stops are processed after the next start. Items
+// stay queued until Done, so a crash mid-batch processes them again, and
+// files that failed stay queued until their retry is due.
+//
+// Changes are appended to a journal rather than rewriting the whole queue,
+// and the journal is folded into the queue file once it has grown as large
+// as the queue, when the queue is opened and when it is closed.
+type Queue struct {
+	path  string
+	codec *secure.Codec
+	mutex sync.Mutex
+	state queueState
+	// journal is nil once the queue is closed, when changes rewrite the
+	// queue file instead
+	journal *os.File
+	records int
+}
+
+// queueChange is one journal record
+type queueChange struct {
+	// Put adds an item, or replaces the queued item with its path
+	Put          *QueueItem `json:"put,omitempty"`
+	Done         string     `json:"done,omitempty"`
+	ReconciledAt *time.Time `json:"reconciled_at,omitempty"`
+}
+
+// queueState is the queue's on-disk form
+type queueState struct {
+	Items []QueueItem `json:"items"`
+	// ReconciledAt is when the output directory was last scanned for files
+	// the watcher missed
+	ReconciledAt time.Time `json:"reconciled_at"`
+}
+
+// OpenQueue loads the pending queue from the state directory, creating it if
+// needed, and folds the journal left by the last run into it
+func OpenQueue(stateDir string, codec *secure.Codec) (*Queue, error) {
+	q := &Queue{
+		path:  filepath.Join(stateDir, QueueFile),
+		codec: codec,
+	}
+
+	data, err := codec.ReadFile(q.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read queue %s: %w", q.path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &q.state); err != nil {
+			return nil, fmt.Errorf("failed to parse queue %s: %w", q.path, err)
+		}
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(q.state.Items, func(i, j int) bool {
+		return q.state.Items[i].QueuedAt.Before(q.state.Items[j].QueuedAt)
+	})
+
+	if err := q.save(); err != nil {
+		return nil, err
+	}
+	journalPath := q.journalPath()
+	if q.journal, err = os.OpenFile(journalPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600); err != nil {
+		return nil, fmt.Errorf("failed to open queue journal %s: %w", journalPath, err)
+	}
+	return q, nil
+}
+
+// CompactQueue folds the queue journal in stateDir into the queue file, so
+// that the queue is held in the queue file alone
+func CompactQueue(stateDir string, codec *secure.Codec) error {
+	q, err := OpenQueue(stateDir, codec)
+	if err != nil {
+		return err
+	}
+	return q.Close()
+}
+
+// Close folds the journal into the queue file. Later changes rewrite the
+// queue file.
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.journal == nil {
+		return nil
+	}
+	err := q.compact()
+	if closeErr := q.journal.Close(); err == nil {
+		err = closeErr
+	}
+	q.journal = nil
+	return err
+}
+
+// Push appends a file unless it is already queued, and persists the queue.
+// It reports whether the file was added; a queued file asked to be
+// reprocessed keeps its place and is reprocessed.
+func (q *Queue) Push(item QueueItem) (bool, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, queued := range q.state.Items {
+		if queued.Path == item.Path {
+			if item.Reprocess && !queued.Reprocess {
+				q.state.Items[i].Reprocess = true
+				return false, q.record(queueChange{Put: &q.state.Items[i]})
+			}
+			return false, nil
+		}
+	}
+	q.state.Items = append(q.state.Items, item)
+	return true, q.record(queueChange{Put: &item})
+}
+
+// Next returns up to n of the oldest files due at now without removing
+// them
+func (q *Queue) Next(n int, now time.Time) []QueueItem {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	items := []QueueItem{}
+	for _, item := range q.state.Items {
+		if len(items) == n {
+			break
+		}
+		if !item.NextAttempt.After(now) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Ready returns the number of queued files due at now
+func (q *Queue) Ready(now time.Time) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ready := 0
+	for _, item := range q.state.Items {
+		if !item.NextAttempt.After(now) {
+			ready++
+		}
+	}
+	return ready
+}
+
+// Retry records a failed attempt of a queued file, which keeps its place
+// and is not returned by Next before item.NextAttempt, and persists the queue
+func (q *Queue) Retry(item QueueItem) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, queued := range q.state.Items {
+		if queued.Path == item.Path {
+			// A reprocess requested meanwhile is kept
+			item.Reprocess = item.Reprocess || queued.Reprocess
+			q.state.Items[i] = item
+			return q.record(queueChange{Put: &item})
+		}
+	}
+	return nil
+}
+
+// Done removes a processed file from the queue and persists it
+func (q *Queue) Done(path string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, item := range q.state.Items {
+		if item.Path == path {
+			q.state.Items = append(q.state.Items[:i], q.state.Items[i+1:]...)
+			return q.record(queueChange{Done: path})
+		}
+	}
+	return nil
+}
+
+// Contains reports whether a file is queued
+func (q *Queue) Contains(path string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, item := range q.state.Items {
+		if item.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of queued files
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.state.Items)
+}
+
+// OldestAge returns how long the oldest queued file has been waiting
+func (q *Queue) OldestAge() time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.state.Items) == 0 {
+		return 0
+	}
+	return time.Since(q.state.Items[0].QueuedAt)
+}
+
+// ReconciledAt returns when the last reconciliation scan finished, zero if
+// there was none
+func (q *Queue) ReconciledAt() time.Time {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.state.ReconciledAt
+}
+
+// SetReconciledAt records when a reconciliation scan finished
+func (q *Queue) SetReconciledAt(t time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.state.ReconciledAt = t
+	return q.record(queueChange{ReconciledAt: &t})
+}
+
+// apply makes a journaled change to the queue in memory
+func (q *Queue) apply(change queueChange) {
+	switch {
+	case change.Put != nil:
+		for i, item := range q.state.Items {
+			if item.Path == change.Put.Path {
+				q.state.Items[i] = *change.Put
+				return
+			}
+		}
+		q.state.Items = append(q.state.Items, *change.Put)
+	case change.Done != "":
+		for i, item := range q.state.Items {
+			if item.Path == change.Done {
+				q.state.Items = append(q.state.Items[:i], q.state.Items[i+1:]...)
+				return
+			}
+		}
+	case change.ReconciledAt != nil:
+		q.state.ReconciledAt = *change.ReconciledAt
+	}
+}
+
+// record appends a change already applied in memory to the journal, and
+// compacts the journal once it is due; callers must hold the mutex
+func (q *Queue) record(change queueChange) error {
+	if q.journal == nil {
+		return q.save()
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue change: %w", err)
+	}
+	// Encrypted records are base64 so each stays on one line
+	if q.codec.Enabled() {
+		sealed, err := q.codec.Seal(data)
+		if err != nil {
+			return err
+		}
+		data = []byte(base64.StdEncoding.EncodeToString(sealed))
+	}
+	// One write per record, so a crash can only cut off the last one
+	if _, err := q.journal.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue journal %s: %w", q.journalPath(), err)
+	}
+
+	q.records++
+	if q.records >= max(compactMin, len(q.state.Items)) {
+		return q.compact()
+	}
+	return nil
+}
+
+// replay applies the changes in the journal. A last record without its line
+// end was cut off by a crash before it was acknowledged, and is skipped.
+func (q *Queue) replay() error {
+	journalPath := q.journalPath()
+	data, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read queue journal %s: %w", journalPath, err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	// The part after the last line end is empty or cut off
+	for i, line := range lines[:len(lines)-1] {
+		if !bytes.HasPrefix(line, []byte("{")) {
+			sealed, err := base64.StdEncoding.DecodeString(string(line))
+			if err != nil {
+				return fmt.Errorf("failed to parse queue journal %s, line %d: %w", journalPath, i+1, err)
+			}
+			if line, err = q.codec.Open(sealed); err != nil {
+				return fmt.Errorf("failed to read queue journal %s, line %d: %w", journalPath, i+1, err)
+			}
+		}
+		var change queueChange
+		if err := json.Unmarshal(line, &change); err != nil {
+			return fmt.Errorf("failed to parse queue journal %s, line %d: %w", journalPath, i+1, err)
+		}
+		q.apply(change)
+	}
+	return nil
+}
+
+// compact writes the queue file and empties the journal; callers must hold
+// the mutex
+func (q *Queue) compact() error {
+	if err := q.save(); err != nil {
+		return err
+	}
+	q.records = 0
+	if err := q.journal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate queue journal %s: %w", q.journalPath(), err)
+	}
+	return nil
+}
+
+func (q *Queue) journalPath() string {
+	return filepath.Join(filepath.Dir(q.path), QueueJournalFile)
+}
+
+// save writes the queue; callers must hold the mutex
+func (q *Queue) save() error {
+	data, err := json.MarshalIndent(q.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode queue: %w", err)
+	}
+
+	if err := q.codec.WriteFile(q.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write queue %s: %w", q.path, err)
+	}
+	return nil
+}
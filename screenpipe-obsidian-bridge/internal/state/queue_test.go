@@ -0,0 +1,159 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// paths returns the paths of the queued items, in order
+func paths(q *Queue) []string {
+	var queued []string
+	for _, item := range q.Next(1<<20, time.Now().Add(24*time.Hour)) {
+		queued = append(queued, item.Path)
+	}
+	return queued
+}
+
+func TestQueueJournal(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, secure.KeySize)
+	encrypted, err := secure.NewWithKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, codec := range []*secure.Codec{nil, encrypted} {
+		t.Run(map[bool]string{false: "plaintext", true: "encrypted"}[codec.Enabled()], func(t *testing.T) {
+			dir := t.TempDir()
+			q, err := OpenQueue(dir, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+			for i, name := range []string{"a", "b", "c", "d"} {
+				if _, err := q.Push(QueueItem{Path: name, QueuedAt: start.Add(time.Duration(i) * time.Second)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := q.Done("b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := q.Retry(QueueItem{Path: "c", QueuedAt: start.Add(2 * time.Second), Attempts: 1, NextAttempt: start.Add(time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.Push(QueueItem{Path: "a", Reprocess: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := q.SetReconciledAt(start); err != nil {
+				t.Fatal(err)
+			}
+
+			// The changes are in the journal, not the queue file
+			journal, err := os.ReadFile(filepath.Join(dir, QueueJournalFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := bytes.Count(journal, []byte("\n")); lines != 8 {
+				t.Errorf("journal has %d records, want 8", lines)
+			}
+			if bytes.Contains(journal, []byte(`"path"`)) == codec.Enabled() {
+				t.Errorf("journal encryption does not follow the codec:\n%s", journal)
+			}
+
+			// A record cut off by a crash is skipped
+			f, err := os.OpenFile(filepath.Join(dir, QueueJournalFile), os.O_WRONLY|os.O_APPEND, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString(`{"done":"d`)
+			f.Close()
+
+			reopened, err := OpenQueue(dir, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(reopened); !reflect.DeepEqual(got, []string{"a", "c", "d"}) {
+				t.Errorf("reopened queue holds %v, want a, c and d", got)
+			}
+			items := reopened.Next(3, start.Add(2*time.Hour))
+			if !items[0].Reprocess || items[1].Attempts != 1 || !reopened.ReconciledAt().Equal(start) {
+				t.Errorf("reopened queue lost changes: %+v, reconciled at %s", items, reopened.ReconciledAt())
+			}
+			if reopened.Ready(start) != 2 {
+				t.Errorf("%d files ready before the retry, want 2", reopened.Ready(start))
+			}
+
+			// Opening folds the journal into the queue file
+			if info, err := os.Stat(filepath.Join(dir, QueueJournalFile)); err != nil || info.Size() != 0 {
+				t.Errorf("journal not emptied on open: %v, %v", info, err)
+			}
+			if err := reopened.Close(); err != nil {
+				t.Fatal(err)
+			}
+			// Changes after Close rewrite the queue file
+			if err := reopened.Done("a"); err != nil {
+				t.Fatal(err)
+			}
+			final, err := OpenQueue(dir, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(final); !reflect.DeepEqual(got, []string{"c", "d"}) {
+				t.Errorf("queue after Close holds %v, want c and d", got)
+			}
+		})
+	}
+}
+
+func TestQueueCompaction(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenQueue(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalPath := filepath.Join(dir, QueueJournalFile)
+	for i := 0; i < compactMin-1; i++ {
+		if _, err := q.Push(QueueItem{Path: string(rune('a'+i%26)) + string(rune('a'+i/26)), QueuedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, _ := os.Stat(journalPath); info.Size() == 0 {
+		t.Fatal("journal compacted early")
+	}
+	if _, err := q.Push(QueueItem{Path: "last", QueuedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(journalPath); info.Size() != 0 {
+		t.Errorf("journal holds %d bytes after %d records, want it compacted", info.Size(), compactMin)
+	}
+
+	reopened, err := OpenQueue(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != compactMin {
+		t.Errorf("queue file holds %d items, want %d", reopened.Len(), compactMin)
+	}
+}
+
+func TestQueueJournalWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	codec, _ := secure.NewWithKey(bytes.Repeat([]byte{1}, secure.KeySize))
+	q, err := OpenQueue(dir, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Push(QueueItem{Path: "a"})
+
+	// The queue file is rewritten on open, so only the journal is checked
+	os.Remove(filepath.Join(dir, QueueFile))
+	other, _ := secure.NewWithKey(bytes.Repeat([]byte{2}, secure.KeySize))
+	if _, err := OpenQueue(dir, other); err == nil {
+		t.Error("journal sealed with another key was accepted")
+	}
+}
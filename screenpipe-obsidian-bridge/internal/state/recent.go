@@ -0,0 +1,113 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/secure"
+)
+
+// RecentTasksFile is the recent action items' file name inside the state directory
+const RecentTasksFile = "recent_tasks.json"
+
+// RecentTask is an action item written to a note, remembered so it is
+// recognized when it is extracted again
+type RecentTask struct {
+	// Text is the action item as first written
+	Text      string    `json:"text"`
+	NotePath  string    `json:"note_path"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Seen counts the extractions merged into it, the first included
+	Seen int `json:"seen"`
+	// Embedding is the text's embedding, when embeddings are compared
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// RecentTasks is the persisted index of recently seen action items, keyed
+// by their normalized text
+type RecentTasks struct {
+	path    string
+	codec   *secure.Codec
+	mutex   sync.Mutex
+	entries map[string]RecentTask
+}
+
+// OpenRecentTasks loads the recent action items from the state directory
+func OpenRecentTasks(stateDir string, codec *secure.Codec) (*RecentTasks, error) {
+	r := &RecentTasks{
+		path:    filepath.Join(stateDir, RecentTasksFile),
+		codec:   codec,
+		entries: make(map[string]RecentTask),
+	}
+
+	data, err := codec.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent tasks %s: %w", r.path, err)
+	}
+
+	if err := json.Unmarshal(data, &r.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse recent tasks %s: %w", r.path, err)
+	}
+
+	return r, nil
+}
+
+// Since returns the action items last seen after t, by key
+func (r *RecentTasks) Since(t time.Time) map[string]RecentTask {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	recent := make(map[string]RecentTask)
+	for key, entry := range r.entries {
+		if entry.LastSeen.After(t) {
+			recent[key] = entry
+		}
+	}
+	return recent
+}
+
+// Update stores entries, forgets the action items last seen before t and
+// persists the index
+func (r *RecentTasks) Update(entries map[string]RecentTask, before time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, entry := range entries {
+		r.entries[key] = entry
+	}
+	for key, entry := range r.entries {
+		if entry.LastSeen.Before(before) {
+			delete(r.entries, key)
+		}
+	}
+	return r.save()
+}
+
+// Len returns the number of remembered action items
+func (r *RecentTasks) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.entries)
+}
+
+// save writes the index; callers must hold the mutex
+func (r *RecentTasks) save() error {
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode recent tasks: %w", err)
+	}
+
+	if err := r.codec.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write recent tasks %s: %w", r.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+)
+
+// uidDomain makes the UIDs of exported to-dos globally unique
+const uidDomain = "screenpipe-obsidian-bridge"
+
+// CalDAV exports tasks as VTODO items to a task list on a CalDAV server,
+// such as Radicale, Nextcloud or Fastmail
+type CalDAV struct {
+	client     client
+	collection string
+	config     *config.TaskSinkConfig
+}
+
+// NewCalDAV creates a sink for the task list collection at endpoint. With
+// keys, requests use basic authentication as username.
+func NewCalDAV(cfg *config.TaskSinkConfig, keys credentials.Provider) *CalDAV {
+	return &CalDAV{
+		client: client{
+			http: &http.Client{},
+			keys: keys,
+			authorize: func(req *http.Request, key string) {
+				req.SetBasicAuth(cfg.Username, key)
+			},
+		},
+		collection: strings.TrimSuffix(cfg.Endpoint, "/") + "/",
+		config:     cfg,
+	}
+}
+
+// Create implements Sink. The to-do is stored under a name derived from the
+// task's UID, so an export retried after a lost response finds it there.
+func (c *CalDAV) Create(ctx context.Context, task Task) (string, error) {
+	name := task.UID + ".ics"
+	header := http.Header{}
+	header.Set("Content-Type", "text/calendar; charset=utf-8")
+	header.Set("If-None-Match", "*")
+	resp, err := c.client.do(ctx, http.MethodPut, c.collection+url.PathEscape(name), header, []byte(vtodo(task, time.Now())))
+	if err != nil {
+		return "", fmt.Errorf("failed to create CalDAV to-do: %w", err)
+	}
+	switch resp.status {
+	case http.StatusCreated, http.StatusNoContent, http.StatusOK, http.StatusPreconditionFailed:
+		return name, nil
+	}
+	return "", fmt.Errorf("failed to create CalDAV to-do: %w", resp.failure())
+}
+
+// Status implements Sink
+func (c *CalDAV) Status(ctx context.Context, id string) (Status, error) {
+	resp, err := c.client.do(ctx, http.MethodGet, c.collection+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return StatusOpen, fmt.Errorf("failed to read CalDAV to-do: %w", err)
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return StatusGone, nil
+	default:
+		return StatusOpen, fmt.Errorf("failed to read CalDAV to-do: %w", resp.failure())
+	}
+
+	for _, line := range unfold(string(resp.body)) {
+		name, value, _ := strings.Cut(line, ":")
+		name, _, _ = strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "STATUS":
+			switch strings.ToUpper(strings.TrimSpace(value)) {
+			case "COMPLETED":
+				return StatusDone, nil
+			case "CANCELLED":
+				return StatusGone, nil
+			}
+		case "COMPLETED":
+			return StatusDone, nil
+		}
+	}
+	return StatusOpen, nil
+}
+
+// Name implements Sink
+func (c *CalDAV) Name() string {
+	return c.config.SinkName()
+}
+
+// vtodo renders a task as an iCalendar VTODO (RFC 5545)
+func vtodo(task Task, now time.Time) string {
+	stamp := now.UTC().Format("20060102T150405Z")
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//" + uidDomain + "//EN",
+		"BEGIN:VTODO",
+		"UID:" + task.UID + "@" + uidDomain,
+		"DTSTAMP:" + stamp,
+		"CREATED:" + stamp,
+		"SUMMARY:" + escapeText(task.Text),
+		"DESCRIPTION:" + escapeText(description(task)),
+	}
+	if task.Link != "" {
+		lines = append(lines, "URL:"+task.Link)
+	}
+	if !task.Due.IsZero() {
+		lines = append(lines, "DUE;VALUE=DATE:"+task.Due.Format("20060102"))
+	}
+	lines = append(lines, "STATUS:NEEDS-ACTION", "END:VTODO", "END:VCALENDAR")
+
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(fold(line))
+	}
+	return out.String()
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// fold splits a content line into lines of at most 75 octets, without
+// breaking UTF-8 sequences, and ends it with CRLF
+func fold(line string) string {
+	var out strings.Builder
+	width := 75
+	for len(line) > width {
+		cut := width
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		out.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space
+		width = 74
+	}
+	out.WriteString(line + "\r\n")
+	return out.String()
+}
+
+// unfold returns the content lines of an iCalendar object
+func unfold(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,160 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+)
+
+// fakeCalDAV stores to-dos PUT into one collection, as a CalDAV server does
+// for the requests the sink makes
+type fakeCalDAV struct {
+	mu      sync.Mutex
+	objects map[string]string
+	puts    int
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, password, ok := r.BasicAuth(); !ok || user != "anna" || password != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/calendars/anna/tasks/")
+	switch r.Method {
+	case http.MethodPut:
+		f.puts++
+		if _, exists := f.objects[name]; exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.objects[name] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		object, ok := f.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, object)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestCalDAV(t *testing.T) {
+	fake := &fakeCalDAV{objects: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sink := NewCalDAV(&config.TaskSinkConfig{
+		Type:     "caldav",
+		Endpoint: server.URL + "/calendars/anna/tasks",
+		Username: "anna",
+	}, password(t, "s3cret"))
+	ctx := context.Background()
+
+	task := Task{
+		UID:  Key("Send Anna the Q3 report"),
+		Text: "Send Anna the Q3 report, with the Zürich figures; all of them",
+		Due:  time.Date(2025, 1, 17, 0, 0, 0, 0, time.Local),
+		Note: "ScreenPipe/2025-01-15 standup.md",
+		Link: "obsidian://open?vault=Work&file=ScreenPipe%2F2025-01-15%20standup",
+	}
+	id, err := sink.Create(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != task.UID+".ics" {
+		t.Errorf("Create returned %q, want the name derived from the UID", id)
+	}
+
+	// A retried export finds the to-do already there
+	if again, err := sink.Create(ctx, task); err != nil || again != id {
+		t.Errorf("second Create = %q, %v, want %q", again, err, id)
+	}
+	if fake.puts != 2 || len(fake.objects) != 1 {
+		t.Errorf("%d PUTs stored %d to-dos, want 2 storing 1", fake.puts, len(fake.objects))
+	}
+
+	stored := unfold(fake.objects[id])
+	for _, want := range []string{
+		"UID:" + task.UID + "@" + uidDomain,
+		`SUMMARY:Send Anna the Q3 report\, with the Zürich figures\; all of them`,
+		"DUE;VALUE=DATE:20250117",
+		"URL:" + task.Link,
+		"STATUS:NEEDS-ACTION",
+	} {
+		if !containsString(stored, want) {
+			t.Errorf("stored to-do has no line %q:\n%s", want, fake.objects[id])
+		}
+	}
+	for _, line := range strings.Split(fake.objects[id], "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets is not folded: %q", len(line), line)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		want   Status
+	}{
+		{"open", "STATUS:NEEDS-ACTION", StatusOpen},
+		{"in progress", "STATUS:IN-PROCESS", StatusOpen},
+		{"completed", "STATUS:COMPLETED", StatusDone},
+		{"completion date only", "COMPLETED:20250116T101500Z", StatusDone},
+		{"cancelled", "STATUS;X-PARAM=1:CANCELLED", StatusGone},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.objects["status.ics"] = strings.Replace(fake.objects[id], "STATUS:NEEDS-ACTION", test.status, 1)
+			fake.mu.Unlock()
+			status, err := sink.Status(ctx, "status.ics")
+			if err != nil || status != test.want {
+				t.Errorf("Status = %v, %v, want %v", status, err, test.want)
+			}
+		})
+	}
+
+	if status, err := sink.Status(ctx, "deleted.ics"); err != nil || status != StatusGone {
+		t.Errorf("Status of a deleted to-do = %v, %v, want gone", status, err)
+	}
+
+	refused := NewCalDAV(&config.TaskSinkConfig{Endpoint: server.URL + "/calendars/anna/tasks/", Username: "anna"}, password(t, "wrong"))
+	if _, err := refused.Status(ctx, id); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("Status with the wrong password returned %v, want a 401 error", err)
+	}
+}
+
+// password returns a provider of a literal CalDAV password
+func password(t *testing.T, key string) credentials.Provider {
+	t.Helper()
+	keys, err := credentials.NewSources("tasks.sinks[0].credentials", []config.CredentialSource{{Key: key}}, &config.SecurityConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
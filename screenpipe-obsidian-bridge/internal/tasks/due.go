@@ -0,0 +1,97 @@
+package tasks
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	isoDate     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	todayWords  = regexp.MustCompile(`(?i)\b(today|tonight|end of (the )?day|eod)\b`)
+	tomorrow    = regexp.MustCompile(`(?i)\btomorrow\b`)
+	endOfWeek   = regexp.MustCompile(`(?i)\b(end of (the )?week|eow)\b`)
+	nextWeek    = regexp.MustCompile(`(?i)\bnext week\b`)
+	weekdayDate = regexp.MustCompile(`(?i)\b(?:by|on|before|due|until|this|next)\s+(mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)(?:day|sday|nesday|rsday|urday)?\b`)
+	monthDay    = regexp.MustCompile(`(?i)\b(?:by|on|before|due|until)\s+(jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b`)
+	dayMonth    = regexp.MustCompile(`(?i)\b(?:by|on|before|due|until)\s+(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?(jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\b`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "sept": time.September, "oct": time.October,
+	"nov": time.November, "dec": time.December,
+}
+
+// DetectDue finds a due date in an action item, relative to now: an ISO
+// date, "today", "tomorrow", "by Friday", "next week", "end of week", or a
+// month and day such as "by Oct 23". It returns the zero time when the text
+// names none.
+func DetectDue(text string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if m := isoDate.FindStringSubmatch(text); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if due, ok := date(year, time.Month(month), day, now.Location()); ok {
+			return due
+		}
+	}
+	if m := monthDay.FindStringSubmatch(text); m != nil {
+		if due, ok := upcoming(today, months[strings.ToLower(m[1])], m[2]); ok {
+			return due
+		}
+	}
+	if m := dayMonth.FindStringSubmatch(text); m != nil {
+		if due, ok := upcoming(today, months[strings.ToLower(m[2])], m[1]); ok {
+			return due
+		}
+	}
+	switch {
+	case tomorrow.MatchString(text):
+		return today.AddDate(0, 0, 1)
+	case todayWords.MatchString(text):
+		return today
+	case nextWeek.MatchString(text):
+		return nextWeekday(today.AddDate(0, 0, 1), time.Monday)
+	case endOfWeek.MatchString(text):
+		return nextWeekday(today, time.Friday)
+	}
+	if m := weekdayDate.FindStringSubmatch(text); m != nil {
+		return nextWeekday(today, weekdays[strings.ToLower(m[1])])
+	}
+	return time.Time{}
+}
+
+// nextWeekday returns the first day on or after from that is a weekday
+func nextWeekday(from time.Time, weekday time.Weekday) time.Time {
+	return from.AddDate(0, 0, (int(weekday)-int(from.Weekday())+7)%7)
+}
+
+// upcoming returns the next occurrence of a month and day, today included
+func upcoming(today time.Time, month time.Month, day string) (time.Time, bool) {
+	d, err := strconv.Atoi(day)
+	if err != nil {
+		return time.Time{}, false
+	}
+	due, ok := date(today.Year(), month, d, today.Location())
+	if ok && due.Before(today) {
+		due, ok = date(today.Year()+1, month, d, today.Location())
+	}
+	return due, ok
+}
+
+// date returns a calendar date, rejecting ones that do not exist
+func date(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return t, t.Month() == month && t.Day() == day
+}
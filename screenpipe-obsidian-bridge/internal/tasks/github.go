@@ -0,0 +1,132 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+)
+
+const (
+	// githubEndpoint is the base URL of GitHub's REST API
+	githubEndpoint = "https://api.github.com"
+	// githubTitleLength is the longest issue title sent; longer action
+	// items are cut and kept whole in the body
+	githubTitleLength = 200
+)
+
+// GitHub exports tasks as issues in a GitHub repository
+type GitHub struct {
+	client client
+	// issues is the repository's issues URL
+	issues string
+	config *config.TaskSinkConfig
+}
+
+// NewGitHub creates a sink for github.repository, authenticating with the
+// tokens keys supplies. Endpoint can point to GitHub Enterprise's API.
+func NewGitHub(cfg *config.TaskSinkConfig, keys credentials.Provider) *GitHub {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = githubEndpoint
+	}
+	return &GitHub{
+		client: client{
+			http: &http.Client{},
+			keys: keys,
+			authorize: func(req *http.Request, key string) {
+				req.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		issues: strings.TrimSuffix(endpoint, "/") + "/repos/" + cfg.Repository + "/issues",
+		config: cfg,
+	}
+}
+
+// githubIssue is the part of an issue that is sent and read
+type githubIssue struct {
+	Number      int      `json:"number,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+// githubHeader returns the headers GitHub's API asks clients to send
+func githubHeader() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return header
+}
+
+// Create implements Sink
+func (g *GitHub) Create(ctx context.Context, task Task) (string, error) {
+	title := task.Text
+	if utf8.RuneCountInString(title) > githubTitleLength {
+		title = string([]rune(title)[:githubTitleLength-1]) + "…"
+	}
+	body, err := json.Marshal(githubIssue{
+		Title:  title,
+		Body:   task.Text + "\n\n" + description(task),
+		Labels: g.config.Labels,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	header := githubHeader()
+	header.Set("Content-Type", "application/json")
+	resp, err := g.client.do(ctx, http.MethodPost, g.issues, header, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitHub issue: %w", err)
+	}
+	if resp.status != http.StatusCreated {
+		return "", fmt.Errorf("failed to create GitHub issue: %w", resp.failure())
+	}
+
+	var issue githubIssue
+	if err := json.Unmarshal(resp.body, &issue); err != nil || issue.Number == 0 {
+		return "", fmt.Errorf("failed to create GitHub issue: unexpected response: %s", resp.body)
+	}
+	return strconv.Itoa(issue.Number), nil
+}
+
+// Status implements Sink. Issues closed as not planned count as gone.
+func (g *GitHub) Status(ctx context.Context, id string) (Status, error) {
+	resp, err := g.client.do(ctx, http.MethodGet, g.issues+"/"+id, githubHeader(), nil)
+	if err != nil {
+		return StatusOpen, fmt.Errorf("failed to read GitHub issue: %w", err)
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return StatusGone, nil
+	default:
+		return StatusOpen, fmt.Errorf("failed to read GitHub issue: %w", resp.failure())
+	}
+
+	var issue githubIssue
+	if err := json.Unmarshal(resp.body, &issue); err != nil {
+		return StatusOpen, fmt.Errorf("failed to read GitHub issue: %w", err)
+	}
+	switch {
+	case issue.State != "closed":
+		return StatusOpen, nil
+	case issue.StateReason == "not_planned":
+		return StatusGone, nil
+	}
+	return StatusDone, nil
+}
+
+// Name implements Sink
+func (g *GitHub) Name() string {
+	return g.config.SinkName()
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// stopWords carry no meaning of their own in an action item
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "for": true,
+	"on": true, "in": true, "at": true, "by": true, "with": true, "about": true,
+	"and": true, "or": true, "is": true, "are": true, "be": true, "it": true,
+	"this": true, "that": true, "my": true, "our": true, "your": true,
+	"their": true, "his": true, "her": true, "me": true, "us": true,
+	"them": true, "him": true, "please": true, "up": true,
+}
+
+// Normalize reduces an action item to its lower-cased words, without
+// punctuation or markdown
+func Normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Tokens returns the significant words of an action item: normalized,
+// without stop words or single letters (the s of "Bob's"), and with plurals
+// reduced to the singular
+func Tokens(text string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range strings.Fields(Normalize(text)) {
+		if stopWords[word] || len(word) == 1 && word[0] >= 'a' && word[0] <= 'z' {
+			continue
+		}
+		if len(word) > 2 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = strings.TrimSuffix(word, "s")
+		}
+		tokens[word] = true
+	}
+	return tokens
+}
+
+// Jaccard returns the share of tokens two action items have in common, from
+// 0 for none to 1 for all
+func Jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Cosine returns the cosine similarity of two embeddings, or 0 when their
+// dimensions differ
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,166 @@
+// Package tasks exports action items to task managers, Todoist, GitHub
+// Issues and CalDAV servers, and reports when they are completed there so
+// the note's checkbox can follow.
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/logging"
+)
+
+var logger = logging.For(logging.ComponentTasks)
+
+// requestTimeout bounds one request to a task manager
+const requestTimeout = 30 * time.Second
+
+// Task is an action item as exported
+type Task struct {
+	// UID identifies the action item, see Key
+	UID  string
+	Text string
+	// Due is the detected due date, zero when none was found
+	Due time.Time
+	// Note is the vault-relative path of the note the item is from, and
+	// Link an obsidian:// URI opening it
+	Note string
+	Link string
+}
+
+// Status is the state of an exported task in a task manager
+type Status int
+
+const (
+	StatusOpen Status = iota
+	StatusDone
+	// StatusGone is a task that was deleted, or closed without being done
+	StatusGone
+)
+
+// Sink is a task manager action items are exported to
+type Sink interface {
+	// Create adds a task and returns its ID in the task manager
+	Create(ctx context.Context, task Task) (string, error)
+	// Status reports what became of the task with the ID Create returned
+	Status(ctx context.Context, id string) (Status, error)
+	// Name identifies the sink in the export state, logs and metrics
+	Name() string
+}
+
+// New creates the configured sinks. Each authenticates with its own
+// credentials, pooled and rotated like the LLM's.
+func New(cfg *config.TasksConfig, securityCfg *config.SecurityConfig) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for i := range cfg.Sinks {
+		sinkCfg := &cfg.Sinks[i]
+		var keys credentials.Provider
+		if len(sinkCfg.Credentials) > 0 {
+			var err error
+			keys, err = credentials.NewSources(fmt.Sprintf("tasks.sinks[%d].credentials", i), sinkCfg.Credentials, securityCfg)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch sinkCfg.Type {
+		case "todoist":
+			sinks = append(sinks, NewTodoist(sinkCfg, keys))
+		case "github":
+			sinks = append(sinks, NewGitHub(sinkCfg, keys))
+		case "caldav":
+			sinks = append(sinks, NewCalDAV(sinkCfg, keys))
+		default:
+			return nil, fmt.Errorf("unsupported task sink: %s", sinkCfg.Type)
+		}
+	}
+	return sinks, nil
+}
+
+// Key returns the key an action item is deduplicated by: a hash of its text
+// ignoring case, spacing and closing punctuation
+func Key(text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	normalized = strings.TrimRight(normalized, ".!;")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// description is the text exported with a task: where it came from
+func description(task Task) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "From the ScreenPipe note %s\n%s", task.Note, task.Link)
+	if !task.Due.IsZero() {
+		fmt.Fprintf(&text, "\nDue %s", task.Due.Format("2006-01-02"))
+	}
+	return text.String()
+}
+
+// client sends authenticated requests to a task manager's HTTP API
+type client struct {
+	http *http.Client
+	keys credentials.Provider
+	// authorize adds the key to a request
+	authorize func(req *http.Request, key string)
+}
+
+// response is a task manager's answer to a request
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do sends a request and reads the response, whatever its status
+func (c *client) do(ctx context.Context, method, url string, header http.Header, body []byte) (*response, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	key := ""
+	if c.keys != nil {
+		if key, err = c.keys.Key(ctx); err != nil {
+			return nil, err
+		}
+		c.authorize(req, key)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, credentials.RedactError(err)
+	}
+	defer resp.Body.Close()
+	if c.keys != nil {
+		c.keys.Report(key, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+// failure describes an unexpected response
+func (r *response) failure() error {
+	detail := strings.TrimSpace(string(r.body))
+	if len(detail) > 200 {
+		detail = detail[:200] + "..."
+	}
+	return fmt.Errorf("status %d: %s", r.status, detail)
+}
@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+)
+
+// todoistEndpoint is the base URL of Todoist's API
+const todoistEndpoint = "https://api.todoist.com/api/v1"
+
+// Todoist exports tasks to Todoist with its REST API
+type Todoist struct {
+	client   client
+	endpoint string
+	config   *config.TaskSinkConfig
+}
+
+// NewTodoist creates a sink for the Todoist account whose API token keys
+// supplies
+func NewTodoist(cfg *config.TaskSinkConfig, keys credentials.Provider) *Todoist {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = todoistEndpoint
+	}
+	return &Todoist{
+		client: client{
+			http: &http.Client{},
+			keys: keys,
+			authorize: func(req *http.Request, key string) {
+				req.Header.Set("Authorization", "Bearer "+key)
+			},
+		},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		config:   cfg,
+	}
+}
+
+// todoistTask is the part of a Todoist task that is sent and read.
+// IsCompleted is what the older REST v2 API calls Checked.
+type todoistTask struct {
+	ID          string   `json:"id,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ProjectID   string   `json:"project_id,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	DueDate     string   `json:"due_date,omitempty"`
+	Checked     bool     `json:"checked,omitempty"`
+	IsCompleted bool     `json:"is_completed,omitempty"`
+	IsDeleted   bool     `json:"is_deleted,omitempty"`
+}
+
+// Create implements Sink
+func (t *Todoist) Create(ctx context.Context, task Task) (string, error) {
+	created := todoistTask{
+		Content:     task.Text,
+		Description: description(task),
+		ProjectID:   t.config.Project,
+		Labels:      t.config.Labels,
+	}
+	if !task.Due.IsZero() {
+		created.DueDate = task.Due.Format("2006-01-02")
+	}
+	body, err := json.Marshal(created)
+	if err != nil {
+		return "", err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	// Retried requests with the same ID create the task only once
+	header.Set("X-Request-Id", task.UID)
+	resp, err := t.client.do(ctx, http.MethodPost, t.endpoint+"/tasks", header, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Todoist task: %w", err)
+	}
+	if resp.status != http.StatusOK && resp.status != http.StatusCreated {
+		return "", fmt.Errorf("failed to create Todoist task: %w", resp.failure())
+	}
+
+	var result todoistTask
+	if err := json.Unmarshal(resp.body, &result); err != nil || result.ID == "" {
+		return "", fmt.Errorf("failed to create Todoist task: unexpected response: %s", resp.body)
+	}
+	return result.ID, nil
+}
+
+// Status implements Sink
+func (t *Todoist) Status(ctx context.Context, id string) (Status, error) {
+	resp, err := t.client.do(ctx, http.MethodGet, t.endpoint+"/tasks/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return StatusOpen, fmt.Errorf("failed to read Todoist task: %w", err)
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return StatusGone, nil
+	default:
+		return StatusOpen, fmt.Errorf("failed to read Todoist task: %w", resp.failure())
+	}
+
+	var task todoistTask
+	if err := json.Unmarshal(resp.body, &task); err != nil {
+		return StatusOpen, fmt.Errorf("failed to read Todoist task: %w", err)
+	}
+	switch {
+	case task.IsDeleted:
+		return StatusGone, nil
+	case task.Checked || task.IsCompleted:
+		return StatusDone, nil
+	}
+	return StatusOpen, nil
+}
+
+// Name implements Sink
+func (t *Todoist) Name() string {
+	return t.config.SinkName()
+}
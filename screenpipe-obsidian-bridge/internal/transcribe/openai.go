@@ -0,0 +1,101 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+)
+
+// OpenAI transcribes through an OpenAI-compatible /audio/transcriptions
+// endpoint, e.g. OpenAI's hosted Whisper or a local faster-whisper server
+type OpenAI struct {
+	client *openai.Client
+	config *config.TranscriptionConfig
+}
+
+// NewOpenAI creates a transcriber for transcription.endpoint, falling back to
+// llm.endpoint. Like the LLM client it asks keys for a key on every request.
+func NewOpenAI(cfg *config.TranscriptionConfig, llmCfg *config.LLMConfig, keys credentials.Provider) *OpenAI {
+	clientConfig := openai.DefaultConfig("")
+	clientConfig.HTTPClient = credentials.NewHTTPClient(keys)
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = llmCfg.Endpoint
+	}
+	if endpoint != "" {
+		clientConfig.BaseURL = strings.TrimSuffix(endpoint, "/")
+	}
+
+	return &OpenAI{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: cfg,
+	}
+}
+
+// Transcribe implements Transcriber
+func (o *OpenAI) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	// verbose_json is the only format with segments and the detected language
+	response, err := o.client.CreateTranscription(ctx, openai.AudioRequest{
+		Model:    o.config.Model,
+		FilePath: path,
+		Language: o.config.Language,
+		Format:   openai.AudioResponseFormatVerboseJSON,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe %s: %w", path, credentials.RedactError(err))
+	}
+
+	transcript := &Transcript{
+		Language: languageCode(response.Language),
+		Duration: seconds(response.Duration),
+	}
+	for _, segment := range response.Segments {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			transcript.Segments = append(transcript.Segments, Segment{
+				Start: seconds(segment.Start),
+				End:   seconds(segment.End),
+				Text:  text,
+			})
+		}
+	}
+	// Servers that ignore verbose_json still return the text
+	if len(transcript.Segments) == 0 && strings.TrimSpace(response.Text) != "" {
+		transcript.Segments = []Segment{{End: transcript.Duration, Text: strings.TrimSpace(response.Text)}}
+	}
+	if transcript.Language == "" {
+		transcript.Language = o.config.Language
+	}
+	return transcript, nil
+}
+
+// Name implements Transcriber
+func (o *OpenAI) Name() string {
+	return "openai"
+}
+
+// languageCodes maps the language names OpenAI's verbose_json reports
+// ("english") to ISO-639-1 codes
+var languageCodes = map[string]string{
+	"arabic": "ar", "chinese": "zh", "czech": "cs", "danish": "da",
+	"dutch": "nl", "english": "en", "finnish": "fi", "french": "fr",
+	"german": "de", "greek": "el", "hebrew": "he", "hindi": "hi",
+	"hungarian": "hu", "indonesian": "id", "italian": "it", "japanese": "ja",
+	"korean": "ko", "norwegian": "no", "polish": "pl", "portuguese": "pt",
+	"romanian": "ro", "russian": "ru", "spanish": "es", "swedish": "sv",
+	"thai": "th", "turkish": "tr", "ukrainian": "uk", "vietnamese": "vi",
+}
+
+// languageCode returns the ISO-639-1 code for a reported language, passing
+// codes and unknown names through
+func languageCode(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[language]; ok {
+		return code
+	}
+	return language
+}
@@ -0,0 +1,180 @@
+// Package transcribe turns audio captures into timestamped text, either
+// through an OpenAI-compatible /audio/transcriptions endpoint or a local
+// whisper.cpp binary.
+package transcribe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+var logger = logging.For(logging.ComponentTranscribe)
+
+// Segment is a stretch of speech with its offset into the recording
+type Segment struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+}
+
+// Transcript is the text of one recording
+type Transcript struct {
+	// Language is the spoken language as an ISO-639-1 code, detected unless
+	// transcription.language is set
+	Language string `json:"language"`
+	// Duration is the length of the recording
+	Duration time.Duration `json:"duration"`
+	Segments []Segment     `json:"segments"`
+}
+
+// Transcriber converts an audio file into a transcript
+type Transcriber interface {
+	Transcribe(ctx context.Context, path string) (*Transcript, error)
+	// Name identifies the implementation in logs and metrics
+	Name() string
+}
+
+// New creates the transcriber for the configured provider, or nil when
+// transcription is disabled. Hosted providers authenticate with keys, the
+// LLM credentials. Every request is recorded in auditLog.
+func New(cfg *config.TranscriptionConfig, llmCfg *config.LLMConfig, keys credentials.Provider, auditLog *audit.Logger) (Transcriber, error) {
+	var transcriber Transcriber
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		transcriber = NewOpenAI(cfg, llmCfg, keys)
+	case "whisper_cpp":
+		whisper, err := NewWhisperCpp(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transcriber = whisper
+	default:
+		return nil, fmt.Errorf("unsupported transcription provider: %s", cfg.Provider)
+	}
+	return &measured{Transcriber: transcriber, config: cfg, auditLog: auditLog}, nil
+}
+
+// measured bounds each transcription by the configured timeout, fills in the
+// duration from the WAV header, records metrics and writes an audit record
+type measured struct {
+	Transcriber
+	config   *config.TranscriptionConfig
+	auditLog *audit.Logger
+}
+
+// Transcribe implements Transcriber
+func (m *measured) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	transcript, err := m.Transcriber.Transcribe(ctx, path)
+	elapsed := time.Since(start)
+	metrics.TranscriptionDuration.With(m.Name()).Observe(elapsed.Seconds())
+	m.audit(ctx, path, start, elapsed, transcript, err)
+	if err != nil {
+		metrics.TranscriptionRequests.With(m.Name(), "error").Inc()
+		return nil, err
+	}
+
+	// The header is exact; providers report rounded or no durations
+	if duration, err := WAVDuration(path); err == nil {
+		transcript.Duration = duration
+	} else if transcript.Duration == 0 && len(transcript.Segments) > 0 {
+		transcript.Duration = transcript.Segments[len(transcript.Segments)-1].End
+	}
+
+	metrics.TranscriptionRequests.With(m.Name(), "success").Inc()
+	metrics.AudioTranscribed.With(m.Name()).Add(transcript.Duration.Seconds())
+	logger.DebugContext(ctx, "transcribed audio",
+		"provider", m.Name(),
+		"path", path,
+		"language", transcript.Language,
+		"duration", transcript.Duration.Round(time.Second),
+		"segments", len(transcript.Segments),
+		"latency_ms", elapsed.Milliseconds())
+	return transcript, nil
+}
+
+// audit writes the audit record of a transcription request. The recording
+// is identified by its hash, as prompts are.
+func (m *measured) audit(ctx context.Context, path string, start time.Time, elapsed time.Duration, transcript *Transcript, err error) {
+	model := m.config.Model
+	if m.config.Provider == "whisper_cpp" {
+		model = filepath.Base(m.config.ModelPath)
+	}
+	record := audit.Record{
+		Timestamp:      start.UTC(),
+		Provider:       m.Name(),
+		Model:          model,
+		PromptTemplate: llm.TemplateTranscription,
+		SourceFile:     audit.SourceFile(ctx, path),
+		CorrelationID:  logging.CorrelationID(ctx),
+		Redaction:      audit.Redaction{ExcludedItems: audit.ExcludedItems(ctx)},
+		LatencyMS:      elapsed.Milliseconds(),
+		Outcome:        audit.OutcomeSuccess,
+	}
+	if f, openErr := os.Open(path); openErr == nil {
+		sum := sha256.New()
+		if _, copyErr := io.Copy(sum, f); copyErr == nil {
+			record.ContentHash = hex.EncodeToString(sum.Sum(nil))
+		}
+		f.Close()
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	} else {
+		texts := make([]string, len(transcript.Segments))
+		for i, segment := range transcript.Segments {
+			texts[i] = segment.Text
+		}
+		record.Response = strings.Join(texts, "\n")
+	}
+	if logErr := m.auditLog.Log(record); logErr != nil {
+		logger.WarnContext(ctx, "failed to write audit record", "error", logErr)
+	}
+}
+
+// Stub returns a fixed transcript, for tests and previews that must not
+// call a transcription service
+type Stub struct {
+	Transcript Transcript
+	Err        error
+}
+
+// Transcribe implements Transcriber
+func (s *Stub) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	transcript := s.Transcript
+	transcript.Segments = append([]Segment(nil), s.Transcript.Segments...)
+	return &transcript, nil
+}
+
+// Name implements Transcriber
+func (s *Stub) Name() string {
+	return "stub"
+}
+
+// seconds converts fractional seconds as reported by transcription APIs
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
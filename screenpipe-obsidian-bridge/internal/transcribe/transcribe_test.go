@@ -0,0 +1,85 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+func TestMeasuredAudits(t *testing.T) {
+	tests := []struct {
+		name     string
+		stub     *Stub
+		outcome  string
+		response string
+	}{
+		{
+			name: "success",
+			stub: &Stub{Transcript: Transcript{Segments: []Segment{
+				{Start: 0, End: time.Second, Text: "Morning everyone."},
+				{Start: time.Second, End: 2 * time.Second, Text: "Quick sync."},
+			}}},
+			outcome:  audit.OutcomeSuccess,
+			response: "Morning everyone.\nQuick sync.",
+		},
+		{
+			name:    "error",
+			stub:    &Stub{Err: errors.New("status 500")},
+			outcome: audit.OutcomeError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			recording := filepath.Join(dir, "meeting.wav")
+			writeTestWAV(t, recording, 2*time.Second)
+			logPath := filepath.Join(dir, "audit.jsonl")
+			auditLog, err := audit.New(&config.SecurityConfig{
+				EnableRequestLogging: true,
+				RequestLog:           config.RequestLogConfig{Path: logPath, MaxSizeMB: 1, IncludeContent: true},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			transcriber := &measured{
+				Transcriber: test.stub,
+				config:      &config.TranscriptionConfig{Provider: "openai", Model: "whisper-1", Timeout: time.Minute},
+				auditLog:    auditLog,
+			}
+
+			ctx := audit.WithExcludedItems(audit.WithSourceFile(context.Background(), "/captures/standup.wav"), 2)
+			transcript, err := transcriber.Transcribe(ctx, recording)
+			if (err != nil) != (test.outcome == audit.OutcomeError) {
+				t.Fatalf("Transcribe error = %v", err)
+			}
+			if err == nil && transcript.Duration != 2*time.Second {
+				t.Errorf("duration = %s, want the WAV header's 2s", transcript.Duration)
+			}
+			auditLog.Close()
+
+			records, err := audit.Search(logPath, audit.Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("%d audit records, want 1", len(records))
+			}
+			record := records[0]
+			if record.Provider != "stub" || record.Model != "whisper-1" || record.PromptTemplate != "transcription" {
+				t.Errorf("record names %s/%s/%s", record.Provider, record.Model, record.PromptTemplate)
+			}
+			if record.SourceFile != "/captures/standup.wav" || record.Redaction.ExcludedItems != 2 || record.ContentHash == "" {
+				t.Errorf("record source %q, excluded %d, hash %q", record.SourceFile, record.Redaction.ExcludedItems, record.ContentHash)
+			}
+			if record.Outcome != test.outcome || record.Response != test.response {
+				t.Errorf("record outcome %s, response %q, want %s, %q", record.Outcome, record.Response, test.outcome, test.response)
+			}
+		})
+	}
+}
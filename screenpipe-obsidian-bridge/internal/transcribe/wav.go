@@ -0,0 +1,194 @@
+package transcribe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// errNotWAV is returned for files without a RIFF/WAVE header
+var errNotWAV = errors.New("not a WAV file")
+
+// WAVDuration reads the length of a WAV recording from its header: the size
+// of the data chunk divided by the byte rate from the fmt chunk
+func WAVDuration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var header [12]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return 0, errNotWAV
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return 0, errNotWAV
+	}
+
+	var byteRate uint32
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(f, chunk[:]); err != nil {
+			return 0, fmt.Errorf("WAV file has no data chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+		// Chunks are padded to an even size
+		skip := int64(size) + int64(size%2)
+
+		switch id {
+		case "fmt ":
+			var format [16]byte
+			if size < 16 {
+				return 0, fmt.Errorf("WAV fmt chunk is too short")
+			}
+			if _, err := io.ReadFull(f, format[:]); err != nil {
+				return 0, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			byteRate = binary.LittleEndian.Uint32(format[8:12])
+			skip -= 16
+		case "data":
+			if byteRate == 0 {
+				return 0, fmt.Errorf("WAV data chunk comes before a valid fmt chunk")
+			}
+			// Recorders that were interrupted leave the size unset
+			if size == 0 || size == 0xFFFFFFFF {
+				info, err := f.Stat()
+				if err != nil {
+					return 0, err
+				}
+				offset, _ := f.Seek(0, io.SeekCurrent)
+				size = uint32(info.Size() - offset)
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), nil
+		}
+
+		if _, err := f.Seek(skip, io.SeekCurrent); err != nil {
+			return 0, fmt.Errorf("failed to skip WAV %q chunk: %w", id, err)
+		}
+	}
+}
+
+// CutWAV writes the part of a WAV recording from from to to into a new WAV
+// file at dst, keeping the recording's format
+func CutWAV(path, dst string, from, to time.Duration) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var header [12]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return errNotWAV
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return errNotWAV
+	}
+
+	var format []byte
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(f, chunk[:]); err != nil {
+			return fmt.Errorf("WAV file has no data chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return fmt.Errorf("WAV fmt chunk is too short")
+			}
+			format = make([]byte, size)
+			if _, err := io.ReadFull(f, format); err != nil {
+				return fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			if size%2 == 1 {
+				if _, err := f.Seek(1, io.SeekCurrent); err != nil {
+					return err
+				}
+			}
+			continue
+		case "data":
+			if format == nil {
+				return fmt.Errorf("WAV data chunk comes before a valid fmt chunk")
+			}
+			byteRate := int64(binary.LittleEndian.Uint32(format[8:12]))
+			blockAlign := int64(binary.LittleEndian.Uint16(format[12:14]))
+			if byteRate == 0 || blockAlign == 0 {
+				return fmt.Errorf("WAV fmt chunk has no byte rate")
+			}
+			// Offsets are rounded down to whole sample frames
+			offset := func(d time.Duration) int64 {
+				return int64(d.Seconds()*float64(byteRate)) / blockAlign * blockAlign
+			}
+			available := int64(size)
+			// Recorders that were interrupted leave the size unset
+			if size == 0 || size == 0xFFFFFFFF {
+				info, err := f.Stat()
+				if err != nil {
+					return err
+				}
+				current, _ := f.Seek(0, io.SeekCurrent)
+				available = info.Size() - current
+			}
+			start, end := offset(from), offset(to)
+			if end > available {
+				end = available
+			}
+			if end < start {
+				end = start
+			}
+			if _, err := f.Seek(start, io.SeekCurrent); err != nil {
+				return fmt.Errorf("failed to seek WAV data: %w", err)
+			}
+			return writeWAV(dst, format, io.LimitReader(f, end-start), end-start)
+		}
+
+		if _, err := f.Seek(int64(size)+int64(size%2), io.SeekCurrent); err != nil {
+			return fmt.Errorf("failed to skip WAV %q chunk: %w", id, err)
+		}
+	}
+}
+
+// writeWAV writes a WAV file of the given fmt chunk and size bytes of data
+func writeWAV(path string, format []byte, data io.Reader, size int64) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, 20+len(format))
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+len(format)+len(format)%2+8+int(size)+int(size%2)))
+	header = append(header, "WAVE"...)
+	header = append(header, "fmt "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(format)))
+	header = append(header, format...)
+	if len(format)%2 == 1 {
+		header = append(header, 0)
+	}
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(size))
+
+	_, err = out.Write(header)
+	if err == nil {
+		var written int64
+		written, err = io.Copy(out, data)
+		if err == nil && written < size {
+			err = fmt.Errorf("WAV data ends after %d of %d bytes", written, size)
+		}
+	}
+	if err == nil && size%2 == 1 {
+		_, err = out.Write([]byte{0})
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package transcribe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestWAV writes a 16 kHz mono 16-bit WAV of the given length whose
+// samples count up, so cuts can be checked by their first sample
+func writeTestWAV(t *testing.T, path string, length time.Duration) {
+	t.Helper()
+	const sampleRate = 16000
+	samples := int(length.Seconds() * sampleRate)
+	data := new(bytes.Buffer)
+	for i := 0; i < samples; i++ {
+		binary.Write(data, binary.LittleEndian, uint16(i))
+	}
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:2], 1)
+	binary.LittleEndian.PutUint16(format[2:4], 1)
+	binary.LittleEndian.PutUint32(format[4:8], sampleRate)
+	binary.LittleEndian.PutUint32(format[8:12], sampleRate*2)
+	binary.LittleEndian.PutUint16(format[12:14], 2)
+	binary.LittleEndian.PutUint16(format[14:16], 16)
+	if err := writeWAV(path, format, data, int64(data.Len())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCutWAV(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "meeting.wav")
+	writeTestWAV(t, source, 3*time.Second)
+
+	tests := []struct {
+		name       string
+		from, to   time.Duration
+		length     time.Duration
+		firstValue uint16
+	}{
+		{"middle", time.Second, 2 * time.Second, time.Second, 16000},
+		{"start", 0, 500 * time.Millisecond, 500 * time.Millisecond, 0},
+		{"past the end", 2500 * time.Millisecond, 10 * time.Second, 500 * time.Millisecond, 40000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			part := filepath.Join(dir, test.name+".wav")
+			if err := CutWAV(source, part, test.from, test.to); err != nil {
+				t.Fatal(err)
+			}
+			length, err := WAVDuration(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if length != test.length {
+				t.Errorf("cut is %s long, want %s", length, test.length)
+			}
+			data, err := os.ReadFile(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first := binary.LittleEndian.Uint16(data[44:46]); first != test.firstValue {
+				t.Errorf("cut starts at sample %d, want %d", first, test.firstValue)
+			}
+		})
+	}
+}
+
+func TestCutWAVRejectsOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "meeting.mp3")
+	if err := os.WriteFile(source, []byte("ID3\x04not a wav file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CutWAV(source, filepath.Join(dir, "part.wav"), 0, time.Second); err == nil {
+		t.Error("CutWAV succeeded, want an error")
+	}
+}
@@ -0,0 +1,107 @@
+package transcribe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/command"
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// WhisperCpp transcribes locally with a whisper.cpp binary, so audio never
+// leaves the machine. Builds without ffmpeg support read only 16 kHz WAV.
+type WhisperCpp struct {
+	binary string
+	config *config.TranscriptionConfig
+}
+
+// NewWhisperCpp resolves the whisper.cpp binary from transcription.binary,
+// searching PATH for bare names
+func NewWhisperCpp(cfg *config.TranscriptionConfig) (*WhisperCpp, error) {
+	binary, err := exec.LookPath(cfg.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("whisper.cpp binary %q not found: %w", cfg.Binary, err)
+	}
+	return &WhisperCpp{binary: binary, config: cfg}, nil
+}
+
+// whisperOutput is the part of whisper.cpp's -oj output that is used
+type whisperOutput struct {
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"`
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text string `json:"text"`
+	} `json:"transcription"`
+}
+
+// Transcribe implements Transcriber
+func (w *WhisperCpp) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	dir, err := os.MkdirTemp("", "bridge-whisper-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	language := w.config.Language
+	if language == "" {
+		language = "auto"
+	}
+	output := filepath.Join(dir, "transcript")
+	cmd := exec.CommandContext(ctx, w.binary,
+		"-m", w.config.ModelPath,
+		"-f", path,
+		"-l", language,
+		"-oj", "-of", output,
+		"-np")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("whisper.cpp timed out on %s: %w", path, ctx.Err())
+		}
+		return nil, fmt.Errorf("whisper.cpp failed on %s: %w: %s", path, err, command.LastLine(string(out)))
+	}
+
+	data, err := os.ReadFile(output + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("whisper.cpp wrote no transcript for %s: %w", path, err)
+	}
+	var parsed whisperOutput
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse whisper.cpp output for %s: %w", path, err)
+	}
+
+	transcript := &Transcript{Language: parsed.Result.Language}
+	for _, segment := range parsed.Transcription {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			transcript.Segments = append(transcript.Segments, Segment{
+				Start: time.Duration(segment.Offsets.From) * time.Millisecond,
+				End:   time.Duration(segment.Offsets.To) * time.Millisecond,
+				Text:  text,
+			})
+		}
+	}
+	if transcript.Language == "" || transcript.Language == "auto" {
+		transcript.Language = w.config.Language
+	}
+	return transcript, nil
+}
+
+// Name implements Transcriber
+func (w *WhisperCpp) Name() string {
+	return "whisper_cpp"
+}
+
+// Binary returns the resolved path of the whisper.cpp executable
+func (w *WhisperCpp) Binary() string {
+	return w.binary
+}
@@ -0,0 +1,108 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/command"
+)
+
+// sampledFrame is a frame image written by ffmpeg
+type sampledFrame struct {
+	path   string
+	offset time.Duration
+}
+
+var (
+	// "Duration: 00:01:02.50, start: 0.000000, bitrate: 1205 kb/s"
+	durationPattern = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+	// "Stream #0:0(und): Video: h264 (High), yuv420p(progressive), 1920x1080 [SAR 1:1 DAR 16:9], ..."
+	resolutionPattern = regexp.MustCompile(`, (\d{2,5})x(\d{2,5})[ ,\[]`)
+	// "[Parsed_showinfo_1 @ 0x...] n:   0 pts:      0 pts_time:0 ..."
+	ptsTimePattern = regexp.MustCompile(`pts_time:\s*(-?[0-9.]+)`)
+)
+
+// sample runs ffmpeg once to write the selected frames into dir as PNG and
+// reads the duration, resolution and frame times from its log
+func (e *Extractor) sample(ctx context.Context, path, dir string) (*Recording, []sampledFrame, error) {
+	var filter string
+	switch e.config.Sampling {
+	case "interval":
+		filter = fmt.Sprintf("fps=1/%g,showinfo", e.config.Interval.Seconds())
+	default:
+		// Always keep the first frame; later ones only when the scene changes
+		filter = fmt.Sprintf("select='eq(n,0)+gt(scene,%g)',showinfo", e.config.SceneThreshold)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.ffmpeg,
+		"-hide_banner", "-nostdin",
+		"-i", path,
+		"-vf", filter,
+		"-vsync", "vfr",
+		"-frames:v", strconv.Itoa(e.config.MaxFrames),
+		filepath.Join(dir, "frame-%05d.png"))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("ffmpeg timed out on %s: %w", path, ctx.Err())
+		}
+		return nil, nil, fmt.Errorf("ffmpeg failed on %s: %w: %s", path, err, command.LastLine(stderr.String()))
+	}
+
+	recording, offsets := parseLog(stderr.String())
+
+	files, err := filepath.Glob(filepath.Join(dir, "frame-*.png"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(files)
+
+	frames := make([]sampledFrame, 0, len(files))
+	for i, file := range files {
+		frame := sampledFrame{path: file}
+		if i < len(offsets) {
+			frame.offset = offsets[i]
+		}
+		frames = append(frames, frame)
+	}
+	return recording, frames, nil
+}
+
+// parseLog reads the input's duration and resolution and the time of every
+// frame showinfo reported from ffmpeg's log
+func parseLog(log string) (*Recording, []time.Duration) {
+	recording := &Recording{}
+	if m := durationPattern.FindStringSubmatch(log); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		recording.Duration = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+			time.Duration(seconds*float64(time.Second))
+	}
+
+	offsets := []time.Duration{}
+	for _, line := range strings.Split(log, "\n") {
+		switch {
+		case recording.Width == 0 && strings.Contains(line, "Stream #") && strings.Contains(line, "Video:"):
+			if m := resolutionPattern.FindStringSubmatch(line); m != nil {
+				recording.Width, _ = strconv.Atoi(m[1])
+				recording.Height, _ = strconv.Atoi(m[2])
+			}
+		case strings.Contains(line, "Parsed_showinfo"):
+			if m := ptsTimePattern.FindStringSubmatch(line); m != nil {
+				seconds, _ := strconv.ParseFloat(m[1], 64)
+				offsets = append(offsets, time.Duration(seconds*float64(time.Second)))
+			}
+		}
+	}
+	return recording, offsets
+}
@@ -0,0 +1,65 @@
+package video
+
+import (
+	"image"
+	"math/bits"
+)
+
+// dHash computes a 64-bit difference hash: the image is reduced to 9x8
+// grayscale cells and each bit records whether a cell is darker than its
+// right neighbour. Frames that look alike have hashes a few bits apart,
+// regardless of small rendering differences or compression noise.
+func dHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	bounds := img.Bounds()
+
+	var cells [height][width]float64
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			cells[y][x] = averageLuma(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if cells[y][x] < cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuma averages the brightness of a block of pixels, sampling at most
+// 16x16 of them so full-resolution frames stay cheap to hash
+func averageLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	stepX := max(1, (x1-x0)/16)
+	stepY := max(1, (y1-y0)/16)
+
+	sum, n := 0.0, 0
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+// distance is the number of bits in which two hashes differ
+func distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
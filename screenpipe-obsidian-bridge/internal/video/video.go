@@ -0,0 +1,159 @@
+// Package video turns screen recordings into time-coded text: ffmpeg samples
+// frames at scene changes or fixed intervals, near-identical frames are
+// dropped by perceptual hash, and the rest go through OCR.
+package video
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
+	"screenpipe-obsidian-bridge/internal/ocr"
+)
+
+var logger = logging.For(logging.ComponentVideo)
+
+// Recording is what was read from one screen recording
+type Recording struct {
+	Duration time.Duration
+	Width    int
+	Height   int
+	// Frames are the distinct frames that contained text, in order
+	Frames []Frame
+}
+
+// Frame is the text recognized in a frame and where it is in the recording
+type Frame struct {
+	Offset time.Duration
+	Text   string
+	// Image is the frame as PNG, kept only when asked for
+	Image []byte
+}
+
+// Extractor reads screen recordings. It is usable without ffmpeg or
+// tesseract installed; Available then says why recordings are skipped.
+type Extractor struct {
+	config *config.VideoConfig
+	ffmpeg string
+	ocr    *ocr.Tesseract
+	// missing is why recordings cannot be read, nil when they can
+	missing error
+}
+
+// New creates an extractor, resolving the ffmpeg and tesseract binaries
+func New(cfg *config.VideoConfig, ocrCfg *config.OCRConfig) *Extractor {
+	e := &Extractor{config: cfg}
+
+	ffmpeg, err := exec.LookPath(cfg.FFmpeg)
+	if err != nil {
+		e.missing = fmt.Errorf("ffmpeg binary %q not found: %w", cfg.FFmpeg, err)
+		return e
+	}
+	e.ffmpeg = ffmpeg
+
+	if e.ocr, err = ocr.NewTesseract(ocrCfg); err != nil {
+		e.missing = err
+	}
+	return e
+}
+
+// Available returns why recordings cannot be read, or nil if they can
+func (e *Extractor) Available() error {
+	return e.missing
+}
+
+// FFmpeg returns the resolved path of the ffmpeg executable, empty when it
+// was not found
+func (e *Extractor) FFmpeg() string {
+	return e.ffmpeg
+}
+
+// Extract samples frames from a recording, drops near-duplicates and
+// recognizes the text in the rest. With keepImages the kept frames' images
+// are returned too, e.g. to store them as keyframes.
+func (e *Extractor) Extract(ctx context.Context, path string, keepImages bool) (*Recording, error) {
+	if e.missing != nil {
+		return nil, e.missing
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "bridge-frames-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	recording, sampled, err := e.sample(ctx, path, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var previousHash uint64
+	previousText := ""
+	duplicates := 0
+	for i, frame := range sampled {
+		hash, err := hashFile(frame.path)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot read sampled frame", "path", path, "frame", frame.path, "error", err)
+			continue
+		}
+		if i > 0 && distance(hash, previousHash) <= e.config.DedupeDistance {
+			duplicates++
+			metrics.VideoFrames.With("duplicate").Inc()
+			continue
+		}
+		previousHash = hash
+
+		text, err := e.ocr.Recognize(ctx, frame.path)
+		if err != nil {
+			return nil, err
+		}
+		// Scrolling and cursor movement change the picture but not the text
+		if text == "" || text == previousText {
+			metrics.VideoFrames.With("no_new_text").Inc()
+			continue
+		}
+		previousText = text
+
+		kept := Frame{Offset: frame.offset, Text: text}
+		if keepImages {
+			if kept.Image, err = os.ReadFile(frame.path); err != nil {
+				return nil, fmt.Errorf("failed to read sampled frame: %w", err)
+			}
+		}
+		metrics.VideoFrames.With("kept").Inc()
+		recording.Frames = append(recording.Frames, kept)
+	}
+
+	logger.DebugContext(ctx, "read screen recording",
+		"path", path,
+		"duration", recording.Duration.Round(time.Second),
+		"resolution", fmt.Sprintf("%dx%d", recording.Width, recording.Height),
+		"sampled", len(sampled),
+		"duplicates", duplicates,
+		"frames", len(recording.Frames))
+	return recording, nil
+}
+
+// hashFile computes the perceptual hash of an image file
+func hashFile(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+	return dHash(img), nil
+}
@@ -0,0 +1,92 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const ffmpegLog = `ffmpeg version 6.1 Copyright (c) 2000-2023 the FFmpeg developers
+Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'monitor_1.mp4':
+  Metadata:
+    encoder         : Lavf60.16.100
+  Duration: 00:01:02.50, start: 0.000000, bitrate: 182 kb/s
+  Stream #0:0[0x1](und): Video: h264 (High) (avc1 / 0x31637661), yuv420p(progressive), 1920x1080 [SAR 1:1 DAR 16:9], 180 kb/s, 1 fps, 1 tbr, 16384 tbn (default)
+Stream mapping:
+  Stream #0:0 -> #0:0 (h264 (native) -> png (native))
+Output #0, image2, to 'frame-%05d.png':
+  Stream #0:0(und): Video: png, rgb24(pc, gbr/unknown/unknown, progressive), 960x540 [SAR 1:1 DAR 16:9], q=2-31, 200 kb/s, 1 fps, 1 tbn (default)
+[Parsed_showinfo_1 @ 0x55d5] config in time_base: 1/16384, frame_rate: 1/1
+[Parsed_showinfo_1 @ 0x55d5] n:   0 pts:      0 pts_time:0       duration:  16384 fmt:yuv420p
+[Parsed_showinfo_1 @ 0x55d5] n:   1 pts: 245760 pts_time:15      duration:  16384 fmt:yuv420p
+[Parsed_showinfo_1 @ 0x55d5] n:   2 pts: 761856 pts_time:46.5    duration:  16384 fmt:yuv420p
+frame=    3 fps=0.0 q=-0.0 Lsize=N/A time=00:00:47.50 bitrate=N/A speed= 102x
+`
+
+func TestParseLog(t *testing.T) {
+	recording, offsets := parseLog(ffmpegLog)
+	if want := time.Minute + 2500*time.Millisecond; recording.Duration != want {
+		t.Errorf("duration = %s, want %s", recording.Duration, want)
+	}
+	// The input's resolution, not the scaled output's
+	if recording.Width != 1920 || recording.Height != 1080 {
+		t.Errorf("resolution = %dx%d, want 1920x1080", recording.Width, recording.Height)
+	}
+	if want := []time.Duration{0, 15 * time.Second, 46500 * time.Millisecond}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets = %v, want %v", offsets, want)
+	}
+
+	recording, offsets = parseLog("monitor_1.mp4: Invalid data found when processing input\n")
+	if recording.Duration != 0 || recording.Width != 0 || len(offsets) != 0 {
+		t.Errorf("parsed %+v and %v from a log without input details", recording, offsets)
+	}
+}
+
+// gradient draws a screen with a bright bar at column bar, plus noise
+// derived from seed to stand in for compression artifacts
+func gradient(width, height, bar int, seed uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(x * 150 / width)
+			if x/(width/8) == bar {
+				v += 100
+			}
+			noise := (uint8(x*7+y*13) ^ seed) % 4
+			img.SetGray(x, y, color.Gray{Y: v - noise})
+		}
+	}
+	return img
+}
+
+func TestDHash(t *testing.T) {
+	frame := gradient(640, 360, 2, 0)
+	if a, b := dHash(frame), dHash(gradient(640, 360, 2, 0)); a != b {
+		t.Errorf("identical frames hash differently: %016x, %016x", a, b)
+	}
+	if d := distance(dHash(frame), dHash(gradient(640, 360, 2, 3))); d > 4 {
+		t.Errorf("noisy copy is %d bits away, want at most 4", d)
+	}
+	if d := distance(dHash(frame), dHash(gradient(1280, 720, 2, 0))); d > 4 {
+		t.Errorf("rescaled copy is %d bits away, want at most 4", d)
+	}
+	if d := distance(dHash(frame), dHash(gradient(640, 360, 6, 0))); d < 10 {
+		t.Errorf("different frame is only %d bits away", d)
+	}
+
+	// Images smaller than the hash grid still hash
+	tiny := image.NewGray(image.Rect(0, 0, 3, 3))
+	tiny.SetGray(2, 1, color.Gray{Y: 255})
+	dHash(tiny)
+}
+
+func TestDistance(t *testing.T) {
+	if d := distance(0, 0); d != 0 {
+		t.Errorf("distance(0, 0) = %d", d)
+	}
+	if d := distance(0xff00, 0x0f0f); d != 8 {
+		t.Errorf("distance(0xff00, 0x0f0f) = %d, want 8", d)
+	}
+}
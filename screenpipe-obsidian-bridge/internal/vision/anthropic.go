@@ -0,0 +1,159 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+const (
+	// anthropicEndpoint is the base URL of Anthropic's API
+	anthropicEndpoint = "https://api.anthropic.com/v1"
+	// anthropicVersion is the Messages API version requested
+	anthropicVersion = "2023-06-01"
+)
+
+// Anthropic describes screenshots with image inputs to Claude's Messages API
+type Anthropic struct {
+	client   *http.Client
+	endpoint string
+	config   *config.VisionConfig
+	keys     credentials.Provider
+}
+
+// NewAnthropic creates a describer for vision.endpoint, defaulting to
+// Anthropic's API. The API key is asked from keys on every request.
+func NewAnthropic(cfg *config.VisionConfig, keys credentials.Provider) *Anthropic {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = anthropicEndpoint
+	}
+	return &Anthropic{
+		client:   &http.Client{},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		config:   cfg,
+		keys:     keys,
+	}
+}
+
+// anthropicRequest is the subset of a Messages API request that is used
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []anthropicContent `json:"content"`
+}
+
+type anthropicContent struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *anthropicSource `json:"source,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// anthropicResponse is the subset of a Messages API response that is read
+type anthropicResponse struct {
+	Content []anthropicContent `json:"content"`
+	Usage   struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Describe implements Describer
+func (a *Anthropic) Describe(ctx context.Context, img *Image) (*Description, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     a.config.Model,
+		MaxTokens: a.config.MaxTokens,
+		Messages: []anthropicMessage{{
+			Role: "user",
+			Content: []anthropicContent{
+				{Type: "image", Source: &anthropicSource{
+					Type:      "base64",
+					MediaType: img.MediaType,
+					Data:      base64.StdEncoding.EncodeToString(img.Data),
+				}},
+				{Type: "text", Text: prompt},
+			},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.keys.Key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", key)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe screenshot: %w", err)
+	}
+	defer resp.Body.Close()
+	a.keys.Report(key, resp.StatusCode)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	var response anthropicResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to describe screenshot: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if resp.StatusCode != http.StatusOK {
+		if response.Error != nil {
+			return nil, fmt.Errorf("failed to describe screenshot: status %d: %s: %s", resp.StatusCode, response.Error.Type, response.Error.Message)
+		}
+		return nil, fmt.Errorf("failed to describe screenshot: status %d", resp.StatusCode)
+	}
+
+	var text strings.Builder
+	for _, content := range response.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	return &Description{
+		Text: text.String(),
+		Usage: llm.TokenUsage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// Name implements Describer
+func (a *Anthropic) Name() string {
+	return "anthropic"
+}
@@ -0,0 +1,69 @@
+package vision
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"screenpipe-obsidian-bridge/internal/imaging"
+)
+
+// Image is a screenshot ready to send to a model
+type Image struct {
+	Data []byte
+	// MediaType is image/png or image/jpeg
+	MediaType string
+	// Width and Height are the dimensions of the original screenshot
+	Width  int
+	Height int
+}
+
+// Prepare reads a screenshot for upload. Images that are larger than
+// maxDimension on their longer side, or than maxBytes, are downscaled and
+// re-encoded as JPEG; the rest are sent as they are.
+func Prepare(path string, maxDimension, maxBytes int) (*Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	header, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %w", path, err)
+	}
+	prepared := &Image{Width: header.Width, Height: header.Height}
+
+	longest := max(header.Width, header.Height)
+	if longest <= maxDimension && len(data) <= maxBytes && (format == "png" || format == "jpeg") {
+		prepared.Data = data
+		prepared.MediaType = "image/" + format
+		return prepared, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+	// Shrink until the encoded image fits; text stays legible well below
+	// the default limits
+	for dimension := min(longest, maxDimension); dimension >= 64; dimension = dimension * 3 / 4 {
+		encoded, err := imaging.Downscale(img, dimension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode image %s: %w", path, err)
+		}
+		if len(encoded) <= maxBytes {
+			width, height := imaging.Fit(header.Width, header.Height, dimension)
+			prepared.Data = encoded
+			prepared.MediaType = "image/jpeg"
+			logger.Debug("downscaled screenshot",
+				"path", path,
+				"from", fmt.Sprintf("%dx%d", header.Width, header.Height),
+				"to", fmt.Sprintf("%dx%d", width, height),
+				"bytes", len(encoded))
+			return prepared, nil
+		}
+	}
+	return nil, fmt.Errorf("image %s does not fit in %d KB even when downscaled", path, maxBytes/1024)
+}
@@ -0,0 +1,80 @@
+package vision
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+// OpenAI describes screenshots with image inputs to the chat completions
+// API, which OpenAI and local servers such as Ollama or LM Studio accept
+type OpenAI struct {
+	client *openai.Client
+	config *config.VisionConfig
+}
+
+// NewOpenAI creates a describer for vision.endpoint, falling back to
+// llm.endpoint. Like the LLM client it asks keys for a key on every request.
+func NewOpenAI(cfg *config.VisionConfig, llmCfg *config.LLMConfig, keys credentials.Provider) *OpenAI {
+	clientConfig := openai.DefaultConfig("")
+	clientConfig.HTTPClient = credentials.NewHTTPClient(keys)
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = llmCfg.Endpoint
+	}
+	if endpoint != "" {
+		clientConfig.BaseURL = strings.TrimSuffix(endpoint, "/")
+	}
+
+	return &OpenAI{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: cfg,
+	}
+}
+
+// Describe implements Describer
+func (o *OpenAI) Describe(ctx context.Context, img *Image) (*Description, error) {
+	dataURL := "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+	response, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: o.config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleUser,
+				MultiContent: []openai.ChatMessagePart{
+					{Type: openai.ChatMessagePartTypeText, Text: prompt},
+					{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{
+						URL:    dataURL,
+						Detail: openai.ImageURLDetailAuto,
+					}},
+				},
+			},
+		},
+		MaxTokens: o.config.MaxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe screenshot: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned")
+	}
+	return &Description{
+		Text: response.Choices[0].Message.Content,
+		Usage: llm.TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// Name implements Describer
+func (o *OpenAI) Name() string {
+	return "openai"
+}
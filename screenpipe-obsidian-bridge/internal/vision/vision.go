@@ -0,0 +1,132 @@
+// Package vision describes screenshots with a vision-capable model, either
+// OpenAI (or a local OpenAI-compatible server) or Anthropic's Claude, to add
+// what text recognition cannot see.
+package vision
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/credentials"
+	"screenpipe-obsidian-bridge/internal/llm"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
+)
+
+var logger = logging.For(logging.ComponentVision)
+
+// prompt asks for the parts of a screenshot that OCR misses; the recognized
+// text reaches the analysis prompts separately
+const prompt = `Describe this screenshot for an activity log. Name the application and what is on screen, what the user appears to be doing, and any charts, images or interface state that text recognition would miss. Do not transcribe long passages of text. Answer in at most three short paragraphs.`
+
+// Describer describes the content of a screenshot
+type Describer interface {
+	Describe(ctx context.Context, img *Image) (*Description, error)
+	// Name identifies the implementation in logs and metrics
+	Name() string
+}
+
+// Description is what the model said about a screenshot, with the tokens
+// it took
+type Description struct {
+	Text  string
+	Usage llm.TokenUsage
+}
+
+// New creates the describer for the configured provider, or nil when vision
+// is disabled. Requests authenticate with vision.credentials, or with keys,
+// the LLM credentials, when none are configured. Like LLM calls, every
+// request is recorded in auditLog and the token and cost metrics, priced
+// with llm.pricing.
+func New(cfg *config.VisionConfig, llmCfg *config.LLMConfig, keys credentials.Provider, securityCfg *config.SecurityConfig, auditLog *audit.Logger) (Describer, error) {
+	if cfg.Provider == "" {
+		return nil, nil
+	}
+	if len(cfg.Credentials) > 0 {
+		var err error
+		if keys, err = credentials.NewSources("vision.credentials", cfg.Credentials, securityCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var describer Describer
+	switch cfg.Provider {
+	case "openai":
+		describer = NewOpenAI(cfg, llmCfg, keys)
+	case "anthropic":
+		describer = NewAnthropic(cfg, keys)
+	default:
+		return nil, fmt.Errorf("unsupported vision provider: %s", cfg.Provider)
+	}
+	return &measured{Describer: describer, config: cfg, pricing: llmCfg.Pricing, auditLog: auditLog}, nil
+}
+
+// measured bounds each description by the configured timeout, records
+// metrics and writes an audit record
+type measured struct {
+	Describer
+	config   *config.VisionConfig
+	pricing  map[string]config.ModelPrice
+	auditLog *audit.Logger
+}
+
+// Describe implements Describer
+func (m *measured) Describe(ctx context.Context, img *Image) (*Description, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	description, err := m.Describer.Describe(ctx, img)
+	elapsed := time.Since(start)
+	metrics.VisionDuration.With(m.Name(), m.config.Model).Observe(elapsed.Seconds())
+	if err != nil {
+		err = credentials.RedactError(err)
+	}
+
+	sum := sha256.Sum256(img.Data)
+	record := audit.Record{
+		Timestamp:      start.UTC(),
+		Provider:       m.Name(),
+		Model:          m.config.Model,
+		PromptTemplate: llm.TemplateScreenshotDescription,
+		SourceFile:     audit.SourceFile(ctx, ""),
+		ContentHash:    hex.EncodeToString(sum[:]),
+		CorrelationID:  logging.CorrelationID(ctx),
+		LatencyMS:      elapsed.Milliseconds(),
+		Outcome:        audit.OutcomeSuccess,
+		Prompt:         prompt,
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	} else {
+		description.Text = strings.TrimSpace(description.Text)
+		record.Response = description.Text
+		record.TokenUsage = audit.TokenUsage{
+			PromptTokens:     description.Usage.PromptTokens,
+			CompletionTokens: description.Usage.CompletionTokens,
+			TotalTokens:      description.Usage.TotalTokens,
+		}
+	}
+	llm.RecordCall(ctx, m.auditLog, m.pricing, record)
+	if err != nil {
+		metrics.VisionRequests.With(m.Name(), m.config.Model, "error").Inc()
+		return nil, err
+	}
+	metrics.VisionRequests.With(m.Name(), m.config.Model, "success").Inc()
+
+	logger.DebugContext(ctx, "described screenshot",
+		"provider", m.Name(),
+		"model", m.config.Model,
+		"bytes", len(img.Data),
+		"characters", len(description.Text),
+		"total_tokens", description.Usage.TotalTokens,
+		"latency_ms", elapsed.Milliseconds())
+	return description, nil
+}
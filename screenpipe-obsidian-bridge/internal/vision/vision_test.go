@@ -0,0 +1,107 @@
+package vision
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"screenpipe-obsidian-bridge/internal/audit"
+	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/llm"
+)
+
+// stubDescriber answers with a fixed description or error
+type stubDescriber struct {
+	description Description
+	err         error
+}
+
+func (s *stubDescriber) Describe(ctx context.Context, img *Image) (*Description, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	description := s.description
+	return &description, nil
+}
+
+func (s *stubDescriber) Name() string {
+	return "stub"
+}
+
+func TestMeasuredAudits(t *testing.T) {
+	tests := []struct {
+		name     string
+		stub     *stubDescriber
+		outcome  string
+		response string
+		tokens   int
+	}{
+		{
+			name: "success",
+			stub: &stubDescriber{description: Description{
+				Text:  "  A Gmail inbox with 3 unread messages.\n",
+				Usage: llm.TokenUsage{PromptTokens: 800, CompletionTokens: 40, TotalTokens: 840},
+			}},
+			outcome:  audit.OutcomeSuccess,
+			response: "A Gmail inbox with 3 unread messages.",
+			tokens:   840,
+		},
+		{
+			name:    "error with a key in it",
+			stub:    &stubDescriber{err: errors.New("invalid key sk-proj-abcdefghijklmnopqrstuvwx")},
+			outcome: audit.OutcomeError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+			auditLog, err := audit.New(&config.SecurityConfig{
+				EnableRequestLogging: true,
+				RequestLog:           config.RequestLogConfig{Path: logPath, MaxSizeMB: 1, IncludeContent: true},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			describer := &measured{
+				Describer: test.stub,
+				config:    &config.VisionConfig{Model: "gpt-4o", Timeout: time.Minute},
+				auditLog:  auditLog,
+			}
+
+			ctx := audit.WithSourceFile(context.Background(), "/captures/inbox.png")
+			description, err := describer.Describe(ctx, &Image{Data: []byte("png"), MediaType: "image/png"})
+			if (err != nil) != (test.outcome == audit.OutcomeError) {
+				t.Fatalf("Describe error = %v", err)
+			}
+			if err == nil && description.Text != test.response {
+				t.Errorf("description %q, want %q", description.Text, test.response)
+			}
+			auditLog.Close()
+
+			records, err := audit.Search(logPath, audit.Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("%d audit records, want 1", len(records))
+			}
+			record := records[0]
+			if record.Provider != "stub" || record.Model != "gpt-4o" || record.PromptTemplate != llm.TemplateScreenshotDescription {
+				t.Errorf("record names %s/%s/%s", record.Provider, record.Model, record.PromptTemplate)
+			}
+			if record.SourceFile != "/captures/inbox.png" || record.ContentHash == "" {
+				t.Errorf("record source %q, hash %q", record.SourceFile, record.ContentHash)
+			}
+			if record.Outcome != test.outcome || record.Response != test.response || record.TokenUsage.TotalTokens != test.tokens {
+				t.Errorf("record outcome %s, response %q, tokens %d", record.Outcome, record.Response, record.TokenUsage.TotalTokens)
+			}
+			if strings.Contains(record.Error, "abcdefghijklmnop") {
+				t.Errorf("error %q was not redacted", record.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// debouncer holds file events back until the file has stopped changing.
+// Bursts of Write and Create events for one path collapse into a single
+// event, sent once the file's size and modification time have stayed the
+// same for the quiet period.
+type debouncer struct {
+	quiet time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingFile
+}
+
+// pendingFile is a file waiting to settle
+type pendingFile struct {
+	operation string
+	state     fileState
+	// changedAt is when the file was last seen changing
+	changedAt time.Time
+	// notBefore delays files that are re-checked after being dropped
+	notBefore time.Time
+}
+
+// newDebouncer creates a debouncer with the given quiet period
+func newDebouncer(quiet time.Duration) *debouncer {
+	return &debouncer{
+		quiet:   quiet,
+		pending: make(map[string]*pendingFile),
+	}
+}
+
+// touch records activity on a file, restarting its quiet period. The first
+// operation seen is kept, so a Create followed by writes stays a Create.
+func (d *debouncer) touch(path, operation string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, _ := statFile(path)
+	if file, ok := d.pending[path]; ok {
+		file.state = state
+		file.changedAt = now
+		return
+	}
+	d.pending[path] = &pendingFile{operation: operation, state: state, changedAt: now}
+}
+
+// retry re-checks a file after delay, e.g. one the event buffer had no
+// room for
+func (d *debouncer) retry(path string, delay time.Duration, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, _ := statFile(path)
+	d.pending[path] = &pendingFile{operation: "RETRY", state: state, changedAt: now, notBefore: now.Add(delay)}
+}
+
+// forget drops a pending file that was removed or renamed away
+func (d *debouncer) forget(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.pending, path)
+}
+
+// settled returns the files that have been quiet long enough, keyed by path
+// with their first operation, and drops files that no longer exist
+func (d *debouncer) settled(now time.Time) map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ready := map[string]string{}
+	for path, file := range d.pending {
+		if now.Before(file.notBefore) || now.Sub(file.changedAt) < d.quiet {
+			continue
+		}
+
+		state, err := statFile(path)
+		if err != nil {
+			// Renamed away or deleted before it settled
+			delete(d.pending, path)
+			continue
+		}
+		if state != file.state {
+			// Still being written without events, e.g. on a polled directory
+			file.state = state
+			file.changedAt = now
+			continue
+		}
+
+		ready[path] = file.operation
+		delete(d.pending, path)
+	}
+	return ready
+}
+
+// size returns the number of files waiting to settle
+func (d *debouncer) size() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.pending)
+}
+
+// statFile returns the size and modification time of a file
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime()}, nil
+}
@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDebounceSettles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ocr.txt")
+	writeFile(t, path, "first")
+
+	d := newDebouncer(time.Second)
+	start := time.Now()
+	d.touch(path, "CREATE", start)
+	d.touch(path, "WRITE", start.Add(500*time.Millisecond))
+
+	if got := d.settled(start.Add(time.Second)); len(got) != 0 {
+		t.Errorf("settled within the quiet period of the last write: %v", got)
+	}
+	got := d.settled(start.Add(1500 * time.Millisecond))
+	if want := map[string]string{path: "CREATE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("settled = %v, want %v", got, want)
+	}
+	if d.size() != 0 {
+		t.Errorf("%d files still pending after settling", d.size())
+	}
+}
+
+func TestDebounceGrowingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audio.txt")
+	writeFile(t, path, "part")
+
+	d := newDebouncer(time.Second)
+	start := time.Now()
+	d.touch(path, "CREATE", start)
+
+	// The file keeps growing without events, as on a polled directory
+	writeFile(t, path, "part two")
+	if got := d.settled(start.Add(2 * time.Second)); len(got) != 0 {
+		t.Errorf("settled while still growing: %v", got)
+	}
+	if got := d.settled(start.Add(2500 * time.Millisecond)); len(got) != 0 {
+		t.Errorf("settled before a quiet period since the last change: %v", got)
+	}
+	got := d.settled(start.Add(3 * time.Second))
+	if want := map[string]string{path: "CREATE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("settled = %v, want %v", got, want)
+	}
+}
+
+func TestDebounceRenameIntoPlace(t *testing.T) {
+	dir := t.TempDir()
+	temp := filepath.Join(dir, ".ocr.txt.part")
+	final := filepath.Join(dir, "ocr.txt")
+	writeFile(t, temp, "text")
+
+	d := newDebouncer(time.Second)
+	start := time.Now()
+	d.touch(temp, "CREATE", start)
+
+	// The watcher forgets the old name and sees a Create for the new one
+	if err := os.Rename(temp, final); err != nil {
+		t.Fatal(err)
+	}
+	d.forget(temp)
+	d.touch(final, "CREATE", start.Add(100*time.Millisecond))
+
+	got := d.settled(start.Add(2 * time.Second))
+	if want := map[string]string{final: "CREATE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("settled = %v, want %v", got, want)
+	}
+}
+
+func TestDebounceDropsVanishedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ocr.txt")
+	writeFile(t, path, "text")
+
+	d := newDebouncer(time.Second)
+	start := time.Now()
+	d.touch(path, "CREATE", start)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := d.settled(start.Add(2 * time.Second)); len(got) != 0 {
+		t.Errorf("settled a removed file: %v", got)
+	}
+	if d.size() != 0 {
+		t.Errorf("removed file still pending")
+	}
+}
+
+func TestDebounceRetry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ocr.txt")
+	writeFile(t, path, "text")
+
+	d := newDebouncer(time.Second)
+	start := time.Now()
+	d.retry(path, 5*time.Second, start)
+
+	if got := d.settled(start.Add(2 * time.Second)); len(got) != 0 {
+		t.Errorf("retried before the delay: %v", got)
+	}
+	got := d.settled(start.Add(5 * time.Second))
+	if want := map[string]string{path: "RETRY"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("settled = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,297 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// file is a matching file found while walking the output directory
+type file struct {
+	path    string
+	modTime time.Time
+}
+
+// fileState is what polling compares to notice a changed file
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// addTreeLocked watches root and every directory below it that is not
+// excluded. Directories beyond the platform's watch limit are polled instead.
+// Only a failure to watch root itself is returned. The caller holds w.mu.
+func (w *Watcher) addTreeLocked(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return fmt.Errorf("failed to add watch path %s: %w", root, err)
+			}
+			logger.Warn("cannot read directory, not watching it", "path", path, "error", err)
+			return fs.SkipDir
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && isExcluded(w.config, path) {
+			return fs.SkipDir
+		}
+		if w.watched[path] || w.polled[path] != nil {
+			return nil
+		}
+
+		err = w.watcher.Add(path)
+		switch {
+		case err == nil:
+			w.watched[path] = true
+		case isLimitError(err):
+			if len(w.polled) == 0 {
+				logger.Warn("file watch limit reached, polling the remaining directories",
+					"interval", w.config.PollInterval, "error", err)
+			}
+			w.polled[path] = scanDirectory(w.config, path)
+		case path == root:
+			return fmt.Errorf("failed to add watch path %s: %w", root, err)
+		default:
+			logger.Warn("cannot watch directory", "path", path, "error", err)
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
+
+// addDirectory starts watching a directory created after the watcher started,
+// and queues the matching files already in it since they were written
+// before the watch existed
+func (w *Watcher) addDirectory(dir string) {
+	w.mu.Lock()
+	cfg := w.config
+	if isExcluded(cfg, dir) || !isBelow(dir, cfg.OutputPath) {
+		w.mu.Unlock()
+		return
+	}
+	if err := w.addTreeLocked(dir); err != nil {
+		logger.Warn("cannot watch new directory", "path", dir, "error", err)
+	}
+	w.mu.Unlock()
+
+	logger.Debug("watching new directory", "path", dir)
+	now := time.Now()
+	for _, f := range matchingFiles(cfg, dir) {
+		w.debounce.touch(f.path, fsnotify.Create.String(), now)
+	}
+}
+
+// removeTreeLocked stops watching path and everything below it, e.g. after
+// the directory was deleted or renamed. The caller holds w.mu.
+func (w *Watcher) removeTreeLocked(path string) {
+	for dir := range w.watched {
+		if dir == path || isBelow(dir, path) {
+			// Deleted directories lose their watch on their own
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+			logger.Debug("stopped watching directory", "path", dir)
+		}
+	}
+	for dir := range w.polled {
+		if dir == path || isBelow(dir, path) {
+			delete(w.polled, dir)
+		}
+	}
+}
+
+// pruneLocked drops watches that are outside the configured tree, after the
+// output directory or exclude patterns changed. The caller holds w.mu.
+func (w *Watcher) pruneLocked() {
+	inTree := func(dir string) bool {
+		return dir == w.config.OutputPath || (isBelow(dir, w.config.OutputPath) && !isExcluded(w.config, dir))
+	}
+	for dir := range w.watched {
+		if !inTree(dir) {
+			w.watcher.Remove(dir)
+			delete(w.watched, dir)
+		}
+	}
+	for dir := range w.polled {
+		if !inTree(dir) {
+			delete(w.polled, dir)
+		}
+	}
+}
+
+// poll scans the directories without a native watch and reports new and
+// changed matching files, picking up new subdirectories as it goes
+func (w *Watcher) poll() {
+	w.mu.Lock()
+	if len(w.polled) == 0 {
+		w.mu.Unlock()
+		return
+	}
+
+	type change struct{ path, operation string }
+	changes := []change{}
+	newDirs := []string{}
+	for dir, seen := range w.polled {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				w.removeTreeLocked(dir)
+			}
+			continue
+		}
+
+		current := make(map[string]fileState, len(entries))
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if isExcluded(w.config, path) {
+				continue
+			}
+			if entry.IsDir() {
+				if !w.watched[path] && w.polled[path] == nil {
+					newDirs = append(newDirs, path)
+				}
+				continue
+			}
+			if !matchesPatterns(w.patterns, entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			state := fileState{size: info.Size(), modTime: info.ModTime()}
+			current[entry.Name()] = state
+			if previous, ok := seen[entry.Name()]; !ok {
+				changes = append(changes, change{path, fsnotify.Create.String()})
+			} else if previous != state {
+				changes = append(changes, change{path, fsnotify.Write.String()})
+			}
+		}
+		w.polled[dir] = current
+	}
+	for _, dir := range newDirs {
+		if err := w.addTreeLocked(dir); err != nil {
+			logger.Warn("cannot watch new directory", "path", dir, "error", err)
+		}
+	}
+	cfg := w.config
+	w.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range changes {
+		w.debounce.touch(c.path, c.operation, now)
+	}
+	for _, dir := range newDirs {
+		for _, f := range matchingFiles(cfg, dir) {
+			w.debounce.touch(f.path, fsnotify.Create.String(), now)
+		}
+	}
+}
+
+// scanDirectory records the matching files in a directory, so polling only
+// reports files that appear or change afterwards
+func scanDirectory(cfg *config.ScreenPipeConfig, dir string) map[string]fileState {
+	seen := map[string]fileState{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return seen
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !matchesPatterns(cfg.WatchPatterns, entry.Name()) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			seen[entry.Name()] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return seen
+}
+
+// Directories lists the output directory and every directory below it that
+// is not excluded, i.e. what the watcher needs to watch
+func Directories(cfg *config.ScreenPipeConfig) []string {
+	dirs := []string{}
+	filepath.WalkDir(cfg.OutputPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != cfg.OutputPath && isExcluded(cfg, path) {
+			return fs.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs
+}
+
+// matchingFiles lists the files below root that match the watch patterns and
+// are not excluded
+func matchingFiles(cfg *config.ScreenPipeConfig, root string) []file {
+	files := []file{}
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if entry != nil && entry.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path != root && isExcluded(cfg, path) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !matchesPatterns(cfg.WatchPatterns, entry.Name()) {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			files = append(files, file{path: path, modTime: info.ModTime()})
+		}
+		return nil
+	})
+	return files
+}
+
+// isExcluded reports whether a path below the output directory matches an
+// exclude pattern, by its name or by its slash-separated path relative to
+// the output directory
+func isExcluded(cfg *config.ScreenPipeConfig, path string) bool {
+	if len(cfg.ExcludePatterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(cfg.OutputPath, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(path)
+	for _, pattern := range cfg.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isBelow reports whether path is inside dir
+func isBelow(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isLimitError reports whether adding a watch failed because the platform's
+// watch or descriptor limit was reached
+func isLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
@@ -2,30 +2,55 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"screenpipe-obsidian-bridge/internal/config"
+	"screenpipe-obsidian-bridge/internal/logging"
+	"screenpipe-obsidian-bridge/internal/metrics"
 )
 
+var logger = logging.For(logging.ComponentWatcher)
+
+// eventRetryDelay is how long a file waits before it is sent again when the
+// event buffer was full
+const eventRetryDelay = time.Second
+
 // FileEvent represents a file system event
 type FileEvent struct {
 	Path      string
 	Operation string
 	Timestamp time.Time
+	// CorrelationID follows the file from detection to the written note
+	CorrelationID string
 }
 
-// Watcher monitors ScreenPipe output directory for changes
+// Watcher monitors the ScreenPipe output directory and every directory below
+// it for changes
 type Watcher struct {
+	watcher *fsnotify.Watcher
+	events  chan FileEvent
+	errors  chan error
+
+	// mu guards the settings a reload can change and the watched directories
+	mu       sync.RWMutex
 	config   *config.ScreenPipeConfig
-	watcher  *fsnotify.Watcher
-	events   chan FileEvent
-	errors   chan error
 	patterns []string
+	// watched holds the directories with a native watch
+	watched map[string]bool
+	// polled holds directories that could not get a native watch because the
+	// platform's watch limit was reached, with the files last seen in them
+	polled map[string]map[string]fileState
+
+	// debounce holds events back until their file stops changing
+	debounce *debouncer
 }
 
 // New creates a new file watcher
@@ -41,21 +66,31 @@ func New(cfg *config.ScreenPipeConfig) (*Watcher, error) {
 		events:   make(chan FileEvent, 100), // Buffer events
 		errors:   make(chan error, 10),
 		patterns: cfg.WatchPatterns,
+		watched:  make(map[string]bool),
+		polled:   make(map[string]map[string]fileState),
+		debounce: newDebouncer(cfg.QuietPeriod),
 	}
 
 	return w, nil
 }
 
-// Start begins monitoring the ScreenPipe output directory
+// Start begins monitoring the ScreenPipe output directory and its
+// subdirectories
 func (w *Watcher) Start(ctx context.Context) error {
-	// Add the ScreenPipe output directory to the watcher
-	err := w.watcher.Add(w.config.OutputPath)
+	w.mu.Lock()
+	err := w.addTreeLocked(w.config.OutputPath)
+	watched, polled := len(w.watched), len(w.polled)
+	w.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to add watch path %s: %w", w.config.OutputPath, err)
+		return err
 	}
 
-	log.Printf("Started watching ScreenPipe output directory: %s", w.config.OutputPath)
-	log.Printf("Watching for file patterns: %v", w.patterns)
+	logger.Info("started watching ScreenPipe output directory",
+		"path", w.config.OutputPath,
+		"patterns", w.patterns,
+		"exclude", w.config.ExcludePatterns,
+		"directories", watched,
+		"polled", polled)
 
 	// Start processing events in a goroutine
 	go w.processEvents(ctx)
@@ -63,10 +98,37 @@ func (w *Watcher) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stops the file watcher
+// Reconfigure switches to a new output directory, watch patterns and exclude
+// patterns without dropping the events already queued. On error the old
+// settings stay in place.
+func (w *Watcher) Reconfigure(cfg *config.ScreenPipeConfig) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous := w.config
+	if cfg.OutputPath != previous.OutputPath || !reflect.DeepEqual(cfg.ExcludePatterns, previous.ExcludePatterns) {
+		w.config = cfg
+		if err := w.addTreeLocked(cfg.OutputPath); err != nil {
+			// Drop whatever part of the new tree was added
+			w.config = previous
+			w.pruneLocked()
+			return err
+		}
+		w.pruneLocked()
+		if cfg.OutputPath != previous.OutputPath {
+			logger.Info("switched watched directory", "from", previous.OutputPath, "to", cfg.OutputPath)
+		}
+	}
+
+	w.config = cfg
+	w.patterns = cfg.WatchPatterns
+	return nil
+}
+
+// Stop stops the file watcher. The Events and Errors channels are closed
+// once event processing has ended, after the context passed to Start is
+// done or the watcher is stopped.
 func (w *Watcher) Stop() error {
-	close(w.events)
-	close(w.errors)
 	return w.watcher.Close()
 }
 
@@ -80,62 +142,132 @@ func (w *Watcher) Errors() <-chan error {
 	return w.errors
 }
 
-// processEvents handles fsnotify events and filters them
+// IsOverflow reports whether an error from Errors means the platform dropped
+// file events, so files may have been missed until the next reconciliation
+func IsOverflow(err error) bool {
+	return errors.Is(err, fsnotify.ErrEventOverflow)
+}
+
+// Pending returns the number of files waiting to stop changing
+func (w *Watcher) Pending() int {
+	return w.debounce.size()
+}
+
+// processEvents handles fsnotify events and filters them, scans the polled
+// directories and sends files once they have settled. It is the only sender
+// on the events and errors channels, so it closes them when it returns.
 func (w *Watcher) processEvents(ctx context.Context) {
+	defer close(w.errors)
+	defer close(w.events)
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Watcher processEvents recovered from panic: %v", r)
+			logger.Error("event processing recovered from panic", "panic", r)
 		}
 	}()
 
+	// The poll interval and quiet period need a restart to change
+	w.mu.RLock()
+	poll := time.NewTicker(w.config.PollInterval)
+	w.mu.RUnlock()
+	defer poll.Stop()
+	settle := time.NewTicker(settleInterval(w.debounce.quiet))
+	defer settle.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Watcher context cancelled, stopping event processing")
+			logger.Debug("context cancelled, stopping event processing")
 			return
 
 		case event, ok := <-w.watcher.Events:
 			if !ok {
-				log.Println("Watcher events channel closed")
+				logger.Debug("fsnotify events channel closed")
 				return
 			}
-
-			// Filter events based on patterns and operations
-			if w.shouldProcessEvent(event) {
-				fileEvent := FileEvent{
-					Path:      event.Name,
-					Operation: event.Op.String(),
-					Timestamp: time.Now(),
-				}
-
-				select {
-				case w.events <- fileEvent:
-					log.Printf("File event: %s %s", fileEvent.Operation, fileEvent.Path)
-				case <-ctx.Done():
-					return
-				default:
-					log.Printf("Warning: Event buffer full, dropping event for %s", event.Name)
-				}
-			}
+			w.handleEvent(ctx, event)
 
 		case err, ok := <-w.watcher.Errors:
 			if !ok {
-				log.Println("Watcher errors channel closed")
+				logger.Debug("fsnotify errors channel closed")
 				return
 			}
 
 			select {
 			case w.errors <- err:
-				log.Printf("Watcher error: %v", err)
+				logger.Debug("forwarded watcher error", "error", err)
 			case <-ctx.Done():
 				return
 			default:
-				log.Printf("Warning: Error buffer full, dropping error: %v", err)
+				logger.Warn("error buffer full, dropping error", "error", err)
+			}
+
+		case <-poll.C:
+			w.poll()
+
+		case now := <-settle.C:
+			for path, operation := range w.debounce.settled(now) {
+				w.emit(ctx, path, operation)
 			}
 		}
 	}
 }
 
+// settleInterval is how often pending files are checked: often enough that
+// a file is sent soon after its quiet period ends
+func settleInterval(quiet time.Duration) time.Duration {
+	if interval := quiet / 4; interval > 50*time.Millisecond {
+		return interval
+	}
+	return 50 * time.Millisecond
+}
+
+// handleEvent follows directories as they are created and removed and
+// forwards events for matching files
+func (w *Watcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			w.addDirectory(event.Name)
+			return
+		}
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		// A file renamed away, e.g. a temporary file moved into place,
+		// arrives again as a Create under its new name
+		w.debounce.forget(event.Name)
+		w.mu.Lock()
+		w.removeTreeLocked(event.Name)
+		w.mu.Unlock()
+	}
+
+	// Filter events based on patterns and operations, then wait for the
+	// file to settle
+	if w.shouldProcessEvent(event) {
+		w.debounce.touch(event.Name, event.Op.String(), time.Now())
+	}
+}
+
+// emit sends a settled file's event without blocking the watcher
+func (w *Watcher) emit(ctx context.Context, path, operation string) {
+	fileEvent := FileEvent{
+		Path:          path,
+		Operation:     operation,
+		Timestamp:     time.Now(),
+		CorrelationID: logging.NewCorrelationID(),
+	}
+
+	select {
+	case w.events <- fileEvent:
+		logger.Debug("file event", "op", fileEvent.Operation, "path", fileEvent.Path, "correlation_id", fileEvent.CorrelationID)
+	case <-ctx.Done():
+	default:
+		// Hold the file back instead of dropping it; it is sent again once
+		// the consumer had time to catch up
+		metrics.EventsDeferred.With().Inc()
+		logger.Warn("event buffer full, deferring event", "path", path)
+		w.debounce.retry(path, eventRetryDelay, time.Now())
+	}
+}
+
 // shouldProcessEvent determines if an event should be processed
 func (w *Watcher) shouldProcessEvent(event fsnotify.Event) bool {
 	// Only process write and create events (ignore chmod, remove, etc.)
@@ -145,14 +277,21 @@ func (w *Watcher) shouldProcessEvent(event fsnotify.Event) bool {
 
 	// Check if file matches any of our watch patterns
 	filename := filepath.Base(event.Name)
-	
-	for _, pattern := range w.patterns {
+	w.mu.RLock()
+	patterns := w.patterns
+	excluded := isExcluded(w.config, event.Name)
+	w.mu.RUnlock()
+	if excluded {
+		return false
+	}
+
+	for _, pattern := range patterns {
 		matched, err := filepath.Match(pattern, filename)
 		if err != nil {
-			log.Printf("Error matching pattern %s against %s: %v", pattern, filename, err)
+			logger.Error("invalid watch pattern", "pattern", pattern, "file", filename, "error", err)
 			continue
 		}
-		
+
 		if matched {
 			return true
 		}
@@ -161,39 +300,72 @@ func (w *Watcher) shouldProcessEvent(event fsnotify.Event) bool {
 	return false
 }
 
-// AddRecursiveWatch adds a directory and all its subdirectories to the watcher
-func (w *Watcher) AddRecursiveWatch(rootPath string) error {
-	return filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// Existing lists the files below the output directory that match the watch
+// patterns and were modified in [since, until), oldest first. Zero times leave
+// that side of the range open.
+func Existing(cfg *config.ScreenPipeConfig, since, until time.Time) ([]string, error) {
+	if _, err := os.Stat(cfg.OutputPath); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", cfg.OutputPath, err)
+	}
+
+	files := []file{}
+	for _, f := range matchingFiles(cfg, cfg.OutputPath) {
+		if !since.IsZero() && f.modTime.Before(since) {
+			continue
 		}
-		
-		if info.IsDir() {
-			return w.watcher.Add(path)
+		if !until.IsZero() && !f.modTime.Before(until) {
+			continue
 		}
-		
-		return nil
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
 	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths, nil
 }
 
-// IsWatching checks if a path is currently being watched
-func (w *Watcher) IsWatching(path string) bool {
-	watchList := w.watcher.WatchList()
-	for _, watchedPath := range watchList {
-		if watchedPath == path {
+// matchesPatterns reports whether a file name matches any watch pattern
+func matchesPatterns(patterns []string, filename string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, filename); matched {
 			return true
 		}
 	}
 	return false
 }
 
-// GetWatchedPaths returns all currently watched paths
+// IsWatching checks if a path is currently being watched or polled
+func (w *Watcher) IsWatching(path string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	_, polled := w.polled[path]
+	return w.watched[path] || polled
+}
+
+// GetWatchedPaths returns all currently watched and polled directories
 func (w *Watcher) GetWatchedPaths() []string {
-	return w.watcher.WatchList()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	paths := make([]string, 0, len(w.watched)+len(w.polled))
+	for path := range w.watched {
+		paths = append(paths, path)
+	}
+	for path := range w.polled {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
 }
 
 // TODO: Add more sophisticated filtering options
 // - File size thresholds
 // - Time-based filtering (only process files newer than X)
 // - Content type detection
-// - Debouncing for rapid file changes 
\ No newline at end of file
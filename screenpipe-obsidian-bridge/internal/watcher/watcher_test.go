@@ -0,0 +1,243 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"screenpipe-obsidian-bridge/internal/config"
+)
+
+// newTestWatcher creates a watcher for a fresh output directory without
+// starting it
+func newTestWatcher(t *testing.T) (*Watcher, string) {
+	t.Helper()
+	root := t.TempDir()
+	w, err := New(&config.ScreenPipeConfig{
+		OutputPath:      root,
+		WatchPatterns:   []string{"*.txt"},
+		ExcludePatterns: []string{"private", "drafts/*"},
+		PollInterval:    time.Second,
+		QuietPeriod:     time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.watcher.Close() })
+	return w, root
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pending returns the files waiting to settle with their operations
+func pending(w *Watcher) map[string]string {
+	w.debounce.mu.Lock()
+	defer w.debounce.mu.Unlock()
+	files := map[string]string{}
+	for path, file := range w.debounce.pending {
+		files[path] = file.operation
+	}
+	return files
+}
+
+func TestIsExcluded(t *testing.T) {
+	cfg := &config.ScreenPipeConfig{
+		OutputPath:      "/data",
+		ExcludePatterns: []string{"private", "*.tmp", "drafts/*"},
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/data", false},
+		{"/data/private", true},
+		{"/data/2025/private", true},
+		{"/data/private-notes", false},
+		{"/data/ocr.tmp", true},
+		{"/data/2025/ocr.tmp", true},
+		{"/data/drafts/a.txt", true},
+		{"/data/2025/drafts/a.txt", false},
+		{"/data/drafts", false},
+		{"/data/ocr.txt", false},
+	}
+	for _, test := range tests {
+		if got := isExcluded(cfg, test.path); got != test.want {
+			t.Errorf("isExcluded(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+
+	if isExcluded(&config.ScreenPipeConfig{OutputPath: "/data"}, "/data/private") {
+		t.Error("excluded a path without exclude patterns")
+	}
+}
+
+func TestAddDirectory(t *testing.T) {
+	w, root := newTestWatcher(t)
+
+	// A directory tree moved in at once, with files written before any
+	// watch existed
+	day := filepath.Join(root, "2025-01-01")
+	writeFile(t, filepath.Join(day, "ocr.txt"), "text")
+	writeFile(t, filepath.Join(day, "frame.png"), "png")
+	writeFile(t, filepath.Join(day, "morning", "audio.txt"), "text")
+	writeFile(t, filepath.Join(day, "private", "secret.txt"), "text")
+
+	w.addDirectory(day)
+
+	for _, dir := range []string{day, filepath.Join(day, "morning")} {
+		if !w.IsWatching(dir) {
+			t.Errorf("not watching %s", dir)
+		}
+	}
+	if w.IsWatching(filepath.Join(day, "private")) {
+		t.Error("watching an excluded directory")
+	}
+	want := map[string]string{
+		filepath.Join(day, "ocr.txt"):              fsnotify.Create.String(),
+		filepath.Join(day, "morning", "audio.txt"): fsnotify.Create.String(),
+	}
+	if got := pending(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+
+	// Excluded directories and ones outside the output directory are ignored
+	w.addDirectory(filepath.Join(day, "private"))
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "other.txt"), "text")
+	w.addDirectory(outside)
+	if w.IsWatching(filepath.Join(day, "private")) || w.IsWatching(outside) {
+		t.Error("watching an excluded or outside directory")
+	}
+	if got := pending(w); len(got) != len(want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveTreeLocked(t *testing.T) {
+	w, root := newTestWatcher(t)
+	for _, dir := range []string{"a/b/c", "a/bc", "d"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.mu.Lock()
+	if err := w.addTreeLocked(root); err != nil {
+		t.Fatal(err)
+	}
+	// A polled directory below the removed one goes too
+	polled := filepath.Join(root, "a", "b", "c")
+	w.watcher.Remove(polled)
+	delete(w.watched, polled)
+	w.polled[polled] = map[string]fileState{}
+
+	w.removeTreeLocked(filepath.Join(root, "a", "b"))
+	w.mu.Unlock()
+
+	want := []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "bc"), filepath.Join(root, "d")}
+	sort.Strings(want)
+	if got := w.GetWatchedPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("watched = %q, want %q", got, want)
+	}
+	if got := w.watcher.WatchList(); len(got) != len(want) {
+		t.Errorf("native watches = %q, want %q", got, want)
+	}
+}
+
+func TestPollingFallback(t *testing.T) {
+	w, root := newTestWatcher(t)
+	writeFile(t, filepath.Join(root, "old.txt"), "old")
+
+	// Poll the output directory as if the watch limit had been reached
+	w.mu.Lock()
+	w.polled[root] = scanDirectory(w.config, root)
+	w.mu.Unlock()
+
+	w.poll()
+	if got := pending(w); len(got) != 0 {
+		t.Fatalf("files seen before polling started are pending: %v", got)
+	}
+
+	written := filepath.Join(root, "new.txt")
+	writeFile(t, written, "new")
+	writeFile(t, filepath.Join(root, "new.png"), "png")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "old.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "2025", "ocr.txt")
+	writeFile(t, nested, "text")
+	writeFile(t, filepath.Join(root, "private", "secret.txt"), "text")
+
+	w.poll()
+	want := map[string]string{
+		written:                        fsnotify.Create.String(),
+		filepath.Join(root, "old.txt"): fsnotify.Write.String(),
+		nested:                         fsnotify.Create.String(),
+	}
+	if got := pending(w); !reflect.DeepEqual(got, want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+	if !w.IsWatching(filepath.Join(root, "2025")) {
+		t.Error("new directory found by polling is not watched")
+	}
+	if w.IsWatching(filepath.Join(root, "private")) {
+		t.Error("excluded directory found by polling is watched")
+	}
+
+	// A polled directory that disappears stops being polled
+	gone := filepath.Join(root, "gone")
+	if err := os.Mkdir(gone, 0755); err != nil {
+		t.Fatal(err)
+	}
+	w.mu.Lock()
+	w.polled[gone] = map[string]fileState{}
+	w.mu.Unlock()
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	if w.IsWatching(gone) {
+		t.Error("still polling a removed directory")
+	}
+}
+
+func TestStopClosesChannels(t *testing.T) {
+	w, _ := newTestWatcher(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := w.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, closed := range map[string]func() bool{
+		"events": func() bool { _, ok := <-w.Events(); return !ok },
+		"errors": func() bool { _, ok := <-w.Errors(); return !ok },
+	} {
+		done := make(chan bool)
+		go func() { done <- closed() }()
+		select {
+		case ok := <-done:
+			if !ok {
+				t.Errorf("%s channel delivered a value after stopping", name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s channel not closed after stopping", name)
+		}
+	}
+}